@@ -3,11 +3,15 @@
 package client
 
 import (
+	"cmp"
 	"context"
 	"encoding/json"
 	"fmt"
 	"log"
+	"os"
 	"os/exec"
+	"slices"
+	"strconv"
 	"strings"
 	"time"
 
@@ -17,6 +21,22 @@ import (
 	"github.com/aws/aws-sdk-go-v2/service/ecs"
 	ecsTypes "github.com/aws/aws-sdk-go-v2/service/ecs/types"
 	"github.com/aws/aws-sdk-go-v2/service/ssm"
+	"github.com/sestrella/iecs/session"
+)
+
+// Maximum number of identifiers accepted by a single ECS describe call.
+const (
+	describeClustersBatchSize  = 100
+	describeServicesBatchSize  = 10
+	describeRevisionsBatchSize = 20
+	describeTasksBatchSize     = 100
+	filterLogStreamsBatchSize  = 100
+)
+
+// Session Manager documents used for port forwarding.
+const (
+	portForwardingDocument             = "AWS-StartPortForwardingSession"
+	portForwardingToRemoteHostDocument = "AWS-StartPortForwardingSessionToRemoteHost"
 )
 
 // awsClient implements the combined Client interface
@@ -24,30 +44,37 @@ type awsClient struct {
 	region     string
 	ecsClient  *ecs.Client
 	logsClient *logs.Client
+	ssmClient  *ssm.Client
 }
 
 // NewClient creates a new combined AWS client
 func NewClient(cfg aws.Config) Client {
 	ecsClient := ecs.NewFromConfig(cfg)
 	logsClient := logs.NewFromConfig(cfg)
+	ssmClient := ssm.NewFromConfig(cfg)
 	return &awsClient{
 		region:     cfg.Region,
 		ecsClient:  ecsClient,
 		logsClient: logsClient,
+		ssmClient:  ssmClient,
 	}
 }
 
 // ECS operations implementation
 
 func (c *awsClient) ListClusters(ctx context.Context) ([]string, error) {
-	listClusters, err := c.ecsClient.ListClusters(ctx, &ecs.ListClustersInput{})
-	if err != nil {
-		return nil, err
+	var clusterArns []string
+	paginator := ecs.NewListClustersPaginator(c.ecsClient, &ecs.ListClustersInput{})
+	for paginator.HasMorePages() {
+		listClusters, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+		clusterArns = append(clusterArns, listClusters.ClusterArns...)
 	}
 
-	clusterArns := listClusters.ClusterArns
 	if len(clusterArns) == 0 {
-		return nil, fmt.Errorf("no clusters found")
+		return nil, ErrNoClusters
 	}
 
 	return clusterArns, nil
@@ -57,25 +84,34 @@ func (c *awsClient) DescribeClusters(
 	ctx context.Context,
 	clusterArns []string,
 ) ([]ecsTypes.Cluster, error) {
-	describeClusters, err := c.ecsClient.DescribeClusters(ctx, &ecs.DescribeClustersInput{
-		Clusters: clusterArns,
-	})
-	if err != nil {
-		return nil, err
+	var clusters []ecsTypes.Cluster
+	for _, batch := range chunk(clusterArns, describeClustersBatchSize) {
+		describeClusters, err := c.ecsClient.DescribeClusters(ctx, &ecs.DescribeClustersInput{
+			Clusters: batch,
+			Include:  []ecsTypes.ClusterField{ecsTypes.ClusterFieldConfigurations},
+		})
+		if err != nil {
+			return nil, err
+		}
+		clusters = append(clusters, describeClusters.Clusters...)
 	}
 
-	return describeClusters.Clusters, nil
+	return clusters, nil
 }
 
 func (c *awsClient) ListServices(ctx context.Context, clusterArn string) ([]string, error) {
-	listServices, err := c.ecsClient.ListServices(ctx, &ecs.ListServicesInput{
+	var serviceArns []string
+	paginator := ecs.NewListServicesPaginator(c.ecsClient, &ecs.ListServicesInput{
 		Cluster: &clusterArn,
 	})
-	if err != nil {
-		return nil, err
+	for paginator.HasMorePages() {
+		listServices, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+		serviceArns = append(serviceArns, listServices.ServiceArns...)
 	}
 
-	serviceArns := listServices.ServiceArns
 	if len(serviceArns) == 0 {
 		return nil, fmt.Errorf("no services found in cluster %s", clusterArn)
 	}
@@ -88,33 +124,90 @@ func (c *awsClient) DescribeServices(
 	clusterArn string,
 	serviceArns []string,
 ) ([]ecsTypes.Service, error) {
-	describeServices, err := c.ecsClient.DescribeServices(ctx, &ecs.DescribeServicesInput{
-		Cluster:  &clusterArn,
-		Services: serviceArns,
+	var services []ecsTypes.Service
+	for _, batch := range chunk(serviceArns, describeServicesBatchSize) {
+		describeServices, err := c.ecsClient.DescribeServices(ctx, &ecs.DescribeServicesInput{
+			Cluster:  &clusterArn,
+			Services: batch,
+		})
+		if err != nil {
+			return nil, err
+		}
+		services = append(services, describeServices.Services...)
+	}
+
+	return services, nil
+}
+
+func (c *awsClient) ListServiceDeployments(
+	ctx context.Context,
+	clusterArn string,
+	serviceArn string,
+	statuses []ecsTypes.ServiceDeploymentStatus,
+) ([]ecsTypes.ServiceDeploymentBrief, error) {
+	var serviceDeployments []ecsTypes.ServiceDeploymentBrief
+	input := &ecs.ListServiceDeploymentsInput{
+		Cluster: &clusterArn,
+		Service: &serviceArn,
+		Status:  statuses,
+	}
+	for {
+		listServiceDeployments, err := c.ecsClient.ListServiceDeployments(ctx, input)
+		if err != nil {
+			return nil, err
+		}
+		serviceDeployments = append(serviceDeployments, listServiceDeployments.ServiceDeployments...)
+
+		if listServiceDeployments.NextToken == nil {
+			break
+		}
+		input.NextToken = listServiceDeployments.NextToken
+	}
+
+	slices.SortStableFunc(serviceDeployments, func(a, b ecsTypes.ServiceDeploymentBrief) int {
+		return cmp.Compare(aws.ToTime(b.CreatedAt).UnixNano(), aws.ToTime(a.CreatedAt).UnixNano())
 	})
-	if err != nil {
-		return nil, err
+
+	return serviceDeployments, nil
+}
+
+func (c *awsClient) DescribeServiceRevisions(
+	ctx context.Context,
+	serviceRevisionArns []string,
+) ([]ecsTypes.ServiceRevision, error) {
+	var serviceRevisions []ecsTypes.ServiceRevision
+	for _, batch := range chunk(serviceRevisionArns, describeRevisionsBatchSize) {
+		describeServiceRevisions, err := c.ecsClient.DescribeServiceRevisions(
+			ctx,
+			&ecs.DescribeServiceRevisionsInput{ServiceRevisionArns: batch},
+		)
+		if err != nil {
+			return nil, err
+		}
+		serviceRevisions = append(serviceRevisions, describeServiceRevisions.ServiceRevisions...)
 	}
 
-	return describeServices.Services, nil
+	return serviceRevisions, nil
 }
 
 func (c *awsClient) ListTasks(
 	ctx context.Context,
 	clusterArn string,
 	serviceName string,
+	desiredStatus ecsTypes.DesiredStatus,
 ) ([]string, error) {
-	listTasks, err := c.ecsClient.ListTasks(ctx, &ecs.ListTasksInput{
-		Cluster:     &clusterArn,
-		ServiceName: &serviceName,
+	var taskArns []string
+	paginator := ecs.NewListTasksPaginator(c.ecsClient, &ecs.ListTasksInput{
+		Cluster:       &clusterArn,
+		ServiceName:   &serviceName,
+		DesiredStatus: desiredStatus,
 	})
-	if err != nil {
-		return nil, err
-	}
-
-	taskArns := listTasks.TaskArns
-	if len(taskArns) == 0 {
-		return nil, fmt.Errorf("no tasks found in service %s", serviceName)
+	for paginator.HasMorePages() {
+		listTasks, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+		taskArns = append(taskArns, listTasks.TaskArns...)
 	}
 
 	return taskArns, nil
@@ -125,15 +218,19 @@ func (c *awsClient) DescribeTasks(
 	clusterArn string,
 	taskArns []string,
 ) ([]ecsTypes.Task, error) {
-	describeTasks, err := c.ecsClient.DescribeTasks(ctx, &ecs.DescribeTasksInput{
-		Cluster: &clusterArn,
-		Tasks:   taskArns,
-	})
-	if err != nil {
-		return nil, err
+	var tasks []ecsTypes.Task
+	for _, batch := range chunk(taskArns, describeTasksBatchSize) {
+		describeTasks, err := c.ecsClient.DescribeTasks(ctx, &ecs.DescribeTasksInput{
+			Cluster: &clusterArn,
+			Tasks:   batch,
+		})
+		if err != nil {
+			return nil, err
+		}
+		tasks = append(tasks, describeTasks.Tasks...)
 	}
 
-	return describeTasks.Tasks, nil
+	return tasks, nil
 }
 
 func (c *awsClient) ExecuteCommand(
@@ -144,7 +241,7 @@ func (c *awsClient) ExecuteCommand(
 	command string,
 	interactive bool,
 ) (*exec.Cmd, error) {
-	smpPath, err := exec.LookPath("session-manager-plugin")
+	path, native, err := sessionRunner(cluster)
 	if err != nil {
 		return nil, err
 	}
@@ -160,26 +257,123 @@ func (c *awsClient) ExecuteCommand(
 		return nil, err
 	}
 
-	session, err := json.Marshal(executeCommand.Session)
+	sessionJSON, err := json.Marshal(executeCommand.Session)
+	if err != nil {
+		return nil, err
+	}
+
+	if native {
+		cmd := exec.Command(path, SessionClientCommand)
+		cmd.Env = append(os.Environ(), fmt.Sprintf("%s=%s", session.EnvVar, sessionJSON))
+		return cmd, nil
+	}
+
+	target, err := ecsTarget(cluster, taskArn, container)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.sessionManagerPlugin(path, sessionJSON, ssm.StartSessionInput{
+		Target: &target,
+	})
+}
+
+// sessionRunner returns the program that runs the sessions started by
+// ExecuteCommand, which is iecs itself unless the plugin is requested with
+// IECS_SESSION_CLIENT=plugin or the iecs executable cannot be found. Sessions
+// encrypted with KMS, which the built-in client does not support, use the
+// plugin whenever it is installed.
+func sessionRunner(cluster *ecsTypes.Cluster) (string, bool, error) {
+	usePlugin := os.Getenv(SessionClientEnvVar) == "plugin"
+	if !usePlugin && SessionKmsKeyId(cluster) != "" {
+		if path, err := exec.LookPath("session-manager-plugin"); err == nil {
+			return path, false, nil
+		}
+	}
+	if !usePlugin {
+		if path, err := os.Executable(); err == nil {
+			return path, true, nil
+		}
+	}
+
+	path, err := exec.LookPath("session-manager-plugin")
+	return path, false, err
+}
+
+func (c *awsClient) StartPortForwardingSession(
+	ctx context.Context,
+	cluster *ecsTypes.Cluster,
+	taskArn string,
+	container *ecsTypes.Container,
+	config PortForwardingConfig,
+) (*exec.Cmd, error) {
+	smpPath, err := exec.LookPath("session-manager-plugin")
+	if err != nil {
+		return nil, err
+	}
+
+	target, err := ecsTarget(cluster, taskArn, container)
 	if err != nil {
 		return nil, err
 	}
 
+	startSessionInput := ssm.StartSessionInput{
+		Target:       &target,
+		DocumentName: aws.String(portForwardingDocument),
+		Parameters: map[string][]string{
+			"portNumber":      {strconv.Itoa(config.RemotePort)},
+			"localPortNumber": {strconv.Itoa(config.LocalPort)},
+		},
+	}
+	if config.RemoteHost != "" {
+		startSessionInput.DocumentName = aws.String(portForwardingToRemoteHostDocument)
+		startSessionInput.Parameters["host"] = []string{config.RemoteHost}
+	}
+
+	startSession, err := c.ssmClient.StartSession(ctx, &startSessionInput)
+	if err != nil {
+		return nil, err
+	}
+
+	session, err := json.Marshal(startSession)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.sessionManagerPlugin(smpPath, session, startSessionInput)
+}
+
+// ecsTarget returns the Session Manager target of a container, which is
+// identified by its cluster, task ID and runtime ID. The task ID is the last
+// segment of the ARN, both in the old (task/<id>) and the current
+// (task/<cluster>/<id>) formats.
+func ecsTarget(
+	cluster *ecsTypes.Cluster,
+	taskArn string,
+	container *ecsTypes.Container,
+) (string, error) {
 	taskArnSlices := strings.Split(taskArn, "/")
 	if len(taskArnSlices) < 2 {
-		return nil, fmt.Errorf("unable to extract task name from '%s'", taskArn)
+		return "", fmt.Errorf("unable to extract task name from '%s'", taskArn)
 	}
 
-	taskName := strings.Join(taskArnSlices[1:], "/")
-	target := fmt.Sprintf(
+	taskId := taskArnSlices[len(taskArnSlices)-1]
+	return fmt.Sprintf(
 		"ecs:%s_%s_%s",
 		*cluster.ClusterName,
-		taskName,
+		taskId,
 		*container.RuntimeId,
-	)
-	startSessionInput, err := json.Marshal(ssm.StartSessionInput{
-		Target: &target,
-	})
+	), nil
+}
+
+// sessionManagerPlugin builds the session-manager-plugin invocation for an
+// already started session.
+func (c *awsClient) sessionManagerPlugin(
+	smpPath string,
+	session []byte,
+	input ssm.StartSessionInput,
+) (*exec.Cmd, error) {
+	startSessionInput, err := json.Marshal(input)
 	if err != nil {
 		return nil, err
 	}
@@ -201,18 +395,23 @@ func (c *awsClient) ListTaskDefinitions(
 	ctx context.Context,
 	familyPrefix string,
 ) ([]string, error) {
-	listTaskDefinitions, err := c.ecsClient.ListTaskDefinitions(
-		ctx,
+	var taskDefinitionArns []string
+	paginator := ecs.NewListTaskDefinitionsPaginator(
+		c.ecsClient,
 		&ecs.ListTaskDefinitionsInput{
 			FamilyPrefix: &familyPrefix,
 			Sort:         ecsTypes.SortOrderDesc,
 		},
 	)
-	if err != nil {
-		return nil, err
+	for paginator.HasMorePages() {
+		listTaskDefinitions, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+		taskDefinitionArns = append(taskDefinitionArns, listTaskDefinitions.TaskDefinitionArns...)
 	}
 
-	return listTaskDefinitions.TaskDefinitionArns, nil
+	return taskDefinitionArns, nil
 }
 
 func (c *awsClient) DescribeTaskDefinition(
@@ -232,6 +431,56 @@ func (c *awsClient) DescribeTaskDefinition(
 	return describeTaskDefinition.TaskDefinition, nil
 }
 
+func (c *awsClient) RegisterTaskDefinition(
+	ctx context.Context,
+	taskDefinition *ecsTypes.TaskDefinition,
+) (*ecsTypes.TaskDefinition, error) {
+	// Tags are not part of the task definition itself, but are kept across
+	// revisions as they drive cost allocation and permissions
+	var tags []ecsTypes.Tag
+	if taskDefinition.TaskDefinitionArn != nil {
+		describeTaskDefinition, err := c.ecsClient.DescribeTaskDefinition(
+			ctx,
+			&ecs.DescribeTaskDefinitionInput{
+				TaskDefinition: taskDefinition.TaskDefinitionArn,
+				Include:        []ecsTypes.TaskDefinitionField{ecsTypes.TaskDefinitionFieldTags},
+			},
+		)
+		if err != nil {
+			return nil, err
+		}
+		tags = describeTaskDefinition.Tags
+	}
+
+	registerTaskDefinition, err := c.ecsClient.RegisterTaskDefinition(
+		ctx,
+		&ecs.RegisterTaskDefinitionInput{
+			Family:                  taskDefinition.Family,
+			ContainerDefinitions:    taskDefinition.ContainerDefinitions,
+			Cpu:                     taskDefinition.Cpu,
+			Memory:                  taskDefinition.Memory,
+			EphemeralStorage:        taskDefinition.EphemeralStorage,
+			ExecutionRoleArn:        taskDefinition.ExecutionRoleArn,
+			InferenceAccelerators:   taskDefinition.InferenceAccelerators,
+			IpcMode:                 taskDefinition.IpcMode,
+			NetworkMode:             taskDefinition.NetworkMode,
+			PidMode:                 taskDefinition.PidMode,
+			PlacementConstraints:    taskDefinition.PlacementConstraints,
+			ProxyConfiguration:      taskDefinition.ProxyConfiguration,
+			RequiresCompatibilities: taskDefinition.RequiresCompatibilities,
+			RuntimePlatform:         taskDefinition.RuntimePlatform,
+			TaskRoleArn:             taskDefinition.TaskRoleArn,
+			Volumes:                 taskDefinition.Volumes,
+			Tags:                    tags,
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return registerTaskDefinition.TaskDefinition, nil
+}
+
 // CloudWatch Logs implementation
 
 func (c *awsClient) StartLiveTail(
@@ -274,7 +523,12 @@ func (c *awsClient) StartLiveTail(
 
 	// Process events
 	for {
-		event := <-eventsStream
+		var event logsTypes.StartLiveTailResponseStream
+		select {
+		case <-ctx.Done():
+			return nil
+		case event = <-eventsStream:
+		}
 		switch e := event.(type) {
 		case *logsTypes.StartLiveTailResponseStreamMemberSessionStart:
 			handler.Start()
@@ -294,33 +548,135 @@ func (c *awsClient) StartLiveTail(
 	}
 }
 
+func (c *awsClient) FilterLogEvents(
+	ctx context.Context,
+	logGroupName string,
+	streamNames []string,
+	startTime time.Time,
+	endTime time.Time,
+) ([]logsTypes.FilteredLogEvent, error) {
+	var events []logsTypes.FilteredLogEvent
+	for _, batch := range chunk(streamNames, filterLogStreamsBatchSize) {
+		input := &logs.FilterLogEventsInput{
+			LogGroupName:   &logGroupName,
+			LogStreamNames: batch,
+			EndTime:        aws.Int64(endTime.UnixMilli()),
+		}
+		if !startTime.IsZero() {
+			input.StartTime = aws.Int64(startTime.UnixMilli())
+		}
+		paginator := logs.NewFilterLogEventsPaginator(c.logsClient, input)
+		for paginator.HasMorePages() {
+			filterLogEvents, err := paginator.NextPage(ctx)
+			if err != nil {
+				return nil, err
+			}
+			events = append(events, filterLogEvents.Events...)
+		}
+	}
+
+	// Events are only ordered within a page, so merge them across streams
+	slices.SortStableFunc(events, func(a, b logsTypes.FilteredLogEvent) int {
+		return cmp.Compare(*a.Timestamp, *b.Timestamp)
+	})
+
+	return events, nil
+}
+
 func (c *awsClient) UpdateService(
 	ctx context.Context,
 	service *ecsTypes.Service,
 	config ServiceConfig,
-	waitTimeout time.Duration,
 ) (*ecsTypes.Service, error) {
 	updateService, err := c.ecsClient.UpdateService(ctx, &ecs.UpdateServiceInput{
 		Cluster:        service.ClusterArn,
 		Service:        service.ServiceArn,
 		TaskDefinition: &config.TaskDefinitionArn,
-		DesiredCount:   &config.DesiredCount,
+		DesiredCount:   config.DesiredCount,
+		// The tasks are replaced even when the configuration is unchanged
+		ForceNewDeployment:   config.ForceNewDeployment,
+		EnableExecuteCommand: config.EnableExecuteCommand,
 	})
 	if err != nil {
 		return nil, err
 	}
 
-	waiter := ecs.NewServicesStableWaiter(c.ecsClient)
-	err = waiter.Wait(ctx, &ecs.DescribeServicesInput{
-		Cluster:  updateService.Service.ClusterArn,
-		Services: []string{*updateService.Service.ServiceArn},
-	}, waitTimeout, func(sswo *ecs.ServicesStableWaiterOptions) {
-		// TODO: make this value parameterizable
-		sswo.LogWaitAttempts = true
-	})
+	return updateService.Service, nil
+}
+
+func (c *awsClient) RunTask(
+	ctx context.Context,
+	service *ecsTypes.Service,
+	overrides []ecsTypes.ContainerOverride,
+) (*ecsTypes.Task, error) {
+	input := &ecs.RunTaskInput{
+		Cluster:              service.ClusterArn,
+		TaskDefinition:       service.TaskDefinition,
+		NetworkConfiguration: service.NetworkConfiguration,
+		PlatformVersion:      service.PlatformVersion,
+		PlacementConstraints: service.PlacementConstraints,
+		PlacementStrategy:    service.PlacementStrategy,
+		EnableExecuteCommand: service.EnableExecuteCommand,
+		StartedBy:            aws.String("iecs"),
+		Overrides:            &ecsTypes.TaskOverride{ContainerOverrides: overrides},
+	}
+	// ECS rejects requests with both a launch type and a strategy
+	if len(service.CapacityProviderStrategy) > 0 {
+		input.CapacityProviderStrategy = service.CapacityProviderStrategy
+	} else {
+		input.LaunchType = service.LaunchType
+	}
+
+	runTask, err := c.ecsClient.RunTask(ctx, input)
 	if err != nil {
 		return nil, err
 	}
+	if len(runTask.Failures) > 0 {
+		failure := runTask.Failures[0]
+		return nil, fmt.Errorf(
+			"unable to run task: %s %s",
+			aws.ToString(failure.Reason),
+			aws.ToString(failure.Detail),
+		)
+	}
+	if len(runTask.Tasks) == 0 {
+		return nil, fmt.Errorf("unable to run task: no task started")
+	}
 
-	return updateService.Service, nil
+	return &runTask.Tasks[0], nil
+}
+
+func (c *awsClient) StopTask(
+	ctx context.Context,
+	clusterArn string,
+	taskArn string,
+	reason string,
+) (*ecsTypes.Task, error) {
+	input := &ecs.StopTaskInput{
+		Cluster: &clusterArn,
+		Task:    &taskArn,
+	}
+	if reason != "" {
+		input.Reason = &reason
+	}
+
+	stopTask, err := c.ecsClient.StopTask(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+
+	return stopTask.Task, nil
+}
+
+// chunk splits items into consecutive batches of at most size elements.
+func chunk[T any](items []T, size int) [][]T {
+	var batches [][]T
+	for size < len(items) {
+		batches = append(batches, items[:size:size])
+		items = items[size:]
+	}
+	if len(items) > 0 {
+		batches = append(batches, items)
+	}
+	return batches
 }
@@ -0,0 +1,580 @@
+//go:build !DEMO
+
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ecs/types"
+	"github.com/sestrella/iecs/session"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const fakeECSPageSize = 3
+
+// fakeECS is a minimal stand-in for the ECS JSON API that pages list results
+// and enforces the batch limits of the describe operations.
+type fakeECS struct {
+	t           *testing.T
+	clusters    []string
+	services    []string
+	tasks       []string
+	revisions   []string
+	deployments []string
+	tags        []map[string]any
+	calls       map[string]int
+	inputs      map[string]map[string]any
+}
+
+func newFakeECS(t *testing.T) *fakeECS {
+	return &fakeECS{t: t, calls: map[string]int{}, inputs: map[string]map[string]any{}}
+}
+
+func (f *fakeECS) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	target := r.Header.Get("X-Amz-Target")
+	operation := target[strings.LastIndex(target, ".")+1:]
+	f.calls[operation]++
+
+	var input map[string]any
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		f.t.Fatalf("unable to decode %s input: %v", operation, err)
+	}
+	f.inputs[operation] = input
+
+	var output map[string]any
+	switch operation {
+	case "ListClusters":
+		output = f.page(input, "clusterArns", f.clusters)
+	case "ListServices":
+		output = f.page(input, "serviceArns", f.services)
+	case "ListTasks":
+		output = f.page(input, "taskArns", f.tasks)
+	case "ListTaskDefinitions":
+		output = f.page(input, "taskDefinitionArns", f.revisions)
+	case "ListServiceDeployments":
+		output = f.page(input, "serviceDeployments", f.deployments)
+		// Deployments are listed oldest first to check they are sorted
+		var serviceDeployments []map[string]any
+		for _, arn := range output["serviceDeployments"].([]string) {
+			serviceDeployments = append(serviceDeployments, map[string]any{
+				"serviceDeploymentArn": arn,
+				"createdAt":            slices.Index(f.deployments, arn),
+			})
+		}
+		output["serviceDeployments"] = serviceDeployments
+	case "DescribeServiceRevisions":
+		output = f.describe(w, input, "serviceRevisionArns", "serviceRevisionArn", 20)
+		if output != nil {
+			output["serviceRevisions"] = output["serviceRevisionArns"]
+			delete(output, "serviceRevisionArns")
+		}
+	case "DescribeClusters":
+		output = f.describe(w, input, "clusters", "clusterArn", 100)
+	case "DescribeServices":
+		output = f.describe(w, input, "services", "serviceArn", 10)
+	case "DescribeTasks":
+		output = f.describe(w, input, "tasks", "taskArn", 100)
+	case "UpdateService":
+		output = map[string]any{"service": map[string]any{"serviceArn": input["service"]}}
+	case "RunTask":
+		output = map[string]any{"tasks": []any{map[string]any{
+			"taskArn":    "arn:aws:ecs:us-east-1:123456789012:task/cluster/run",
+			"lastStatus": "PROVISIONING",
+		}}}
+	case "StopTask":
+		output = map[string]any{"task": map[string]any{
+			"taskArn":       input["task"],
+			"desiredStatus": "STOPPED",
+			"stoppedReason": input["reason"],
+		}}
+	case "ExecuteCommand":
+		output = map[string]any{"session": map[string]any{
+			"sessionId":  "ecs-execute-command-1",
+			"streamUrl":  "wss://ssmmessages.us-east-1.amazonaws.com/v1/data-channel/ecs-execute-command-1",
+			"tokenValue": "token",
+		}}
+	case "DescribeTaskDefinition":
+		output = map[string]any{
+			"taskDefinition": map[string]any{"taskDefinitionArn": input["taskDefinition"]},
+			"tags":           f.tags,
+		}
+	case "RegisterTaskDefinition":
+		taskDefinition := maps.Clone(input)
+		taskDefinition["taskDefinitionArn"] = fmt.Sprintf(
+			"arn:aws:ecs:us-east-1:123456789012:task-definition/%s:2",
+			input["family"],
+		)
+		taskDefinition["revision"] = 2
+		output = map[string]any{"taskDefinition": taskDefinition}
+	default:
+		f.t.Fatalf("unexpected operation %s", operation)
+	}
+	if output == nil {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/x-amz-json-1.1")
+	if err := json.NewEncoder(w).Encode(output); err != nil {
+		f.t.Fatalf("unable to encode %s output: %v", operation, err)
+	}
+}
+
+func (f *fakeECS) page(input map[string]any, key string, items []string) map[string]any {
+	offset := 0
+	if token, ok := input["nextToken"].(string); ok {
+		offset, _ = strconv.Atoi(token)
+	}
+
+	end := min(offset+fakeECSPageSize, len(items))
+	output := map[string]any{key: items[offset:end]}
+	if end < len(items) {
+		output["nextToken"] = strconv.Itoa(end)
+	}
+	return output
+}
+
+func (f *fakeECS) describe(
+	w http.ResponseWriter,
+	input map[string]any,
+	key string,
+	arnKey string,
+	limit int,
+) map[string]any {
+	arns, _ := input[key].([]any)
+	if len(arns) > limit {
+		w.Header().Set("Content-Type", "application/x-amz-json-1.1")
+		w.Header().Set("X-Amzn-Errortype", "InvalidParameterException")
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = fmt.Fprintf(w, `{"message":"too many %s: %d"}`, key, len(arns))
+		return nil
+	}
+
+	var resources []map[string]any
+	for _, arn := range arns {
+		resources = append(resources, map[string]any{arnKey: arn})
+	}
+	return map[string]any{key: resources}
+}
+
+func newFakeClient(t *testing.T, fake *fakeECS) Client {
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+
+	return NewClient(aws.Config{
+		Region:       "us-east-1",
+		Credentials:  aws.AnonymousCredentials{},
+		BaseEndpoint: aws.String(server.URL),
+	})
+}
+
+func fakeArns(kind string, count int) []string {
+	var arns []string
+	for i := range count {
+		arns = append(arns, fmt.Sprintf("arn:aws:ecs:us-east-1:123456789012:%s/%d", kind, i))
+	}
+	return arns
+}
+
+func TestAwsClient_ListClusters_Paginates(t *testing.T) {
+	fake := newFakeECS(t)
+	fake.clusters = fakeArns("cluster", 7)
+	client := newFakeClient(t, fake)
+
+	clusterArns, err := client.ListClusters(context.Background())
+
+	require.NoError(t, err)
+	assert.Equal(t, fake.clusters, clusterArns)
+	assert.Equal(t, 3, fake.calls["ListClusters"])
+}
+
+func TestAwsClient_ListServices_Paginates(t *testing.T) {
+	fake := newFakeECS(t)
+	fake.services = fakeArns("service", 25)
+	client := newFakeClient(t, fake)
+
+	serviceArns, err := client.ListServices(context.Background(), "cluster")
+
+	require.NoError(t, err)
+	assert.Equal(t, fake.services, serviceArns)
+	assert.Equal(t, 9, fake.calls["ListServices"])
+}
+
+func TestAwsClient_ListTasks_Paginates(t *testing.T) {
+	fake := newFakeECS(t)
+	fake.tasks = fakeArns("task", 4)
+	client := newFakeClient(t, fake)
+
+	taskArns, err := client.ListTasks(
+		context.Background(),
+		"cluster",
+		"service",
+		types.DesiredStatusRunning,
+	)
+
+	require.NoError(t, err)
+	assert.Equal(t, fake.tasks, taskArns)
+	assert.Equal(t, 2, fake.calls["ListTasks"])
+}
+
+func TestAwsClient_ListTaskDefinitions_Paginates(t *testing.T) {
+	fake := newFakeECS(t)
+	fake.revisions = fakeArns("task-definition", 150)
+	client := newFakeClient(t, fake)
+
+	taskDefinitionArns, err := client.ListTaskDefinitions(context.Background(), "family")
+
+	require.NoError(t, err)
+	assert.Equal(t, fake.revisions, taskDefinitionArns)
+	assert.Equal(t, 50, fake.calls["ListTaskDefinitions"])
+}
+
+func TestAwsClient_ListTasks_Empty(t *testing.T) {
+	client := newFakeClient(t, newFakeECS(t))
+
+	taskArns, err := client.ListTasks(
+		context.Background(),
+		"cluster",
+		"service",
+		types.DesiredStatusStopped,
+	)
+
+	assert.NoError(t, err)
+	assert.Empty(t, taskArns)
+}
+
+func TestAwsClient_ListClusters_Empty(t *testing.T) {
+	client := newFakeClient(t, newFakeECS(t))
+
+	_, err := client.ListClusters(context.Background())
+
+	assert.EqualError(t, err, "no clusters found")
+}
+
+func TestAwsClient_DescribeClusters_Batches(t *testing.T) {
+	fake := newFakeECS(t)
+	clusterArns := fakeArns("cluster", 201)
+	client := newFakeClient(t, fake)
+
+	clusters, err := client.DescribeClusters(context.Background(), clusterArns)
+
+	require.NoError(t, err)
+	require.Len(t, clusters, len(clusterArns))
+	for i, cluster := range clusters {
+		assert.Equal(t, clusterArns[i], *cluster.ClusterArn)
+	}
+	assert.Equal(t, 3, fake.calls["DescribeClusters"])
+	assert.Equal(t, []any{"CONFIGURATIONS"}, fake.inputs["DescribeClusters"]["include"])
+}
+
+func TestAwsClient_DescribeServices_Batches(t *testing.T) {
+	fake := newFakeECS(t)
+	serviceArns := fakeArns("service", 25)
+	client := newFakeClient(t, fake)
+
+	services, err := client.DescribeServices(context.Background(), "cluster", serviceArns)
+
+	require.NoError(t, err)
+	require.Len(t, services, len(serviceArns))
+	for i, service := range services {
+		assert.Equal(t, serviceArns[i], *service.ServiceArn)
+	}
+	assert.Equal(t, 3, fake.calls["DescribeServices"])
+}
+
+func TestAwsClient_DescribeTasks_Batches(t *testing.T) {
+	fake := newFakeECS(t)
+	taskArns := fakeArns("task", 100)
+	client := newFakeClient(t, fake)
+
+	tasks, err := client.DescribeTasks(context.Background(), "cluster", taskArns)
+
+	require.NoError(t, err)
+	require.Len(t, tasks, len(taskArns))
+	assert.Equal(t, 1, fake.calls["DescribeTasks"])
+}
+
+func TestAwsClient_ListServiceDeployments_Paginates(t *testing.T) {
+	fake := newFakeECS(t)
+	fake.deployments = fakeArns("service-deployment", 5)
+	client := newFakeClient(t, fake)
+
+	serviceDeployments, err := client.ListServiceDeployments(
+		context.Background(),
+		"cluster",
+		"service",
+		[]types.ServiceDeploymentStatus{types.ServiceDeploymentStatusSuccessful},
+	)
+
+	require.NoError(t, err)
+	var serviceDeploymentArns []string
+	for _, serviceDeployment := range serviceDeployments {
+		serviceDeploymentArns = append(serviceDeploymentArns, *serviceDeployment.ServiceDeploymentArn)
+	}
+	expected := slices.Clone(fake.deployments)
+	slices.Reverse(expected)
+	assert.Equal(t, expected, serviceDeploymentArns)
+	assert.Equal(t, 2, fake.calls["ListServiceDeployments"])
+	assert.Equal(t, []any{"SUCCESSFUL"}, fake.inputs["ListServiceDeployments"]["status"])
+}
+
+func TestAwsClient_DescribeServiceRevisions_Batches(t *testing.T) {
+	fake := newFakeECS(t)
+	serviceRevisionArns := fakeArns("service-revision", 45)
+	client := newFakeClient(t, fake)
+
+	serviceRevisions, err := client.DescribeServiceRevisions(context.Background(), serviceRevisionArns)
+
+	require.NoError(t, err)
+	require.Len(t, serviceRevisions, len(serviceRevisionArns))
+	for i, serviceRevision := range serviceRevisions {
+		assert.Equal(t, serviceRevisionArns[i], *serviceRevision.ServiceRevisionArn)
+	}
+	assert.Equal(t, 3, fake.calls["DescribeServiceRevisions"])
+}
+
+func TestAwsClient_RegisterTaskDefinition(t *testing.T) {
+	fake := newFakeECS(t)
+	fake.tags = []map[string]any{{"key": "team", "value": "payments"}}
+	client := newFakeClient(t, fake)
+
+	taskDefinition, err := client.RegisterTaskDefinition(context.Background(), &types.TaskDefinition{
+		TaskDefinitionArn: aws.String("arn:aws:ecs:us-east-1:123456789012:task-definition/api:1"),
+		Family:            aws.String("api"),
+		Revision:          1,
+		Status:            types.TaskDefinitionStatusActive,
+		Cpu:               aws.String("256"),
+		Memory:            aws.String("512"),
+		NetworkMode:       types.NetworkModeAwsvpc,
+		ContainerDefinitions: []types.ContainerDefinition{
+			{Name: aws.String("app"), Image: aws.String("api:1.1.0")},
+		},
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, "arn:aws:ecs:us-east-1:123456789012:task-definition/api:2", *taskDefinition.TaskDefinitionArn)
+	input := fake.inputs["RegisterTaskDefinition"]
+	assert.Equal(t, "awsvpc", input["networkMode"])
+	assert.Equal(t, "256", input["cpu"])
+	assert.NotContains(t, input, "taskDefinitionArn")
+	assert.NotContains(t, input, "status")
+	assert.Equal(t, "api:1.1.0", input["containerDefinitions"].([]any)[0].(map[string]any)["image"])
+	assert.Equal(t, []any{map[string]any{"key": "team", "value": "payments"}}, input["tags"])
+	assert.Equal(t, []any{"TAGS"}, fake.inputs["DescribeTaskDefinition"]["include"])
+}
+
+func TestAwsClient_StopTask(t *testing.T) {
+	fake := newFakeECS(t)
+	client := newFakeClient(t, fake)
+
+	task, err := client.StopTask(context.Background(), "cluster", "task", "wedged")
+
+	require.NoError(t, err)
+	assert.Equal(t, "STOPPED", *task.DesiredStatus)
+	assert.Equal(t, "wedged", *task.StoppedReason)
+	assert.Equal(t, map[string]any{"cluster": "cluster", "task": "task", "reason": "wedged"}, fake.inputs["StopTask"])
+}
+
+func TestAwsClient_UpdateService_ForceNewDeployment(t *testing.T) {
+	fake := newFakeECS(t)
+	client := newFakeClient(t, fake)
+
+	_, err := client.UpdateService(
+		context.Background(),
+		&types.Service{ClusterArn: aws.String("cluster"), ServiceArn: aws.String("service")},
+		ServiceConfig{TaskDefinitionArn: "api:1", DesiredCount: aws.Int32(2), ForceNewDeployment: true},
+	)
+
+	require.NoError(t, err)
+	assert.Equal(t, true, fake.inputs["UpdateService"]["forceNewDeployment"])
+	assert.Equal(t, float64(2), fake.inputs["UpdateService"]["desiredCount"])
+}
+
+func TestAwsClient_UpdateService_EnableExecuteCommand(t *testing.T) {
+	fake := newFakeECS(t)
+	client := newFakeClient(t, fake)
+	service := &types.Service{ClusterArn: aws.String("cluster"), ServiceArn: aws.String("service")}
+
+	_, err := client.UpdateService(context.Background(), service, ServiceConfig{TaskDefinitionArn: "api:1"})
+	require.NoError(t, err)
+	assert.NotContains(t, fake.inputs["UpdateService"], "enableExecuteCommand")
+	assert.NotContains(t, fake.inputs["UpdateService"], "desiredCount")
+
+	_, err = client.UpdateService(
+		context.Background(),
+		service,
+		ServiceConfig{TaskDefinitionArn: "api:1", EnableExecuteCommand: aws.Bool(true)},
+	)
+	require.NoError(t, err)
+	assert.Equal(t, true, fake.inputs["UpdateService"]["enableExecuteCommand"])
+}
+
+func TestAwsClient_RunTask(t *testing.T) {
+	fake := newFakeECS(t)
+	client := newFakeClient(t, fake)
+
+	task, err := client.RunTask(
+		context.Background(),
+		&types.Service{
+			ClusterArn:     aws.String("cluster"),
+			TaskDefinition: aws.String("api:3"),
+			LaunchType:     types.LaunchTypeFargate,
+			NetworkConfiguration: &types.NetworkConfiguration{
+				AwsvpcConfiguration: &types.AwsVpcConfiguration{Subnets: []string{"subnet-1"}},
+			},
+		},
+		[]types.ContainerOverride{{Name: aws.String("app"), Command: []string{"migrate"}}},
+	)
+
+	require.NoError(t, err)
+	assert.Equal(t, "arn:aws:ecs:us-east-1:123456789012:task/cluster/run", *task.TaskArn)
+	input := fake.inputs["RunTask"]
+	assert.Equal(t, "api:3", input["taskDefinition"])
+	assert.Equal(t, "FARGATE", input["launchType"])
+	assert.NotContains(t, input, "capacityProviderStrategy")
+	assert.Equal(t, []any{"subnet-1"}, input["networkConfiguration"].(map[string]any)["awsvpcConfiguration"].(map[string]any)["subnets"])
+	assert.Equal(t, []any{"migrate"}, input["overrides"].(map[string]any)["containerOverrides"].([]any)[0].(map[string]any)["command"])
+}
+
+func TestAwsClient_RunTask_CapacityProviderStrategy(t *testing.T) {
+	fake := newFakeECS(t)
+	client := newFakeClient(t, fake)
+
+	_, err := client.RunTask(
+		context.Background(),
+		&types.Service{
+			ClusterArn:     aws.String("cluster"),
+			TaskDefinition: aws.String("api:3"),
+			CapacityProviderStrategy: []types.CapacityProviderStrategyItem{
+				{CapacityProvider: aws.String("FARGATE_SPOT"), Weight: 1},
+			},
+		},
+		nil,
+	)
+
+	require.NoError(t, err)
+	input := fake.inputs["RunTask"]
+	assert.NotContains(t, input, "launchType")
+	assert.Equal(t, "FARGATE_SPOT", input["capacityProviderStrategy"].([]any)[0].(map[string]any)["capacityProvider"])
+}
+
+func TestAwsClient_ExecuteCommand_SessionClient(t *testing.T) {
+	t.Setenv(SessionClientEnvVar, "")
+	fake := newFakeECS(t)
+	client := newFakeClient(t, fake)
+
+	cmd, err := client.ExecuteCommand(
+		context.Background(),
+		&types.Cluster{ClusterArn: aws.String("cluster"), ClusterName: aws.String("cluster")},
+		"arn:aws:ecs:us-east-1:123456789012:task/cluster/task",
+		&types.Container{Name: aws.String("app"), RuntimeId: aws.String("runtime")},
+		"/bin/sh",
+		true,
+	)
+
+	require.NoError(t, err)
+	executable, err := os.Executable()
+	require.NoError(t, err)
+	assert.Equal(t, []string{executable, SessionClientCommand}, cmd.Args)
+	assert.Contains(
+		t,
+		cmd.Env,
+		session.EnvVar+`={"SessionId":"ecs-execute-command-1",`+
+			`"StreamUrl":"wss://ssmmessages.us-east-1.amazonaws.com/v1/data-channel/ecs-execute-command-1",`+
+			`"TokenValue":"token"}`,
+	)
+	assert.Equal(t, "/bin/sh", fake.inputs["ExecuteCommand"]["command"])
+}
+
+func TestAwsClient_ExecuteCommand_Plugin(t *testing.T) {
+	t.Setenv(SessionClientEnvVar, "plugin")
+	t.Setenv("PATH", t.TempDir())
+	fake := newFakeECS(t)
+	client := newFakeClient(t, fake)
+
+	_, err := client.ExecuteCommand(
+		context.Background(),
+		&types.Cluster{ClusterArn: aws.String("cluster"), ClusterName: aws.String("cluster")},
+		"arn:aws:ecs:us-east-1:123456789012:task/cluster/task",
+		&types.Container{Name: aws.String("app"), RuntimeId: aws.String("runtime")},
+		"/bin/sh",
+		true,
+	)
+
+	assert.ErrorIs(t, err, exec.ErrNotFound)
+	// The session is not started without a program to run it
+	assert.Zero(t, fake.calls["ExecuteCommand"])
+}
+
+func TestAwsClient_ExecuteCommand_EncryptedPlugin(t *testing.T) {
+	t.Setenv(SessionClientEnvVar, "")
+	dir := t.TempDir()
+	pluginPath := filepath.Join(dir, "session-manager-plugin")
+	require.NoError(t, os.WriteFile(pluginPath, []byte("#!/bin/sh\n"), 0o755))
+	t.Setenv("PATH", dir)
+	fake := newFakeECS(t)
+	client := newFakeClient(t, fake)
+
+	cmd, err := client.ExecuteCommand(
+		context.Background(),
+		&types.Cluster{
+			ClusterArn:  aws.String("cluster"),
+			ClusterName: aws.String("cluster"),
+			Configuration: &types.ClusterConfiguration{
+				ExecuteCommandConfiguration: &types.ExecuteCommandConfiguration{KmsKeyId: aws.String("key")},
+			},
+		},
+		"arn:aws:ecs:us-east-1:123456789012:task/cluster/task",
+		&types.Container{Name: aws.String("app"), RuntimeId: aws.String("runtime")},
+		"/bin/sh",
+		true,
+	)
+
+	require.NoError(t, err)
+	assert.Equal(t, pluginPath, cmd.Path)
+}
+
+func TestChunk(t *testing.T) {
+	assert.Nil(t, chunk([]int{}, 2))
+	assert.Equal(t, [][]int{{1, 2}, {3, 4}, {5}}, chunk([]int{1, 2, 3, 4, 5}, 2))
+	assert.Equal(t, [][]int{{1, 2}}, chunk([]int{1, 2}, 2))
+}
+
+func TestEcsTarget(t *testing.T) {
+	cluster := &types.Cluster{ClusterName: aws.String("my-cluster")}
+	container := &types.Container{RuntimeId: aws.String("1234-5678")}
+
+	target, err := ecsTarget(
+		cluster,
+		"arn:aws:ecs:us-east-1:123456789012:task/my-cluster/abcd",
+		container,
+	)
+
+	require.NoError(t, err)
+	assert.Equal(t, "ecs:my-cluster_abcd_1234-5678", target)
+
+	target, err = ecsTarget(
+		cluster,
+		"arn:aws:ecs:us-east-1:123456789012:task/abcd",
+		container,
+	)
+
+	require.NoError(t, err)
+	assert.Equal(t, "ecs:my-cluster_abcd_1234-5678", target)
+
+	_, err = ecsTarget(cluster, "invalid", container)
+	assert.Error(t, err)
+}
@@ -2,22 +2,60 @@ package client
 
 import (
 	"context"
+	"errors"
 	"os/exec"
 	"time"
 
+	"github.com/aws/aws-sdk-go-v2/aws"
 	logsTypes "github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs/types"
 	ecsTypes "github.com/aws/aws-sdk-go-v2/service/ecs/types"
 )
 
+// ErrNoClusters is returned when listing clusters finds none.
+var ErrNoClusters = errors.New("no clusters found")
+
+const (
+	// SessionClientCommand is the hidden iecs command running the sessions
+	// started by ExecuteCommand, in place of session-manager-plugin.
+	SessionClientCommand = "session-client"
+	// SessionClientEnvVar set to "plugin" runs the sessions started by
+	// ExecuteCommand with session-manager-plugin instead. Sessions encrypted
+	// with KMS use the plugin regardless, when it is installed.
+	SessionClientEnvVar = "IECS_SESSION_CLIENT"
+)
+
+// SessionKmsKeyId returns the KMS key the sessions started in the cluster are
+// encrypted with, if any.
+func SessionKmsKeyId(cluster *ecsTypes.Cluster) string {
+	if cluster.Configuration == nil || cluster.Configuration.ExecuteCommandConfiguration == nil {
+		return ""
+	}
+	return aws.ToString(cluster.Configuration.ExecuteCommandConfiguration.KmsKeyId)
+}
+
 // EventHandler is a function that handles log events.
 type LiveTailHandlers struct {
 	Start  func()
 	Update func(logsTypes.LiveTailSessionLogEvent)
 }
 
+// ServiceConfig is the configuration applied to a service. With
+// ForceNewDeployment, the tasks are replaced even when nothing else changes.
+// DesiredCount and EnableExecuteCommand are left unchanged when nil.
 type ServiceConfig struct {
-	TaskDefinitionArn string
-	DesiredCount      int32
+	TaskDefinitionArn    string
+	DesiredCount         *int32
+	ForceNewDeployment   bool
+	EnableExecuteCommand *bool
+}
+
+// PortForwardingConfig describes a port forwarding session. When RemoteHost is
+// set, the traffic is forwarded to that host as reached from the container
+// instead of to the container itself.
+type PortForwardingConfig struct {
+	LocalPort  int
+	RemotePort int
+	RemoteHost string
 }
 
 // Client interface combines ECS and CloudWatch Logs operations.
@@ -33,20 +71,58 @@ type Client interface {
 		clusterArn string,
 		serviceArns []string,
 	) ([]ecsTypes.Service, error)
+	// ListServiceDeployments returns the deployments of a service with the
+	// given statuses, newest first. ECS only records the deployments of
+	// services using the rolling update deployment controller.
+	ListServiceDeployments(
+		ctx context.Context,
+		clusterArn string,
+		serviceArn string,
+		statuses []ecsTypes.ServiceDeploymentStatus,
+	) ([]ecsTypes.ServiceDeploymentBrief, error)
+	// DescribeServiceRevisions returns the configuration, such as the task
+	// definition, a service was deployed with.
+	DescribeServiceRevisions(
+		ctx context.Context,
+		serviceRevisionArns []string,
+	) ([]ecsTypes.ServiceRevision, error)
+	// UpdateService starts a deployment of the service without waiting for
+	// it to complete.
 	UpdateService(
 		ctx context.Context,
 		service *ecsTypes.Service,
 		config ServiceConfig,
-		waitTimeout time.Duration,
 	) (*ecsTypes.Service, error)
 
 	// Tasks
-	ListTasks(ctx context.Context, clusterArn string, serviceArn string) ([]string, error)
+	// ListTasks returns the tasks of a service with the given desired status.
+	// Stopped tasks are only kept by ECS for a short period of time.
+	ListTasks(
+		ctx context.Context,
+		clusterArn string,
+		serviceArn string,
+		desiredStatus ecsTypes.DesiredStatus,
+	) ([]string, error)
 	DescribeTasks(
 		ctx context.Context,
 		clusterArn string,
 		taskArns []string,
 	) ([]ecsTypes.Task, error)
+	// RunTask starts a standalone task from the task definition of the
+	// service, using its network configuration, launch type or capacity
+	// provider strategy and placement settings.
+	RunTask(
+		ctx context.Context,
+		service *ecsTypes.Service,
+		overrides []ecsTypes.ContainerOverride,
+	) (*ecsTypes.Task, error)
+	// StopTask stops a running task, recording the given reason on it.
+	StopTask(
+		ctx context.Context,
+		clusterArn string,
+		taskArn string,
+		reason string,
+	) (*ecsTypes.Task, error)
 
 	// Task Definitions
 	ListTaskDefinitions(
@@ -57,6 +133,13 @@ type Client interface {
 		ctx context.Context,
 		taskDefinitionArn string,
 	) (*ecsTypes.TaskDefinition, error)
+	// RegisterTaskDefinition registers a new revision of the task definition
+	// family with the settings of the given task definition, and the tags of
+	// the revision it was described from.
+	RegisterTaskDefinition(
+		ctx context.Context,
+		taskDefinition *ecsTypes.TaskDefinition,
+	) (*ecsTypes.TaskDefinition, error)
 
 	// Others
 	ExecuteCommand(
@@ -67,10 +150,26 @@ type Client interface {
 		command string,
 		interactive bool,
 	) (*exec.Cmd, error)
+	StartPortForwardingSession(
+		ctx context.Context,
+		cluster *ecsTypes.Cluster,
+		taskArn string,
+		container *ecsTypes.Container,
+		config PortForwardingConfig,
+	) (*exec.Cmd, error)
 	StartLiveTail(
 		ctx context.Context,
 		logGroupName string,
 		streamPrefix string,
 		handler LiveTailHandlers,
 	) error
+	// FilterLogEvents returns the events logged to the given streams between
+	// startTime and endTime, ordered by timestamp.
+	FilterLogEvents(
+		ctx context.Context,
+		logGroupName string,
+		streamNames []string,
+		startTime time.Time,
+		endTime time.Time,
+	) ([]logsTypes.FilteredLogEvent, error)
 }
@@ -6,6 +6,7 @@ import (
 	"context"
 	"fmt"
 	"os/exec"
+	"strings"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
@@ -63,9 +64,11 @@ func (c DemoClient) DescribeServices(
 			ClusterArn:   aws.String(clusterArn),
 			Status:       aws.String("ACTIVE"),
 			DesiredCount: 1,
+			RunningCount: 1,
 			TaskDefinition: aws.String(
 				"arn:aws:ecs:us-east-1:123456789012:task-definition/task-def-1:1",
 			),
+			EnableExecuteCommand: true,
 		})
 	}
 	return services, nil
@@ -75,17 +78,62 @@ func (c DemoClient) UpdateService(
 	ctx context.Context,
 	service *ecsTypes.Service,
 	input ServiceConfig,
-	waitTimeout time.Duration,
 ) (*ecsTypes.Service, error) {
-	time.Sleep(waitTimeout)
 	return service, nil
 }
 
+func (c DemoClient) ListServiceDeployments(
+	ctx context.Context,
+	clusterArn string,
+	serviceArn string,
+	statuses []ecsTypes.ServiceDeploymentStatus,
+) ([]ecsTypes.ServiceDeploymentBrief, error) {
+	serviceDeployments := []ecsTypes.ServiceDeploymentBrief{}
+	for i, revision := range []string{"1", "2"} {
+		serviceDeployments = append(serviceDeployments, ecsTypes.ServiceDeploymentBrief{
+			ClusterArn: aws.String(clusterArn),
+			ServiceArn: aws.String(serviceArn),
+			ServiceDeploymentArn: aws.String(
+				fmt.Sprintf("arn:aws:ecs:us-east-1:123456789012:service-deployment/cluster-1/service-1/%s", revision),
+			),
+			TargetServiceRevisionArn: aws.String(
+				fmt.Sprintf("arn:aws:ecs:us-east-1:123456789012:service-revision/cluster-1/service-1/%s", revision),
+			),
+			Status:    ecsTypes.ServiceDeploymentStatusSuccessful,
+			CreatedAt: aws.Time(time.Now().Add(-time.Duration(i+1) * time.Hour)),
+		})
+	}
+	return serviceDeployments, nil
+}
+
+func (c DemoClient) DescribeServiceRevisions(
+	ctx context.Context,
+	serviceRevisionArns []string,
+) ([]ecsTypes.ServiceRevision, error) {
+	serviceRevisions := []ecsTypes.ServiceRevision{}
+	for _, arn := range serviceRevisionArns {
+		revision := arn[strings.LastIndex(arn, "/")+1:]
+		serviceRevisions = append(serviceRevisions, ecsTypes.ServiceRevision{
+			ServiceRevisionArn: aws.String(arn),
+			TaskDefinition: aws.String(
+				fmt.Sprintf("arn:aws:ecs:us-east-1:123456789012:task-definition/task-def-1:%s", revision),
+			),
+		})
+	}
+	return serviceRevisions, nil
+}
+
 func (c DemoClient) ListTasks(
 	ctx context.Context,
 	clusterArn string,
 	serviceArn string,
+	desiredStatus ecsTypes.DesiredStatus,
 ) ([]string, error) {
+	if desiredStatus == ecsTypes.DesiredStatusStopped {
+		return []string{
+			"arn:aws:ecs:us-east-1:123456789012:task/cluster-1/task-3",
+		}, nil
+	}
 	return []string{
 		"arn:aws:ecs:us-east-1:123456789012:task/cluster-1/task-1",
 		"arn:aws:ecs:us-east-1:123456789012:task/cluster-1/task-2",
@@ -99,29 +147,81 @@ func (c DemoClient) DescribeTasks(
 ) ([]ecsTypes.Task, error) {
 	tasks := []ecsTypes.Task{}
 	for _, arn := range taskArns {
-		tasks = append(tasks, ecsTypes.Task{
+		task := ecsTypes.Task{
 			TaskArn:       aws.String(arn),
 			ClusterArn:    aws.String(clusterArn),
 			LastStatus:    aws.String("RUNNING"),
 			DesiredStatus: aws.String("RUNNING"),
+			CreatedAt:     aws.Time(time.Now().Add(-time.Hour)),
 			TaskDefinitionArn: aws.String(
 				"arn:aws:ecs:us-east-1:123456789012:task-definition/task-def-1:1",
 			),
+			EnableExecuteCommand: true,
 			Containers: []ecsTypes.Container{
 				{
-					Name:      aws.String("container-1"),
-					RuntimeId: aws.String("runtime-id-1"),
+					Name:          aws.String("container-1"),
+					RuntimeId:     aws.String("runtime-id-1"),
+					ManagedAgents: demoManagedAgents(),
 				},
 				{
-					Name:      aws.String("container-2"),
-					RuntimeId: aws.String("runtime-id-2"),
+					Name:          aws.String("container-2"),
+					RuntimeId:     aws.String("runtime-id-2"),
+					ManagedAgents: demoManagedAgents(),
 				},
 			},
-		})
+		}
+		// The one-off task started by RunTask completes right away
+		if strings.HasSuffix(arn, "task-run") {
+			task.LastStatus = aws.String("STOPPED")
+			task.DesiredStatus = aws.String("STOPPED")
+			task.StoppedAt = aws.Time(time.Now())
+			task.StoppedReason = aws.String("Essential container in task exited")
+			task.Containers[0].ExitCode = aws.Int32(0)
+			task.Containers[1].ExitCode = aws.Int32(0)
+		}
+		if strings.HasSuffix(arn, "task-3") {
+			task.LastStatus = aws.String("STOPPED")
+			task.DesiredStatus = aws.String("STOPPED")
+			task.StoppedAt = aws.Time(time.Now().Add(-5 * time.Minute))
+			task.StoppedReason = aws.String("Essential container in task exited")
+			task.Containers[0].ExitCode = aws.Int32(1)
+			task.Containers[1].ExitCode = aws.Int32(0)
+		}
+		tasks = append(tasks, task)
 	}
 	return tasks, nil
 }
 
+func (c DemoClient) RunTask(
+	ctx context.Context,
+	service *ecsTypes.Service,
+	overrides []ecsTypes.ContainerOverride,
+) (*ecsTypes.Task, error) {
+	return &ecsTypes.Task{
+		TaskArn:           aws.String("arn:aws:ecs:us-east-1:123456789012:task/cluster-1/task-run"),
+		ClusterArn:        service.ClusterArn,
+		TaskDefinitionArn: service.TaskDefinition,
+		LastStatus:        aws.String("PROVISIONING"),
+		DesiredStatus:     aws.String("RUNNING"),
+		CreatedAt:         aws.Time(time.Now()),
+	}, nil
+}
+
+func (c DemoClient) StopTask(
+	ctx context.Context,
+	clusterArn string,
+	taskArn string,
+	reason string,
+) (*ecsTypes.Task, error) {
+	return &ecsTypes.Task{
+		TaskArn:       aws.String(taskArn),
+		ClusterArn:    aws.String(clusterArn),
+		LastStatus:    aws.String("RUNNING"),
+		DesiredStatus: aws.String("STOPPED"),
+		StoppedReason: aws.String(reason),
+	}, nil
+}
+
 func (c DemoClient) ListTaskDefinitions(
 	ctx context.Context,
 	familyPrefix string,
@@ -140,6 +240,7 @@ func (c DemoClient) DescribeTaskDefinition(
 		TaskDefinitionArn: aws.String(taskDefinitionArn),
 		Family:            aws.String("task-def-1"),
 		Revision:          1,
+		TaskRoleArn:       aws.String("arn:aws:iam::123456789012:role/task-role-1"),
 		ContainerDefinitions: []ecsTypes.ContainerDefinition{
 			{
 				Name: aws.String("container-1"),
@@ -167,6 +268,22 @@ func (c DemoClient) DescribeTaskDefinition(
 	}, nil
 }
 
+func (c DemoClient) RegisterTaskDefinition(
+	ctx context.Context,
+	taskDefinition *ecsTypes.TaskDefinition,
+) (*ecsTypes.TaskDefinition, error) {
+	registered := *taskDefinition
+	registered.Revision++
+	registered.TaskDefinitionArn = aws.String(
+		fmt.Sprintf(
+			"arn:aws:ecs:us-east-1:123456789012:task-definition/%s:%d",
+			*taskDefinition.Family,
+			registered.Revision,
+		),
+	)
+	return &registered, nil
+}
+
 func (c DemoClient) StartLiveTail(
 	ctx context.Context,
 	logGroupName string,
@@ -184,6 +301,26 @@ func (c DemoClient) StartLiveTail(
 	return nil
 }
 
+func (c DemoClient) FilterLogEvents(
+	ctx context.Context,
+	logGroupName string,
+	streamNames []string,
+	startTime time.Time,
+	endTime time.Time,
+) ([]logsTypes.FilteredLogEvent, error) {
+	events := []logsTypes.FilteredLogEvent{}
+	for i, streamName := range streamNames {
+		for j := range 3 {
+			events = append(events, logsTypes.FilteredLogEvent{
+				LogStreamName: aws.String(streamName),
+				Message:       aws.String(fmt.Sprintf("past log message %d", j)),
+				Timestamp:     aws.Int64(startTime.Add(time.Duration(i+j) * time.Second).UnixMilli()),
+			})
+		}
+	}
+	return events, nil
+}
+
 func (c DemoClient) ExecuteCommand(
 	ctx context.Context,
 	cluster *ecsTypes.Cluster,
@@ -195,3 +332,24 @@ func (c DemoClient) ExecuteCommand(
 	cmd := exec.Command(command)
 	return cmd, nil
 }
+
+func (c DemoClient) StartPortForwardingSession(
+	ctx context.Context,
+	cluster *ecsTypes.Cluster,
+	taskArn string,
+	container *ecsTypes.Container,
+	config PortForwardingConfig,
+) (*exec.Cmd, error) {
+	cmd := exec.Command(
+		"echo",
+		fmt.Sprintf("Port %d opened for sessionId demo", config.LocalPort),
+	)
+	return cmd, nil
+}
+
+func demoManagedAgents() []ecsTypes.ManagedAgent {
+	return []ecsTypes.ManagedAgent{{
+		Name:       ecsTypes.ManagedAgentNameExecuteCommandAgent,
+		LastStatus: aws.String("RUNNING"),
+	}}
+}
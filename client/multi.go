@@ -0,0 +1,372 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os/exec"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	logsTypes "github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs/types"
+	ecsTypes "github.com/aws/aws-sdk-go-v2/service/ecs/types"
+)
+
+var _ Client = &MultiClient{}
+
+// Target is a client bound to an AWS profile and region.
+type Target struct {
+	Profile string
+	Region  string
+	Client  Client
+}
+
+func (t Target) String() string {
+	profile := t.Profile
+	if profile == "" {
+		profile = "default"
+	}
+	region := t.Region
+	if region == "" {
+		region = "default region"
+	}
+	return fmt.Sprintf("%s (%s)", profile, region)
+}
+
+// MultiClient lists the clusters of several targets concurrently. Every other
+// call goes to the target owning the cluster, service, task or task definition
+// it refers to, which is told by the region and account of its ARN.
+type MultiClient struct {
+	targets []Target
+	mu      sync.Mutex
+	owners  map[string]Client
+}
+
+// NewMultiClient creates a client that discovers clusters across the given
+// targets.
+func NewMultiClient(targets []Target) *MultiClient {
+	return &MultiClient{
+		targets: targets,
+		owners:  map[string]Client{},
+	}
+}
+
+// ForCluster returns the client to use for the resources of the given
+// cluster. The calls that don't refer to an ARN, such as the ones about logs,
+// can't be routed by a MultiClient, so they must go through this client.
+func ForCluster(c Client, clusterArn string) (Client, error) {
+	if multi, ok := c.(*MultiClient); ok {
+		return multi.route(clusterArn)
+	}
+	return c, nil
+}
+
+// ListClusters lists the clusters of every target. Targets that fail are
+// reported and skipped, unless all of them fail.
+func (c *MultiClient) ListClusters(ctx context.Context) ([]string, error) {
+	results := make([][]string, len(c.targets))
+	errs := make([]error, len(c.targets))
+
+	var wg sync.WaitGroup
+	for i, target := range c.targets {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			clusterArns, err := target.Client.ListClusters(ctx)
+			if err != nil && !errors.Is(err, ErrNoClusters) {
+				errs[i] = fmt.Errorf("%s: %w", target, err)
+			}
+			results[i] = clusterArns
+		}()
+	}
+	wg.Wait()
+
+	failed := 0
+	for _, err := range errs {
+		if err != nil {
+			failed++
+		}
+	}
+	if failed == len(c.targets) {
+		return nil, errors.Join(errs...)
+	}
+	for _, err := range errs {
+		if err != nil {
+			log.Printf("Unable to list clusters of %v", err)
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var clusterArns []string
+	for i, targetArns := range results {
+		for _, clusterArn := range targetArns {
+			key, err := arnScope(clusterArn)
+			if err != nil {
+				return nil, err
+			}
+			// The same account may be reachable through several profiles
+			if owner, ok := c.owners[key]; ok && owner != c.targets[i].Client {
+				continue
+			}
+			c.owners[key] = c.targets[i].Client
+			clusterArns = append(clusterArns, clusterArn)
+		}
+	}
+
+	if len(clusterArns) == 0 {
+		return nil, ErrNoClusters
+	}
+
+	return clusterArns, nil
+}
+
+func (c *MultiClient) DescribeClusters(
+	ctx context.Context,
+	clusterArns []string,
+) ([]ecsTypes.Cluster, error) {
+	var owners []Client
+	byOwner := map[Client][]string{}
+	for _, clusterArn := range clusterArns {
+		owner, err := c.route(clusterArn)
+		if err != nil {
+			return nil, err
+		}
+		if !slices.Contains(owners, owner) {
+			owners = append(owners, owner)
+		}
+		byOwner[owner] = append(byOwner[owner], clusterArn)
+	}
+
+	var clusters []ecsTypes.Cluster
+	for _, owner := range owners {
+		ownerClusters, err := owner.DescribeClusters(ctx, byOwner[owner])
+		if err != nil {
+			return nil, err
+		}
+		clusters = append(clusters, ownerClusters...)
+	}
+
+	return clusters, nil
+}
+
+// route returns the client of the target owning the resource with the given
+// ARN.
+func (c *MultiClient) route(arn string) (Client, error) {
+	key, err := arnScope(arn)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	owner, ok := c.owners[key]
+	if !ok {
+		return nil, fmt.Errorf("no profile found for %s, list the clusters first", arn)
+	}
+	return owner, nil
+}
+
+// arnScope returns the region and account of an ARN.
+func arnScope(arn string) (string, error) {
+	// arn:partition:service:region:account:resource
+	parts := strings.SplitN(arn, ":", 6)
+	if len(parts) < 6 || parts[0] != "arn" {
+		return "", fmt.Errorf("invalid ARN %s", arn)
+	}
+	return parts[3] + ":" + parts[4], nil
+}
+
+// errNoArn is returned by the calls that can't be routed, see ForCluster.
+var errNoArn = errors.New("unable to tell which profile and region to use, the client of the cluster is required")
+
+func (c *MultiClient) ListServices(ctx context.Context, clusterArn string) ([]string, error) {
+	client, err := c.route(clusterArn)
+	if err != nil {
+		return nil, err
+	}
+	return client.ListServices(ctx, clusterArn)
+}
+
+func (c *MultiClient) DescribeServices(
+	ctx context.Context,
+	clusterArn string,
+	serviceArns []string,
+) ([]ecsTypes.Service, error) {
+	client, err := c.route(clusterArn)
+	if err != nil {
+		return nil, err
+	}
+	return client.DescribeServices(ctx, clusterArn, serviceArns)
+}
+
+func (c *MultiClient) UpdateService(
+	ctx context.Context,
+	service *ecsTypes.Service,
+	config ServiceConfig,
+) (*ecsTypes.Service, error) {
+	client, err := c.route(*service.ServiceArn)
+	if err != nil {
+		return nil, err
+	}
+	return client.UpdateService(ctx, service, config)
+}
+
+func (c *MultiClient) ListTasks(
+	ctx context.Context,
+	clusterArn string,
+	serviceArn string,
+	desiredStatus ecsTypes.DesiredStatus,
+) ([]string, error) {
+	client, err := c.route(clusterArn)
+	if err != nil {
+		return nil, err
+	}
+	return client.ListTasks(ctx, clusterArn, serviceArn, desiredStatus)
+}
+
+func (c *MultiClient) RunTask(
+	ctx context.Context,
+	service *ecsTypes.Service,
+	overrides []ecsTypes.ContainerOverride,
+) (*ecsTypes.Task, error) {
+	client, err := c.route(*service.ServiceArn)
+	if err != nil {
+		return nil, err
+	}
+	return client.RunTask(ctx, service, overrides)
+}
+
+func (c *MultiClient) StopTask(
+	ctx context.Context,
+	clusterArn string,
+	taskArn string,
+	reason string,
+) (*ecsTypes.Task, error) {
+	client, err := c.route(clusterArn)
+	if err != nil {
+		return nil, err
+	}
+	return client.StopTask(ctx, clusterArn, taskArn, reason)
+}
+
+func (c *MultiClient) ListServiceDeployments(
+	ctx context.Context,
+	clusterArn string,
+	serviceArn string,
+	statuses []ecsTypes.ServiceDeploymentStatus,
+) ([]ecsTypes.ServiceDeploymentBrief, error) {
+	client, err := c.route(clusterArn)
+	if err != nil {
+		return nil, err
+	}
+	return client.ListServiceDeployments(ctx, clusterArn, serviceArn, statuses)
+}
+
+func (c *MultiClient) DescribeServiceRevisions(
+	ctx context.Context,
+	serviceRevisionArns []string,
+) ([]ecsTypes.ServiceRevision, error) {
+	if len(serviceRevisionArns) == 0 {
+		return nil, nil
+	}
+	client, err := c.route(serviceRevisionArns[0])
+	if err != nil {
+		return nil, err
+	}
+	return client.DescribeServiceRevisions(ctx, serviceRevisionArns)
+}
+
+func (c *MultiClient) DescribeTasks(
+	ctx context.Context,
+	clusterArn string,
+	taskArns []string,
+) ([]ecsTypes.Task, error) {
+	client, err := c.route(clusterArn)
+	if err != nil {
+		return nil, err
+	}
+	return client.DescribeTasks(ctx, clusterArn, taskArns)
+}
+
+func (c *MultiClient) ListTaskDefinitions(
+	ctx context.Context,
+	familyPrefix string,
+) ([]string, error) {
+	return nil, errNoArn
+}
+
+func (c *MultiClient) DescribeTaskDefinition(
+	ctx context.Context,
+	taskDefinitionArn string,
+) (*ecsTypes.TaskDefinition, error) {
+	client, err := c.route(taskDefinitionArn)
+	if err != nil {
+		return nil, err
+	}
+	return client.DescribeTaskDefinition(ctx, taskDefinitionArn)
+}
+
+func (c *MultiClient) RegisterTaskDefinition(
+	ctx context.Context,
+	taskDefinition *ecsTypes.TaskDefinition,
+) (*ecsTypes.TaskDefinition, error) {
+	client, err := c.route(*taskDefinition.TaskDefinitionArn)
+	if err != nil {
+		return nil, err
+	}
+	return client.RegisterTaskDefinition(ctx, taskDefinition)
+}
+
+func (c *MultiClient) ExecuteCommand(
+	ctx context.Context,
+	cluster *ecsTypes.Cluster,
+	taskArn string,
+	container *ecsTypes.Container,
+	command string,
+	interactive bool,
+) (*exec.Cmd, error) {
+	client, err := c.route(*cluster.ClusterArn)
+	if err != nil {
+		return nil, err
+	}
+	return client.ExecuteCommand(ctx, cluster, taskArn, container, command, interactive)
+}
+
+func (c *MultiClient) StartPortForwardingSession(
+	ctx context.Context,
+	cluster *ecsTypes.Cluster,
+	taskArn string,
+	container *ecsTypes.Container,
+	config PortForwardingConfig,
+) (*exec.Cmd, error) {
+	client, err := c.route(*cluster.ClusterArn)
+	if err != nil {
+		return nil, err
+	}
+	return client.StartPortForwardingSession(ctx, cluster, taskArn, container, config)
+}
+
+func (c *MultiClient) StartLiveTail(
+	ctx context.Context,
+	logGroupName string,
+	streamPrefix string,
+	handler LiveTailHandlers,
+) error {
+	return errNoArn
+}
+
+func (c *MultiClient) FilterLogEvents(
+	ctx context.Context,
+	logGroupName string,
+	streamNames []string,
+	startTime time.Time,
+	endTime time.Time,
+) ([]logsTypes.FilteredLogEvent, error) {
+	return nil, errNoArn
+}
@@ -0,0 +1,143 @@
+//go:build !DEMO
+
+package client
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/ecs/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// failingClient fails to list clusters, as with an expired profile.
+type failingClient struct {
+	Client
+}
+
+func (failingClient) ListClusters(ctx context.Context) ([]string, error) {
+	return nil, errors.New("token expired")
+}
+
+func TestMultiClient_RoutesByArn(t *testing.T) {
+	east := newFakeECS(t)
+	east.clusters = []string{"arn:aws:ecs:us-east-1:123456789012:cluster/api"}
+	east.tasks = fakeArns("task", 1)
+	west := newFakeECS(t)
+	west.clusters = []string{"arn:aws:ecs:us-west-2:123456789012:cluster/api"}
+	west.tasks = fakeArns("task", 2)
+	empty := newFakeECS(t)
+	client := NewMultiClient([]Target{
+		{Profile: "staging", Region: "us-east-1", Client: newFakeClient(t, east)},
+		{Profile: "staging", Region: "us-west-2", Client: newFakeClient(t, west)},
+		{Profile: "staging", Region: "eu-west-1", Client: newFakeClient(t, empty)},
+	})
+
+	clusterArns, err := client.ListClusters(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, append(east.clusters, west.clusters...), clusterArns)
+
+	// Clusters of several targets are described at once
+	clusters, err := client.DescribeClusters(context.Background(), clusterArns)
+	require.NoError(t, err)
+	assert.Len(t, clusters, 2)
+	assert.Equal(t, 1, east.calls["DescribeClusters"])
+	assert.Equal(t, 1, west.calls["DescribeClusters"])
+
+	taskArns, err := client.ListTasks(context.Background(), west.clusters[0], "api", types.DesiredStatusRunning)
+	require.NoError(t, err)
+	assert.Equal(t, west.tasks, taskArns)
+	taskArns, err = client.ListTasks(context.Background(), east.clusters[0], "api", types.DesiredStatusRunning)
+	require.NoError(t, err)
+	assert.Equal(t, east.tasks, taskArns)
+
+	_, err = client.ListTasks(
+		context.Background(),
+		"arn:aws:ecs:eu-west-1:123456789012:cluster/api",
+		"api",
+		types.DesiredStatusRunning,
+	)
+	assert.EqualError(
+		t,
+		err,
+		"no profile found for arn:aws:ecs:eu-west-1:123456789012:cluster/api, list the clusters first",
+	)
+}
+
+func TestForCluster(t *testing.T) {
+	west := newFakeECS(t)
+	west.clusters = []string{"arn:aws:ecs:us-west-2:123456789012:cluster/api"}
+	west.revisions = []string{"arn:aws:ecs:us-west-2:123456789012:task-definition/api:1"}
+	westClient := newFakeClient(t, west)
+	client := NewMultiClient([]Target{{Region: "us-west-2", Client: westClient}})
+
+	_, err := client.ListTaskDefinitions(context.Background(), "api")
+	assert.ErrorIs(t, err, errNoArn)
+
+	_, err = client.ListClusters(context.Background())
+	require.NoError(t, err)
+
+	clusterClient, err := ForCluster(client, west.clusters[0])
+	require.NoError(t, err)
+	revisions, err := clusterClient.ListTaskDefinitions(context.Background(), "api")
+	require.NoError(t, err)
+	assert.Equal(t, west.revisions, revisions)
+
+	// Other clients are used as they are
+	clusterClient, err = ForCluster(westClient, west.clusters[0])
+	require.NoError(t, err)
+	assert.Equal(t, westClient, clusterClient)
+}
+
+func TestMultiClient_SkipsFailingTargets(t *testing.T) {
+	west := newFakeECS(t)
+	west.clusters = []string{"arn:aws:ecs:us-west-2:123456789012:cluster/api"}
+	client := NewMultiClient([]Target{
+		{Profile: "expired", Region: "us-east-1", Client: failingClient{}},
+		{Profile: "staging", Region: "us-west-2", Client: newFakeClient(t, west)},
+	})
+
+	clusterArns, err := client.ListClusters(context.Background())
+
+	require.NoError(t, err)
+	assert.Equal(t, west.clusters, clusterArns)
+}
+
+func TestMultiClient_AllTargetsFail(t *testing.T) {
+	client := NewMultiClient([]Target{
+		{Profile: "expired", Region: "us-east-1", Client: failingClient{}},
+		{Profile: "expired", Region: "us-west-2", Client: failingClient{}},
+	})
+
+	_, err := client.ListClusters(context.Background())
+
+	assert.EqualError(t, err, "expired (us-east-1): token expired\nexpired (us-west-2): token expired")
+}
+
+func TestMultiClient_DeduplicatesClusters(t *testing.T) {
+	admin := newFakeECS(t)
+	admin.clusters = fakeArns("cluster", 2)
+	readonly := newFakeECS(t)
+	readonly.clusters = fakeArns("cluster", 2)
+	client := NewMultiClient([]Target{
+		{Profile: "admin", Client: newFakeClient(t, admin)},
+		{Profile: "readonly", Client: newFakeClient(t, readonly)},
+	})
+
+	clusterArns, err := client.ListClusters(context.Background())
+
+	require.NoError(t, err)
+	assert.Equal(t, admin.clusters, clusterArns)
+}
+
+func TestMultiClient_NoClusters(t *testing.T) {
+	client := NewMultiClient([]Target{
+		{Region: "us-east-1", Client: newFakeClient(t, newFakeECS(t))},
+	})
+
+	_, err := client.ListClusters(context.Background())
+
+	assert.ErrorIs(t, err, ErrNoClusters)
+}
@@ -0,0 +1,191 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+const currentContextKey = "current-context"
+
+// ConfigFile holds the defaults loaded from the configuration file, e.g.:
+//
+//	theme: dracula
+//	current-context: staging
+//	contexts:
+//	  staging:
+//	    profile: staging
+//	    region: us-east-1
+//	    cluster: staging
+//	    service: api
+//	commands:
+//	  exec:
+//	    command: /bin/sh
+type ConfigFile struct {
+	Theme          string                       `yaml:"theme,omitempty"`
+	CurrentContext string                       `yaml:"current-context,omitempty"`
+	Contexts       map[string]Context           `yaml:"contexts,omitempty"`
+	Commands       map[string]map[string]string `yaml:"commands,omitempty"`
+}
+
+// Context bundles the AWS profile, region and resource filters used together.
+// Profiles and Regions enable looking for clusters across all of them.
+type Context struct {
+	Profile  string   `json:"profile,omitempty" yaml:"profile,omitempty"`
+	Region   string   `json:"region,omitempty" yaml:"region,omitempty"`
+	Profiles []string `json:"profiles,omitempty" yaml:"profiles,omitempty"`
+	Regions  []string `json:"regions,omitempty" yaml:"regions,omitempty"`
+	Cluster  string   `json:"cluster,omitempty" yaml:"cluster,omitempty"`
+	Service  string   `json:"service,omitempty" yaml:"service,omitempty"`
+}
+
+// defaultConfigPath returns the configuration file location according to the
+// XDG base directory specification.
+func defaultConfigPath() (string, error) {
+	configHome := os.Getenv("XDG_CONFIG_HOME")
+	if configHome == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		configHome = filepath.Join(home, ".config")
+	}
+
+	return filepath.Join(configHome, "iecs", "config.yaml"), nil
+}
+
+// loadConfigFile reads the configuration file at path. A missing file results
+// in an empty configuration unless required is set.
+func loadConfigFile(path string, required bool) (*ConfigFile, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) && !required {
+		return &ConfigFile{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var configFile ConfigFile
+	if err = yaml.Unmarshal(data, &configFile); err != nil {
+		return nil, fmt.Errorf("invalid config file %s: %w", path, err)
+	}
+
+	return &configFile, nil
+}
+
+// lookupContext returns the context with the given name, falling back to the
+// current one when name is empty.
+func (c *ConfigFile) lookupContext(name string) (*Context, error) {
+	if name == "" {
+		name = c.CurrentContext
+	}
+	if name == "" {
+		return &Context{}, nil
+	}
+
+	context, ok := c.Contexts[name]
+	if !ok {
+		return nil, fmt.Errorf("context \"%s\" not found", name)
+	}
+
+	return &context, nil
+}
+
+// contextNames returns the names of the contexts in alphabetical order.
+func (c *ConfigFile) contextNames() []string {
+	names := make([]string, 0, len(c.Contexts))
+	for name := range c.Contexts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// applyConfigFile uses the configuration file values as defaults for the flags
+// that were not set explicitly.
+func applyConfigFile(cmd *cobra.Command, configFile *ConfigFile, context *Context) error {
+	defaults := map[string]string{
+		"theme":    configFile.Theme,
+		"profile":  context.Profile,
+		"region":   context.Region,
+		"profiles": strings.Join(context.Profiles, ","),
+		"regions":  strings.Join(context.Regions, ","),
+		"cluster":  context.Cluster,
+		"service":  context.Service,
+	}
+	for name, value := range configFile.Commands[cmd.Name()] {
+		defaults[name] = value
+	}
+
+	for name, value := range defaults {
+		if value == "" || cmd.Flags().Changed(name) {
+			continue
+		}
+		if cmd.Flags().Lookup(name) == nil {
+			return fmt.Errorf("unknown flag \"%s\" in config file defaults for %s", name, cmd.Name())
+		}
+		if err := cmd.Flags().Set(name, value); err != nil {
+			return fmt.Errorf("invalid default for flag \"%s\": %w", name, err)
+		}
+	}
+
+	return nil
+}
+
+// saveCurrentContext updates the current context in the configuration file,
+// preserving the rest of its content, comments included.
+func saveCurrentContext(path string, name string) error {
+	var document yaml.Node
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if err = yaml.Unmarshal(data, &document); err != nil {
+		return fmt.Errorf("invalid config file %s: %w", path, err)
+	}
+
+	if len(document.Content) == 0 {
+		document.Kind = yaml.DocumentNode
+		document.Content = []*yaml.Node{{Kind: yaml.MappingNode}}
+	}
+	root := document.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return fmt.Errorf("invalid config file %s: expecting a mapping", path)
+	}
+
+	value := &yaml.Node{Kind: yaml.ScalarNode, Value: name}
+	found := false
+	for i := 0; i < len(root.Content); i += 2 {
+		if root.Content[i].Value == currentContextKey {
+			root.Content[i+1] = value
+			found = true
+		}
+	}
+	if !found {
+		root.Content = append(
+			root.Content,
+			&yaml.Node{Kind: yaml.ScalarNode, Value: currentContextKey},
+			value,
+		)
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	encoder := yaml.NewEncoder(file)
+	encoder.SetIndent(2)
+	if err = encoder.Encode(&document); err != nil {
+		return err
+	}
+	return encoder.Close()
+}
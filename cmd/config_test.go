@@ -0,0 +1,158 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testConfigFile = `# iecs settings
+theme: dracula
+current-context: staging
+contexts:
+  staging:
+    profile: staging
+    region: us-east-1
+    cluster: staging
+    service: api
+  production:
+    profile: production
+    region: eu-west-1
+    cluster: production
+commands:
+  exec:
+    command: /bin/bash
+    interactive: false
+`
+
+func writeTestConfigFile(t *testing.T) string {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(testConfigFile), 0o600))
+	return path
+}
+
+func TestLoadConfigFile(t *testing.T) {
+	configFile, err := loadConfigFile(writeTestConfigFile(t), true)
+
+	require.NoError(t, err)
+	assert.Equal(t, "dracula", configFile.Theme)
+	assert.Equal(t, []string{"production", "staging"}, configFile.contextNames())
+	assert.Equal(t, "false", configFile.Commands["exec"]["interactive"])
+}
+
+func TestLoadConfigFile_Missing(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+
+	configFile, err := loadConfigFile(path, false)
+	require.NoError(t, err)
+	assert.Equal(t, &ConfigFile{}, configFile)
+
+	_, err = loadConfigFile(path, true)
+	assert.Error(t, err)
+}
+
+func TestConfigFile_LookupContext(t *testing.T) {
+	configFile, err := loadConfigFile(writeTestConfigFile(t), true)
+	require.NoError(t, err)
+
+	current, err := configFile.lookupContext("")
+	require.NoError(t, err)
+	assert.Equal(t, "staging", current.Profile)
+
+	production, err := configFile.lookupContext("production")
+	require.NoError(t, err)
+	assert.Equal(t, "eu-west-1", production.Region)
+
+	_, err = configFile.lookupContext("development")
+	assert.EqualError(t, err, "context \"development\" not found")
+}
+
+func TestApplyConfigFile(t *testing.T) {
+	configFile, err := loadConfigFile(writeTestConfigFile(t), true)
+	require.NoError(t, err)
+	context, err := configFile.lookupContext("")
+	require.NoError(t, err)
+
+	cmd := &cobra.Command{Use: "exec"}
+	cmd.Flags().String("theme", "charm", "")
+	cmd.Flags().String("profile", "", "")
+	cmd.Flags().String("region", "", "")
+	cmd.Flags().String("cluster", "", "")
+	cmd.Flags().String("service", "", "")
+	cmd.Flags().String("command", "/bin/sh", "")
+	cmd.Flags().Bool("interactive", true, "")
+	require.NoError(t, cmd.Flags().Set("service", "web"))
+
+	require.NoError(t, applyConfigFile(cmd, configFile, context))
+
+	theme, _ := cmd.Flags().GetString("theme")
+	profile, _ := cmd.Flags().GetString("profile")
+	region, _ := cmd.Flags().GetString("region")
+	cluster, _ := cmd.Flags().GetString("cluster")
+	service, _ := cmd.Flags().GetString("service")
+	command, _ := cmd.Flags().GetString("command")
+	interactive, _ := cmd.Flags().GetBool("interactive")
+	assert.Equal(t, "dracula", theme)
+	assert.Equal(t, "staging", profile)
+	assert.Equal(t, "us-east-1", region)
+	assert.Equal(t, "staging", cluster)
+	assert.Equal(t, "web", service)
+	assert.Equal(t, "/bin/bash", command)
+	assert.False(t, interactive)
+}
+
+func TestApplyConfigFile_UnknownFlag(t *testing.T) {
+	configFile := &ConfigFile{
+		Commands: map[string]map[string]string{"logs": {"tail": "10"}},
+	}
+
+	err := applyConfigFile(&cobra.Command{Use: "logs"}, configFile, &Context{})
+
+	assert.ErrorContains(t, err, "unknown flag \"tail\"")
+}
+
+func TestRunContextUse(t *testing.T) {
+	path := writeTestConfigFile(t)
+	configFile, err := loadConfigFile(path, true)
+	require.NoError(t, err)
+
+	var output bytes.Buffer
+	require.NoError(t, runContextUse(&output, path, configFile, "production"))
+	assert.Equal(t, "Switched to context \"production\"\n", output.String())
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "# iecs settings")
+	assert.Contains(t, string(data), "current-context: production")
+
+	err = runContextUse(&output, path, configFile, "development")
+	assert.ErrorContains(t, err, "context \"development\" not found")
+}
+
+func TestManagesContexts(t *testing.T) {
+	assert.True(t, managesContexts(contextUseCmd))
+	assert.True(t, managesContexts(contextListCmd))
+	assert.False(t, managesContexts(execCmd))
+	assert.False(t, managesContexts(rootCmd))
+}
+
+func TestRunContextList(t *testing.T) {
+	configFile, err := loadConfigFile(writeTestConfigFile(t), true)
+	require.NoError(t, err)
+
+	var output bytes.Buffer
+	require.NoError(t, runContextList(&output, configFile))
+
+	assert.Equal(
+		t,
+		"CURRENT  NAME        PROFILE     REGION     CLUSTER     SERVICE\n"+
+			"         production  production  eu-west-1  production  \n"+
+			"*        staging     staging     us-east-1  staging     api\n",
+		output.String(),
+	)
+}
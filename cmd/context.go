@@ -0,0 +1,108 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+)
+
+var contextCmd = &cobra.Command{
+	Use:   "context",
+	Short: "Manage the contexts defined in the config file",
+	Example: `
+  iecs context list
+  iecs context use staging
+  `,
+}
+
+var contextListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the available contexts",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runContextList(os.Stdout, configFile)
+	},
+	Aliases: []string{"ls"},
+}
+
+var contextUseCmd = &cobra.Command{
+	Use:   "use <name>",
+	Short: "Set the current context",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runContextUse(decorationOutput(), configPath, configFile, args[0])
+	},
+}
+
+// managesContexts reports whether cmd is one of the context commands.
+func managesContexts(cmd *cobra.Command) bool {
+	for ; cmd != nil; cmd = cmd.Parent() {
+		if cmd == contextCmd {
+			return true
+		}
+	}
+	return false
+}
+
+// ContextOutput is the machine-readable form of a context.
+type ContextOutput struct {
+	Name    string `json:"name" yaml:"name"`
+	Current bool   `json:"current" yaml:"current"`
+	Context `yaml:",inline"`
+}
+
+func runContextList(w io.Writer, configFile *ConfigFile) error {
+	var contexts []ContextOutput
+	for _, name := range configFile.contextNames() {
+		contexts = append(contexts, ContextOutput{
+			Name:    name,
+			Current: name == configFile.CurrentContext,
+			Context: configFile.Contexts[name],
+		})
+	}
+
+	if structuredOutput() {
+		return writeOutput(w, outputFormat, contexts)
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "CURRENT\tNAME\tPROFILE\tREGION\tCLUSTER\tSERVICE")
+	for _, context := range contexts {
+		current := ""
+		if context.Current {
+			current = "*"
+		}
+		fmt.Fprintf(
+			tw,
+			"%s\t%s\t%s\t%s\t%s\t%s\n",
+			current,
+			context.Name,
+			context.Profile,
+			context.Region,
+			context.Cluster,
+			context.Service,
+		)
+	}
+	return tw.Flush()
+}
+
+func runContextUse(w io.Writer, path string, configFile *ConfigFile, name string) error {
+	if _, ok := configFile.Contexts[name]; !ok {
+		return fmt.Errorf("context \"%s\" not found in %s", name, path)
+	}
+
+	if err := saveCurrentContext(path, name); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(w, "Switched to context \"%s\"\n", name)
+	return nil
+}
+
+func init() {
+	contextCmd.AddCommand(contextListCmd)
+	contextCmd.AddCommand(contextUseCmd)
+	rootCmd.AddCommand(contextCmd)
+}
@@ -0,0 +1,745 @@
+package cmd
+
+import (
+	"archive/tar"
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sestrella/iecs/client"
+	"github.com/sestrella/iecs/selector"
+	"github.com/spf13/cobra"
+)
+
+// Markers delimiting the parts of the remote output that iecs cares about, as
+// the session output also includes the plugin banners and the terminal echo.
+const (
+	cpChecksumMarker = "__IECS_CP_SHA256__"
+	cpBeginMarker    = "__IECS_CP_BEGIN__"
+	cpEndMarker      = "__IECS_CP_END__"
+	cpDoneMarker     = "__IECS_CP_DONE__"
+	cpLineLength     = 76
+)
+
+// CopyLocation is either a local path or a path inside a container, written as
+// <task>:<container>:<path>. Both the task and the container can be left empty
+// to select them interactively.
+type CopyLocation struct {
+	task      string
+	container string
+	path      string
+	remote    bool
+}
+
+var cpCmd = &cobra.Command{
+	Use:   "cp <src> <dst>",
+	Short: "Copy files and directories between the local machine and a container",
+	Long: `Copy files and directories between the local machine and a container.
+
+Container paths are written as <task>:<container>:<path>, where the task is
+either an ID or an ARN. Leave the task or container empty to select them
+interactively. Like cp, copying into an existing directory, or a path ending
+with a slash, places the copy inside of it. The container must provide sh, tar,
+base64, sha256sum and mktemp.`,
+	Example: `
+  iecs cp ./config.yml 0123456789abcdef:app:/etc/app/config.yml
+  iecs cp ./config.yml ::/etc/app/
+  iecs cp ::/var/log/app ./app-logs
+  `,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		src := parseCopyLocation(args[0])
+		dst := parseCopyLocation(args[1])
+		if src.remote == dst.remote {
+			return fmt.Errorf("exactly one of the source and destination must be a container path")
+		}
+
+		remote := src
+		if dst.remote {
+			remote = dst
+		}
+
+		awsClient, err := newClient(context.TODO())
+		if err != nil {
+			return err
+		}
+
+		selection, err := cpSelector(
+			context.TODO(),
+			newSelectors(awsClient),
+			remote,
+		)
+		if err != nil {
+			return err
+		}
+
+		if dst.remote {
+			return runCopyUpload(context.TODO(), awsClient, *selection, src.path, dst.path)
+		}
+		return runCopyDownload(context.TODO(), awsClient, *selection, src.path, dst.path)
+	},
+}
+
+func parseCopyLocation(arg string) CopyLocation {
+	// Split from the right, as task ARNs contain colons themselves
+	pathIndex := strings.LastIndex(arg, ":")
+	if pathIndex < 0 {
+		return CopyLocation{path: arg}
+	}
+	containerIndex := strings.LastIndex(arg[:pathIndex], ":")
+	if containerIndex < 0 {
+		return CopyLocation{path: arg}
+	}
+
+	return CopyLocation{
+		task:      arg[:containerIndex],
+		container: arg[containerIndex+1 : pathIndex],
+		path:      arg[pathIndex+1:],
+		remote:    true,
+	}
+}
+
+func cpSelector(
+	ctx context.Context,
+	selectors selector.Selectors,
+	location CopyLocation,
+) (*ExecSelection, error) {
+	cpTaskFilter := taskFilter
+	if location.task != "" {
+		filter, err := selector.NewFilter(location.task)
+		if err != nil {
+			return nil, err
+		}
+		cpTaskFilter = filter
+	}
+
+	cpContainerFilter := containerFilter
+	if location.container != "" {
+		filter, err := selector.NewFilter(location.container)
+		if err != nil {
+			return nil, err
+		}
+		cpContainerFilter = filter
+	}
+
+	cluster, err := selectors.Cluster(ctx, clusterFilter)
+	if err != nil {
+		return nil, err
+	}
+
+	service, err := selectors.Service(ctx, cluster, serviceFilter)
+	if err != nil {
+		return nil, err
+	}
+
+	task, err := selectors.Task(ctx, service, cpTaskFilter)
+	if err != nil {
+		return nil, err
+	}
+
+	container, err := selectors.Container(ctx, task.Containers, cpContainerFilter)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ExecSelection{
+		cluster:   cluster,
+		service:   service,
+		task:      task,
+		container: container,
+	}, nil
+}
+
+func runCopyUpload(
+	ctx context.Context,
+	client client.Client,
+	selection ExecSelection,
+	localPath string,
+	remotePath string,
+) error {
+	info, err := os.Lstat(localPath)
+	if err != nil {
+		return err
+	}
+	// Directories are archived without a root entry, so the remote end can
+	// extract them under any name
+	name := filepath.Base(localPath)
+	rootName := name
+	if info.IsDir() {
+		rootName = "."
+	}
+
+	// The archive is built upfront so its checksum can be verified remotely
+	// before anything is extracted
+	archive, err := os.CreateTemp("", "iecs-cp-*.tar")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(archive.Name())
+	defer archive.Close()
+
+	hash := sha256.New()
+	if err = writeTar(io.MultiWriter(archive, hash), localPath, rootName); err != nil {
+		return err
+	}
+	checksum := hex.EncodeToString(hash.Sum(nil))
+
+	size, err := archive.Seek(0, io.SeekEnd)
+	if err != nil {
+		return err
+	}
+	if _, err = archive.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	cmd, err := client.ExecuteCommand(
+		ctx,
+		selection.cluster,
+		*selection.task.TaskArn,
+		selection.container,
+		cpUploadCommand(remotePath, name, info.IsDir(), checksum),
+		true,
+	)
+	if err != nil {
+		return err
+	}
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	cmd.Stderr = os.Stderr
+	if err = cmd.Start(); err != nil {
+		return err
+	}
+
+	go func() {
+		progress := newCopyProgress("Uploading", size)
+		encoder := base64.NewEncoder(base64.StdEncoding, &lineWriter{w: stdin})
+		if _, err := io.Copy(encoder, io.TeeReader(archive, progress)); err != nil {
+			fmt.Fprintf(os.Stderr, "Unable to upload archive: %v\n", err)
+		}
+		progress.done()
+		// Flush the encoder and send an EOF through the remote terminal
+		if err := encoder.Close(); err != nil {
+			fmt.Fprintf(os.Stderr, "Unable to upload archive: %v\n", err)
+		}
+		if _, err := io.WriteString(stdin, "\n\x04"); err != nil {
+			fmt.Fprintf(os.Stderr, "Unable to upload archive: %v\n", err)
+		}
+	}()
+
+	output, err := parseCopyOutput(stdout, io.Discard)
+	if err != nil {
+		return err
+	}
+	// The markers tell why the copy failed better than the exit code
+	waitErr := cmd.Wait()
+	if output.checksum != checksum {
+		return fmt.Errorf("checksum mismatch: local %s, remote %s", checksum, output.checksum)
+	}
+	if !output.complete {
+		return fmt.Errorf("unable to extract the archive at %s in the container", remotePath)
+	}
+	if waitErr != nil {
+		return waitErr
+	}
+
+	return printCopyResult(selection, localPath, output.destination, checksum)
+}
+
+func runCopyDownload(
+	ctx context.Context,
+	client client.Client,
+	selection ExecSelection,
+	remotePath string,
+	localPath string,
+) error {
+	remotePath = path.Clean(remotePath)
+
+	archive, err := os.CreateTemp("", "iecs-cp-*.tar")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(archive.Name())
+	defer archive.Close()
+
+	cmd, err := client.ExecuteCommand(
+		ctx,
+		selection.cluster,
+		*selection.task.TaskArn,
+		selection.container,
+		cpDownloadCommand(remotePath),
+		true,
+	)
+	if err != nil {
+		return err
+	}
+
+	// Keep the session stdin open, but don't let it read from the terminal
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return err
+	}
+	defer stdin.Close()
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	cmd.Stderr = os.Stderr
+	if err = cmd.Start(); err != nil {
+		return err
+	}
+
+	hash := sha256.New()
+	output, err := parseCopyOutput(stdout, io.MultiWriter(archive, hash))
+	if err != nil {
+		return err
+	}
+	waitErr := cmd.Wait()
+	if !output.complete {
+		return fmt.Errorf("incomplete archive received from the container")
+	}
+	checksum := hex.EncodeToString(hash.Sum(nil))
+	if output.checksum != checksum {
+		return fmt.Errorf("checksum mismatch: local %s, remote %s", checksum, output.checksum)
+	}
+	if waitErr != nil {
+		return waitErr
+	}
+
+	if _, err = archive.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	err = extractTar(
+		archive,
+		filepath.Dir(localPath),
+		path.Base(remotePath),
+		filepath.Base(localPath),
+	)
+	if err != nil {
+		return err
+	}
+
+	return printCopyResult(selection, remotePath, localPath, checksum)
+}
+
+// cpUploadCommand returns the remote command that decodes the archive read
+// from stdin and, provided its checksum matches, extracts it at remotePath.
+// Like cp, an existing directory at remotePath, or a remotePath ending with a
+// slash, receives the copy under its original name. The destination is
+// reported once extracted.
+func cpUploadCommand(remotePath string, name string, dir bool, checksum string) string {
+	steps := []string{
+		`archive=$(mktemp) || exit 1`,
+		`trap 'rm -f "$archive"' EXIT`,
+		`base64 -d > "$archive" || exit 1`,
+		fmt.Sprintf(`echo %s $(sha256sum "$archive")`, cpChecksumMarker),
+		fmt.Sprintf(`test "$(sha256sum "$archive" | cut -d " " -f 1)" = %s || exit 1`, checksum),
+		fmt.Sprintf(`target=%s`, shellQuote(path.Clean(remotePath))),
+	}
+	if strings.HasSuffix(remotePath, "/") {
+		steps = append(steps, `mkdir -p "$target" || exit 1`)
+	}
+	steps = append(steps, fmt.Sprintf(`if [ -d "$target" ]; then target="$target"/%s; fi`, shellQuote(name)))
+	if dir {
+		steps = append(steps, `mkdir -p "$target" && tar -xf "$archive" -C "$target" || exit 1`)
+	} else {
+		steps = append(steps, fmt.Sprintf(
+			`mkdir -p "$(dirname "$target")" && tar -xOf "$archive" %s > "$target" || exit 1`,
+			shellQuote(name),
+		))
+	}
+	steps = append(steps, fmt.Sprintf(`echo %s "$target"`, cpDoneMarker))
+	return "sh -c " + shellQuote(strings.Join(steps, "; "))
+}
+
+// cpDownloadCommand returns the remote command that archives remotePath and
+// prints it encoded in base64, preceded by its checksum and size.
+func cpDownloadCommand(remotePath string) string {
+	steps := []string{
+		`archive=$(mktemp) || exit 1`,
+		`trap 'rm -f "$archive"' EXIT`,
+		fmt.Sprintf(
+			`tar -cf "$archive" -C %s %s || exit 1`,
+			shellQuote(path.Dir(remotePath)),
+			shellQuote(path.Base(remotePath)),
+		),
+		fmt.Sprintf(`echo %s $(sha256sum "$archive") $(wc -c < "$archive")`, cpChecksumMarker),
+		fmt.Sprintf(`echo %s`, cpBeginMarker),
+		`base64 "$archive"`,
+		fmt.Sprintf(`echo %s`, cpEndMarker),
+	}
+	return "sh -c " + shellQuote(strings.Join(steps, "; "))
+}
+
+// CopyOutput is the machine-readable form of a completed copy.
+type CopyOutput struct {
+	SelectionOutput `yaml:",inline"`
+	Source          string `json:"source" yaml:"source"`
+	Destination     string `json:"destination" yaml:"destination"`
+	Checksum        string `json:"checksum" yaml:"checksum"`
+}
+
+func printCopyResult(
+	selection ExecSelection,
+	source string,
+	destination string,
+	checksum string,
+) error {
+	if structuredOutput() {
+		return printOutput(CopyOutput{
+			SelectionOutput: selection.output(),
+			Source:          source,
+			Destination:     destination,
+			Checksum:        checksum,
+		})
+	}
+
+	fmt.Fprintf(os.Stderr, "%s %s\n", titleStyle.Render("Checksum:"), checksum)
+	return nil
+}
+
+type copyOutput struct {
+	checksum    string
+	size        int64
+	complete    bool
+	destination string
+}
+
+// parseCopyOutput scans the session output for the checksum and done lines,
+// and decodes the base64 archive found between the begin and end markers into
+// archive.
+func parseCopyOutput(r io.Reader, archive io.Writer) (*copyOutput, error) {
+	var output copyOutput
+	var progress *copyProgress
+	decoding := false
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		switch {
+		case strings.HasPrefix(line, cpChecksumMarker):
+			// <marker> <checksum> <file> [<size>]
+			fields := strings.Fields(line)
+			if len(fields) < 2 {
+				return nil, fmt.Errorf("unable to parse checksum from '%s'", line)
+			}
+			output.checksum = fields[1]
+			if len(fields) > 3 {
+				output.size, _ = strconv.ParseInt(fields[3], 10, 64)
+			}
+		case strings.HasPrefix(line, cpDoneMarker+" "):
+			output.complete = true
+			output.destination = strings.TrimPrefix(line, cpDoneMarker+" ")
+		case line == cpBeginMarker:
+			decoding = true
+			progress = newCopyProgress("Downloading", output.size)
+		case line == cpEndMarker:
+			decoding = false
+			output.complete = true
+			progress.done()
+		case decoding:
+			data, err := base64.StdEncoding.DecodeString(line)
+			if err != nil {
+				return nil, fmt.Errorf("unable to decode archive: %w", err)
+			}
+			if _, err = archive.Write(data); err != nil {
+				return nil, err
+			}
+			if _, err = progress.Write(data); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if output.checksum == "" {
+		return nil, fmt.Errorf("no checksum received from the container")
+	}
+
+	return &output, nil
+}
+
+// writeTar archives the file or directory at localPath, naming its root entry
+// rootName.
+func writeTar(w io.Writer, localPath string, rootName string) error {
+	tw := tar.NewWriter(w)
+
+	err := filepath.Walk(localPath, func(file string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		relPath, err := filepath.Rel(localPath, file)
+		if err != nil {
+			return err
+		}
+
+		var link string
+		if info.Mode()&os.ModeSymlink != 0 {
+			if link, err = os.Readlink(file); err != nil {
+				return err
+			}
+		}
+
+		header, err := tar.FileInfoHeader(info, link)
+		if err != nil {
+			return err
+		}
+		header.Name = path.Join(rootName, filepath.ToSlash(relPath))
+		if info.IsDir() {
+			header.Name += "/"
+		}
+		if err = tw.WriteHeader(header); err != nil {
+			return err
+		}
+
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		f, err := os.Open(file)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		_, err = io.Copy(tw, f)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	return tw.Close()
+}
+
+// extractTar extracts an archive into dir, renaming its root entry from
+// oldRoot to newRoot. The archive comes from the container, so its entries
+// and links are not allowed to reach outside of the root entry.
+func extractTar(r io.Reader, dir string, oldRoot string, newRoot string) error {
+	root := filepath.Join(dir, newRoot)
+
+	tr := tar.NewReader(r)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		name := path.Clean(header.Name)
+		if name != oldRoot && !strings.HasPrefix(name, oldRoot+"/") {
+			return fmt.Errorf("invalid archive entry '%s'", header.Name)
+		}
+		name = strings.TrimPrefix(name, oldRoot)
+		target := filepath.Join(root, filepath.FromSlash(name))
+		if !withinDir(dir, target) {
+			return fmt.Errorf("invalid archive entry '%s'", header.Name)
+		}
+		if target != root {
+			if err = checkParents(root, target); err != nil {
+				return fmt.Errorf("invalid archive entry '%s': %w", header.Name, err)
+			}
+		}
+
+		mode := os.FileMode(header.Mode).Perm()
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err = os.MkdirAll(target, mode|0o700); err != nil {
+				return err
+			}
+		case tar.TypeSymlink:
+			// Links are resolved from the entry they belong to
+			link := filepath.FromSlash(header.Linkname)
+			if filepath.IsAbs(link) || !withinDir(root, filepath.Join(filepath.Dir(target), link)) {
+				return fmt.Errorf("invalid link '%s' -> '%s'", header.Name, header.Linkname)
+			}
+			if err = removeFile(target); err != nil {
+				return err
+			}
+			if err = os.Symlink(header.Linkname, target); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if err = os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+				return err
+			}
+			// Don't write through a link left by a previous copy
+			if err = removeFile(target); err != nil {
+				return err
+			}
+			f, err := os.OpenFile(target, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, mode)
+			if err != nil {
+				return err
+			}
+			if _, err = io.Copy(f, tr); err != nil {
+				f.Close()
+				return err
+			}
+			if err = f.Close(); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// withinDir reports whether target is lexically dir or a path under it.
+func withinDir(dir string, target string) bool {
+	relPath, err := filepath.Rel(dir, target)
+	return err == nil && relPath != ".." && !strings.HasPrefix(relPath, ".."+string(filepath.Separator))
+}
+
+// checkParents makes sure that the links among the existing parents of
+// target, within root, don't lead outside of root.
+func checkParents(root string, target string) error {
+	realRoot, err := filepath.EvalSymlinks(root)
+	if err != nil {
+		// Nothing exists under root yet
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+
+	for parent := filepath.Dir(target); parent != root; parent = filepath.Dir(parent) {
+		realParent, err := filepath.EvalSymlinks(parent)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if !withinDir(realRoot, realParent) {
+			return fmt.Errorf("%s leads outside of %s", parent, root)
+		}
+		return nil
+	}
+	return nil
+}
+
+// removeFile removes the file or link at name, if any.
+func removeFile(name string) error {
+	info, err := os.Lstat(name)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if info.IsDir() {
+		return fmt.Errorf("%s is a directory", name)
+	}
+	return os.Remove(name)
+}
+
+// lineWriter splits the base64 stream into lines, as the remote terminal
+// limits the length of the lines it accepts.
+type lineWriter struct {
+	w      io.Writer
+	column int
+}
+
+func (l *lineWriter) Write(p []byte) (int, error) {
+	written := 0
+	for len(p) > 0 {
+		n := min(cpLineLength-l.column, len(p))
+		if _, err := l.w.Write(p[:n]); err != nil {
+			return written, err
+		}
+		written += n
+		l.column += n
+		p = p[n:]
+
+		if l.column == cpLineLength {
+			if _, err := l.w.Write([]byte("\n")); err != nil {
+				return written, err
+			}
+			l.column = 0
+		}
+	}
+	return written, nil
+}
+
+// copyProgress reports the number of bytes transferred to stderr.
+type copyProgress struct {
+	label   string
+	total   int64
+	written int64
+	printed time.Time
+}
+
+func newCopyProgress(label string, total int64) *copyProgress {
+	return &copyProgress{label: label, total: total}
+}
+
+func (p *copyProgress) Write(data []byte) (int, error) {
+	p.written += int64(len(data))
+	if time.Since(p.printed) > 100*time.Millisecond {
+		p.print()
+	}
+	return len(data), nil
+}
+
+func (p *copyProgress) print() {
+	p.printed = time.Now()
+	if p.total > 0 {
+		fmt.Fprintf(
+			os.Stderr,
+			"\r%s %s / %s (%d%%)",
+			titleStyle.Render(p.label+":"),
+			formatBytes(p.written),
+			formatBytes(p.total),
+			p.written*100/p.total,
+		)
+	} else {
+		fmt.Fprintf(os.Stderr, "\r%s %s", titleStyle.Render(p.label+":"), formatBytes(p.written))
+	}
+}
+
+func (p *copyProgress) done() {
+	if p == nil {
+		return
+	}
+	p.print()
+	fmt.Fprintln(os.Stderr)
+}
+
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+// shellQuote quotes a string to be used as a single sh word.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+func init() {
+	rootCmd.AddCommand(cpCmd)
+}
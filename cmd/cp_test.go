@@ -0,0 +1,362 @@
+package cmd
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/ecs/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func cpSelection() ExecSelection {
+	clusterArn := "arn:aws:ecs:us-east-1:123456789012:cluster/my-cluster"
+	clusterName := "my-cluster"
+	taskArn := "arn:aws:ecs:us-east-1:123456789012:task/my-cluster/12345678-1234-1234-1234-123456789012"
+	containerName := "my-container"
+
+	return ExecSelection{
+		cluster:   &types.Cluster{ClusterArn: &clusterArn, ClusterName: &clusterName},
+		task:      &types.Task{TaskArn: &taskArn},
+		container: &types.Container{Name: &containerName},
+	}
+}
+
+func writeFixture(t *testing.T) string {
+	dir := filepath.Join(t.TempDir(), "config")
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "nested"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "app.yml"), []byte("port: 8080\n"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "nested", "db.yml"), []byte("host: db\n"), 0o600))
+	return dir
+}
+
+func TestParseCopyLocation(t *testing.T) {
+	assert.Equal(t, CopyLocation{path: "./config.yml"}, parseCopyLocation("./config.yml"))
+	assert.Equal(
+		t,
+		CopyLocation{task: "abcd", container: "app", path: "/etc/app", remote: true},
+		parseCopyLocation("abcd:app:/etc/app"),
+	)
+	assert.Equal(
+		t,
+		CopyLocation{path: "/tmp", remote: true},
+		parseCopyLocation("::/tmp"),
+	)
+	assert.Equal(
+		t,
+		CopyLocation{
+			task:      "arn:aws:ecs:us-east-1:123456789012:task/my-cluster/abcd",
+			container: "app",
+			path:      "/tmp",
+			remote:    true,
+		},
+		parseCopyLocation("arn:aws:ecs:us-east-1:123456789012:task/my-cluster/abcd:app:/tmp"),
+	)
+}
+
+func TestTarRoundTrip(t *testing.T) {
+	src := writeFixture(t)
+	dst := t.TempDir()
+
+	var archive bytes.Buffer
+	require.NoError(t, writeTar(&archive, src, "config"))
+	require.NoError(t, extractTar(&archive, dst, "config", "renamed"))
+
+	content, err := os.ReadFile(filepath.Join(dst, "renamed", "nested", "db.yml"))
+	require.NoError(t, err)
+	assert.Equal(t, "host: db\n", string(content))
+
+	info, err := os.Stat(filepath.Join(dst, "renamed", "nested", "db.yml"))
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+}
+
+func TestExtractTar_RejectsUnexpectedEntries(t *testing.T) {
+	src := writeFixture(t)
+
+	var archive bytes.Buffer
+	require.NoError(t, writeTar(&archive, src, "../escape"))
+
+	err := extractTar(&archive, t.TempDir(), "config", "config")
+	assert.ErrorContains(t, err, "invalid archive entry")
+}
+
+func TestExtractTar_Links(t *testing.T) {
+	entries := func(t *testing.T, headers ...tar.Header) *bytes.Buffer {
+		var archive bytes.Buffer
+		tw := tar.NewWriter(&archive)
+		for _, header := range headers {
+			header.Mode = 0o644
+			require.NoError(t, tw.WriteHeader(&header))
+			if header.Typeflag == tar.TypeReg {
+				_, err := tw.Write(make([]byte, header.Size))
+				require.NoError(t, err)
+			}
+		}
+		require.NoError(t, tw.Close())
+		return &archive
+	}
+
+	t.Run("absolute link", func(t *testing.T) {
+		archive := entries(t,
+			tar.Header{Name: "config/", Typeflag: tar.TypeDir},
+			tar.Header{Name: "config/x", Typeflag: tar.TypeSymlink, Linkname: "/etc"},
+		)
+
+		err := extractTar(archive, t.TempDir(), "config", "config")
+
+		assert.EqualError(t, err, "invalid link 'config/x' -> '/etc'")
+	})
+
+	t.Run("escaping link", func(t *testing.T) {
+		archive := entries(t,
+			tar.Header{Name: "config/", Typeflag: tar.TypeDir},
+			tar.Header{Name: "config/x", Typeflag: tar.TypeSymlink, Linkname: "../.."},
+		)
+
+		err := extractTar(archive, t.TempDir(), "config", "config")
+
+		assert.EqualError(t, err, "invalid link 'config/x' -> '../..'")
+	})
+
+	t.Run("writing through a link", func(t *testing.T) {
+		dir := t.TempDir()
+		// a points to the root, so a/b points to its parent
+		archive := entries(t,
+			tar.Header{Name: "config/", Typeflag: tar.TypeDir},
+			tar.Header{Name: "config/a", Typeflag: tar.TypeSymlink, Linkname: "."},
+			tar.Header{Name: "config/a/b", Typeflag: tar.TypeSymlink, Linkname: ".."},
+			tar.Header{Name: "config/b/escaped", Typeflag: tar.TypeReg, Size: 1},
+		)
+
+		err := extractTar(archive, dir, "config", "config")
+
+		assert.ErrorContains(t, err, "invalid archive entry 'config/b/escaped'")
+		assert.NoFileExists(t, filepath.Join(dir, "escaped"))
+	})
+
+	t.Run("extracting twice", func(t *testing.T) {
+		dir := t.TempDir()
+		for range 2 {
+			archive := entries(t,
+				tar.Header{Name: "config/", Typeflag: tar.TypeDir},
+				tar.Header{Name: "config/app.yml", Typeflag: tar.TypeReg, Size: 1},
+				tar.Header{Name: "config/current.yml", Typeflag: tar.TypeSymlink, Linkname: "app.yml"},
+			)
+
+			require.NoError(t, extractTar(archive, dir, "config", "config"))
+		}
+
+		link, err := os.Readlink(filepath.Join(dir, "config", "current.yml"))
+		require.NoError(t, err)
+		assert.Equal(t, "app.yml", link)
+	})
+}
+
+func TestLineWriter(t *testing.T) {
+	var out bytes.Buffer
+	writer := &lineWriter{w: &out}
+
+	_, err := writer.Write(bytes.Repeat([]byte("a"), cpLineLength+10))
+	require.NoError(t, err)
+	_, err = writer.Write(bytes.Repeat([]byte("b"), cpLineLength-10))
+	require.NoError(t, err)
+
+	lines := bytes.Split(out.Bytes(), []byte("\n"))
+	assert.Len(t, lines, 3)
+	assert.Len(t, lines[0], cpLineLength)
+	assert.Len(t, lines[1], cpLineLength)
+	assert.Empty(t, lines[2])
+}
+
+func TestRunCopyUpload(t *testing.T) {
+	mockClient := new(MockClient)
+	selection := cpSelection()
+	src := writeFixture(t)
+
+	// The checksum of the archive the remote end is expected to report
+	hash := sha256.New()
+	require.NoError(t, writeTar(hash, src, "."))
+	checksum := hex.EncodeToString(hash.Sum(nil))
+
+	mockClient.On("ExecuteCommand",
+		mock.Anything,
+		selection.cluster,
+		*selection.task.TaskArn,
+		selection.container,
+		cpUploadCommand("/etc/app/", "config", true, checksum),
+		true,
+	).Return(exec.Command(
+		"sh",
+		"-c",
+		"cat > /dev/null & echo "+cpChecksumMarker+" "+checksum+" -; echo "+cpDoneMarker+" /etc/app/config",
+	), nil)
+
+	err := runCopyUpload(context.Background(), mockClient, selection, src, "/etc/app/")
+
+	assert.NoError(t, err)
+	mockClient.AssertExpectations(t)
+}
+
+func TestRunCopyUpload_NotExtracted(t *testing.T) {
+	mockClient := new(MockClient)
+	selection := cpSelection()
+	src := writeFixture(t)
+
+	hash := sha256.New()
+	require.NoError(t, writeTar(hash, src, "."))
+	checksum := hex.EncodeToString(hash.Sum(nil))
+
+	// The checksum matches, but the extraction fails
+	mockClient.On("ExecuteCommand",
+		mock.Anything,
+		selection.cluster,
+		*selection.task.TaskArn,
+		selection.container,
+		mock.AnythingOfType("string"),
+		true,
+	).Return(exec.Command("sh", "-c", "cat > /dev/null & echo "+cpChecksumMarker+" "+checksum+" -; exit 1"), nil)
+
+	err := runCopyUpload(context.Background(), mockClient, selection, src, "/etc/app/config")
+
+	assert.EqualError(t, err, "unable to extract the archive at /etc/app/config in the container")
+}
+
+func TestCpUploadCommand(t *testing.T) {
+	for _, tool := range []string{"sh", "tar", "base64", "sha256sum", "mktemp"} {
+		if _, err := exec.LookPath(tool); err != nil {
+			t.Skipf("%s is not available", tool)
+		}
+	}
+
+	src := writeFixture(t)
+	file := filepath.Join(src, "app.yml")
+
+	// Run the remote script locally, as if the container was this machine
+	upload := func(t *testing.T, localPath string, dir bool, remotePath string) (string, error) {
+		rootName := filepath.Base(localPath)
+		if dir {
+			rootName = "."
+		}
+		var archive bytes.Buffer
+		require.NoError(t, writeTar(&archive, localPath, rootName))
+		sum := sha256.Sum256(archive.Bytes())
+
+		command := cpUploadCommand(remotePath, filepath.Base(localPath), dir, hex.EncodeToString(sum[:]))
+		cmd := exec.Command("sh", "-c", command)
+		cmd.Stdin = strings.NewReader(base64.StdEncoding.EncodeToString(archive.Bytes()))
+		out, err := cmd.Output()
+		if err != nil {
+			return "", err
+		}
+		output, err := parseCopyOutput(bytes.NewReader(out), io.Discard)
+		if err != nil {
+			return "", err
+		}
+		return output.destination, nil
+	}
+
+	t.Run("new file", func(t *testing.T) {
+		remote := filepath.Join(t.TempDir(), "etc", "app.yml")
+
+		destination, err := upload(t, file, false, remote)
+
+		require.NoError(t, err)
+		assert.Equal(t, remote, destination)
+		content, err := os.ReadFile(remote)
+		require.NoError(t, err)
+		assert.Equal(t, "port: 8080\n", string(content))
+	})
+
+	t.Run("into an existing directory", func(t *testing.T) {
+		remote := t.TempDir()
+
+		destination, err := upload(t, src, true, remote)
+
+		require.NoError(t, err)
+		assert.Equal(t, filepath.Join(remote, "config"), destination)
+		content, err := os.ReadFile(filepath.Join(remote, "config", "nested", "db.yml"))
+		require.NoError(t, err)
+		assert.Equal(t, "host: db\n", string(content))
+	})
+
+	t.Run("into a directory ending with a slash", func(t *testing.T) {
+		remote := filepath.Join(t.TempDir(), "etc")
+
+		destination, err := upload(t, file, false, remote+"/")
+
+		require.NoError(t, err)
+		assert.Equal(t, filepath.Join(remote, "app.yml"), destination)
+	})
+
+	t.Run("directory over a file", func(t *testing.T) {
+		remote := filepath.Join(t.TempDir(), "config")
+		require.NoError(t, os.WriteFile(remote, nil, 0o644))
+
+		_, err := upload(t, src, true, remote)
+
+		assert.Error(t, err)
+	})
+}
+
+func TestRunCopyUpload_ChecksumMismatch(t *testing.T) {
+	mockClient := new(MockClient)
+	selection := cpSelection()
+	src := writeFixture(t)
+
+	mockClient.On("ExecuteCommand",
+		mock.Anything,
+		selection.cluster,
+		*selection.task.TaskArn,
+		selection.container,
+		mock.AnythingOfType("string"),
+		true,
+	).Return(exec.Command("sh", "-c", "cat > /dev/null & echo "+cpChecksumMarker+" corrupted -"), nil)
+
+	err := runCopyUpload(context.Background(), mockClient, selection, src, "/etc/app/config")
+
+	assert.ErrorContains(t, err, "checksum mismatch")
+}
+
+func TestRunCopyDownload(t *testing.T) {
+	for _, tool := range []string{"sh", "tar", "base64", "sha256sum"} {
+		if _, err := exec.LookPath(tool); err != nil {
+			t.Skipf("%s is not available", tool)
+		}
+	}
+
+	mockClient := new(MockClient)
+	selection := cpSelection()
+	src := writeFixture(t)
+	dst := filepath.Join(t.TempDir(), "downloaded")
+
+	// Run the remote script locally, as if the container was this machine
+	command := cpDownloadCommand(src)
+	mockClient.On("ExecuteCommand",
+		mock.Anything,
+		selection.cluster,
+		*selection.task.TaskArn,
+		selection.container,
+		command,
+		true,
+	).Return(exec.Command("sh", "-c", command), nil)
+
+	err := runCopyDownload(context.Background(), mockClient, selection, src, dst)
+
+	require.NoError(t, err)
+	content, err := os.ReadFile(filepath.Join(dst, "app.yml"))
+	require.NoError(t, err)
+	assert.Equal(t, "port: 8080\n", string(content))
+	mockClient.AssertExpectations(t)
+}
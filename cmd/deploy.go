@@ -0,0 +1,222 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/ecs/types"
+	"github.com/sestrella/iecs/client"
+	"github.com/sestrella/iecs/selector"
+	"github.com/spf13/cobra"
+)
+
+const deployImageFlag = "image"
+
+var deployCmd = &cobra.Command{
+	Use:   "deploy",
+	Short: "Deploy new container images to a service",
+	Long: `Registers a new revision of the service's task definition with the given
+images and updates the service to use it. Images given as name=image are
+assigned to the named container, otherwise to the containers selected with
+--container.`,
+	Example: `
+  iecs deploy --container app --image repo:tag
+  iecs deploy --image app=repo:tag --image worker=repo-worker:tag
+  `,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		imageValues, err := cmd.Flags().GetStringArray(deployImageFlag)
+		if err != nil {
+			return err
+		}
+
+		client, err := newClient(context.Background())
+		if err != nil {
+			return err
+		}
+
+		selectors := newSelectors(client)
+
+		cluster, err := selectors.Cluster(context.Background(), clusterFilter)
+		if err != nil {
+			return err
+		}
+
+		service, err := selectors.Service(context.Background(), cluster, serviceFilter)
+		if err != nil {
+			return err
+		}
+
+		images, err := deployImages(
+			context.Background(),
+			selectors,
+			*service.TaskDefinition,
+			imageValues,
+		)
+		if err != nil {
+			return err
+		}
+
+		return runDeploy(
+			context.Background(),
+			client,
+			selectors,
+			UpdateSelection{
+				cluster: *cluster,
+				service: *service,
+			},
+			images,
+		)
+	},
+}
+
+// deployImages maps container names to the images to deploy. Images that do
+// not name a container are assigned to the selected containers.
+func deployImages(
+	ctx context.Context,
+	selectors selector.Selectors,
+	taskDefinitionArn string,
+	values []string,
+) (map[string]string, error) {
+	images := map[string]string{}
+	var unnamedImages []string
+	for _, value := range values {
+		if name, image, ok := strings.Cut(value, "="); ok {
+			images[name] = image
+		} else {
+			unnamedImages = append(unnamedImages, value)
+		}
+	}
+
+	switch len(unnamedImages) {
+	case 0:
+		return images, nil
+	case 1:
+	default:
+		return nil, fmt.Errorf(
+			"expecting at most one image without a container name, got: %s",
+			strings.Join(unnamedImages, ", "),
+		)
+	}
+
+	containerDefinitions, err := selectors.ContainerDefinitions(ctx, taskDefinitionArn, containerFilter)
+	if err != nil {
+		return nil, err
+	}
+	for _, containerDefinition := range containerDefinitions {
+		images[*containerDefinition.Name] = unnamedImages[0]
+	}
+
+	return images, nil
+}
+
+// withImages returns a copy of the task definition using the given images.
+func withImages(
+	taskDefinition *types.TaskDefinition,
+	images map[string]string,
+) (*types.TaskDefinition, error) {
+	updated := *taskDefinition
+	updated.ContainerDefinitions = slices.Clone(taskDefinition.ContainerDefinitions)
+
+	var unknownNames []string
+	for name, image := range images {
+		index := slices.IndexFunc(
+			updated.ContainerDefinitions,
+			func(containerDefinition types.ContainerDefinition) bool {
+				return *containerDefinition.Name == name
+			},
+		)
+		if index == -1 {
+			unknownNames = append(unknownNames, name)
+			continue
+		}
+		updated.ContainerDefinitions[index].Image = &image
+	}
+
+	if len(unknownNames) > 0 {
+		slices.Sort(unknownNames)
+		return nil, fmt.Errorf(
+			"containers not found in task definition %s: %s",
+			*taskDefinition.TaskDefinitionArn,
+			strings.Join(unknownNames, ", "),
+		)
+	}
+
+	return &updated, nil
+}
+
+func runDeploy(
+	ctx context.Context,
+	client client.Client,
+	selectors selector.Selectors,
+	selection UpdateSelection,
+	images map[string]string,
+) error {
+	if len(images) == 0 {
+		return fmt.Errorf("no images to deploy")
+	}
+
+	currentTaskDefinition, err := client.DescribeTaskDefinition(ctx, *selection.service.TaskDefinition)
+	if err != nil {
+		return err
+	}
+
+	taskDefinition, err := withImages(currentTaskDefinition, images)
+	if err != nil {
+		return err
+	}
+
+	printDeployPlan(decorationOutput(), currentTaskDefinition, taskDefinition)
+
+	confirmed, err := confirmUpdate(selectors)
+	if err != nil || !confirmed {
+		return err
+	}
+
+	registeredTaskDefinition, err := client.RegisterTaskDefinition(ctx, taskDefinition)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(
+		decorationOutput(),
+		"%s %s\n",
+		titleStyle.Render("Registered:"),
+		*registeredTaskDefinition.TaskDefinitionArn,
+	)
+
+	selection.serviceConfig.TaskDefinitionArn = *registeredTaskDefinition.TaskDefinitionArn
+
+	return runUpdate(ctx, selection, client, waitTimeoutFlag)
+}
+
+func printDeployPlan(
+	w io.Writer,
+	currentTaskDefinition *types.TaskDefinition,
+	taskDefinition *types.TaskDefinition,
+) {
+	fmt.Fprintf(
+		w,
+		"%s %s -> new revision\n",
+		titleStyle.Render("Task definition:"),
+		*currentTaskDefinition.TaskDefinitionArn,
+	)
+	printDiff(w, taskDefinitionDiff(currentTaskDefinition, taskDefinition))
+}
+
+func init() {
+	rootCmd.AddCommand(deployCmd)
+
+	deployCmd.Flags().
+		StringArray(deployImageFlag, nil, "The image to deploy, optionally prefixed by the container name as name=image")
+	deployCmd.Flags().
+		DurationVarP(&waitTimeoutFlag, "wait-timeout", "w", 5*time.Minute, "The wait time for the service to become available")
+	deployCmd.Flags().
+		BoolVarP(&yesFlag, "yes", "y", false, "Deploy without asking for confirmation")
+	deployCmd.Flags().BoolVar(&noColorsFlag, "no-colors", false, "Disable diff coloring")
+	if err := deployCmd.MarkFlagRequired(deployImageFlag); err != nil {
+		panic(err)
+	}
+}
@@ -0,0 +1,98 @@
+package cmd
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ecs/types"
+	"github.com/sestrella/iecs/client"
+	"github.com/sestrella/iecs/selector"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithImages(t *testing.T) {
+	taskDefinition := testTaskDefinition("1", "api:1.0.0")
+
+	updated, err := withImages(taskDefinition, map[string]string{"app": "api:1.1.0"})
+
+	require.NoError(t, err)
+	assert.Equal(t, "api:1.1.0", *updated.ContainerDefinitions[0].Image)
+	assert.Equal(t, "api:1.0.0", *taskDefinition.ContainerDefinitions[0].Image)
+}
+
+func TestWithImages_UnknownContainer(t *testing.T) {
+	taskDefinition := testTaskDefinition("1", "api:1.0.0")
+
+	_, err := withImages(taskDefinition, map[string]string{"worker": "worker:1.0.0"})
+
+	assert.ErrorContains(t, err, "containers not found in task definition")
+	assert.ErrorContains(t, err, "worker")
+}
+
+func TestDeployImages_Named(t *testing.T) {
+	images, err := deployImages(
+		context.Background(),
+		selector.Selectors{},
+		"task-definition",
+		[]string{"app=api:1.1.0", "worker=worker:2.0.0"},
+	)
+
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"app": "api:1.1.0", "worker": "worker:2.0.0"}, images)
+}
+
+func TestDeployImages_SeveralUnnamed(t *testing.T) {
+	_, err := deployImages(
+		context.Background(),
+		selector.Selectors{},
+		"task-definition",
+		[]string{"api:1.1.0", "worker:2.0.0"},
+	)
+
+	assert.ErrorContains(t, err, "expecting at most one image without a container name")
+}
+
+func TestRunDeploy(t *testing.T) {
+	yesFlag = true
+	waitTimeoutFlag = time.Minute
+	t.Cleanup(func() { yesFlag = false })
+
+	mockClient := new(MockClient)
+	current := testTaskDefinition("1", "api:1.0.0")
+	registered := testTaskDefinition("2", "api:1.1.0")
+	service := types.Service{
+		ClusterArn:     aws.String("arn:aws:ecs:us-east-1:123456789012:cluster/my-cluster"),
+		ServiceArn:     aws.String("arn:aws:ecs:us-east-1:123456789012:service/my-cluster/api"),
+		TaskDefinition: current.TaskDefinitionArn,
+		DesiredCount:   2,
+	}
+	deployed := service
+	deployed.TaskDefinition = registered.TaskDefinitionArn
+	deployed.RunningCount = 2
+
+	mockClient.On("DescribeTaskDefinition", mock.Anything, *current.TaskDefinitionArn).
+		Return(current, nil)
+	mockClient.On("RegisterTaskDefinition", mock.Anything, mock.MatchedBy(func(taskDefinition *types.TaskDefinition) bool {
+		return *taskDefinition.ContainerDefinitions[0].Image == "api:1.1.0"
+	})).Return(registered, nil)
+	mockClient.On("UpdateService", mock.Anything, &service, client.ServiceConfig{
+		TaskDefinitionArn: *registered.TaskDefinitionArn,
+	}).Return(&service, nil)
+	mockClient.On("DescribeServices", mock.Anything, *service.ClusterArn, []string{*service.ServiceArn}).
+		Return([]types.Service{deployed}, nil)
+
+	err := runDeploy(
+		context.Background(),
+		mockClient,
+		selector.Selectors{},
+		UpdateSelection{service: service},
+		map[string]string{"app": "api:1.1.0"},
+	)
+
+	require.NoError(t, err)
+	mockClient.AssertExpectations(t)
+}
@@ -0,0 +1,323 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/ecs/types"
+	"github.com/sestrella/iecs/client"
+	"github.com/spf13/cobra"
+)
+
+const describeEventsLimit = 10
+
+var describeCmd = &cobra.Command{
+	Use:   "describe",
+	Short: "Show a summary of the status of a service",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		client, err := newClient(context.TODO())
+		if err != nil {
+			return err
+		}
+
+		selectors := newSelectors(client)
+
+		cluster, err := selectors.Cluster(context.TODO(), clusterFilter)
+		if err != nil {
+			return err
+		}
+
+		service, err := selectors.Service(context.TODO(), cluster, serviceFilter)
+		if err != nil {
+			return err
+		}
+
+		output, err := describeService(context.TODO(), client, service)
+		if err != nil {
+			return err
+		}
+
+		if structuredOutput() {
+			return printOutput(output)
+		}
+
+		printDescribe(os.Stdout, output, time.Now())
+		return nil
+	},
+}
+
+// DescribeOutput is the summary of the status of a service.
+type DescribeOutput struct {
+	ServiceOutput     `yaml:",inline"`
+	LaunchType        string               `json:"launchType,omitempty" yaml:"launchType,omitempty"`
+	CapacityProviders []string             `json:"capacityProviders,omitempty" yaml:"capacityProviders,omitempty"`
+	Network           *NetworkOutput       `json:"network,omitempty" yaml:"network,omitempty"`
+	LoadBalancers     []LoadBalancerOutput `json:"loadBalancers,omitempty" yaml:"loadBalancers,omitempty"`
+	Deployments       []DeploymentOutput   `json:"deployments" yaml:"deployments"`
+	Tasks             []TaskOutput         `json:"tasks" yaml:"tasks"`
+	Events            []EventOutput        `json:"events" yaml:"events"`
+}
+
+type NetworkOutput struct {
+	Subnets        []string `json:"subnets" yaml:"subnets"`
+	SecurityGroups []string `json:"securityGroups" yaml:"securityGroups"`
+	AssignPublicIp string   `json:"assignPublicIp" yaml:"assignPublicIp"`
+}
+
+type LoadBalancerOutput struct {
+	TargetGroup      string `json:"targetGroup,omitempty" yaml:"targetGroup,omitempty"`
+	LoadBalancerName string `json:"loadBalancerName,omitempty" yaml:"loadBalancerName,omitempty"`
+	Container        string `json:"container" yaml:"container"`
+	Port             int32  `json:"port" yaml:"port"`
+}
+
+type DeploymentOutput struct {
+	Id                 string     `json:"id" yaml:"id"`
+	Status             string     `json:"status" yaml:"status"`
+	TaskDefinition     string     `json:"taskDefinition" yaml:"taskDefinition"`
+	RolloutState       string     `json:"rolloutState,omitempty" yaml:"rolloutState,omitempty"`
+	RolloutStateReason string     `json:"rolloutStateReason,omitempty" yaml:"rolloutStateReason,omitempty"`
+	DesiredCount       int32      `json:"desiredCount" yaml:"desiredCount"`
+	RunningCount       int32      `json:"runningCount" yaml:"runningCount"`
+	PendingCount       int32      `json:"pendingCount" yaml:"pendingCount"`
+	FailedTasks        int32      `json:"failedTasks" yaml:"failedTasks"`
+	CreatedAt          *time.Time `json:"createdAt,omitempty" yaml:"createdAt,omitempty"`
+}
+
+type TaskOutput struct {
+	Task             string     `json:"task" yaml:"task"`
+	TaskDefinition   string     `json:"taskDefinition" yaml:"taskDefinition"`
+	LastStatus       string     `json:"lastStatus" yaml:"lastStatus"`
+	HealthStatus     string     `json:"healthStatus" yaml:"healthStatus"`
+	AvailabilityZone string     `json:"availabilityZone,omitempty" yaml:"availabilityZone,omitempty"`
+	PrivateIp        string     `json:"privateIp,omitempty" yaml:"privateIp,omitempty"`
+	StartedAt        *time.Time `json:"startedAt,omitempty" yaml:"startedAt,omitempty"`
+}
+
+func describeService(
+	ctx context.Context,
+	client client.Client,
+	service *types.Service,
+) (DescribeOutput, error) {
+	output := DescribeOutput{
+		ServiceOutput: serviceOutput(service),
+		LaunchType:    string(service.LaunchType),
+		Deployments:   []DeploymentOutput{},
+		Tasks:         []TaskOutput{},
+		Events:        []EventOutput{},
+	}
+
+	for _, strategy := range service.CapacityProviderStrategy {
+		output.CapacityProviders = append(
+			output.CapacityProviders,
+			fmt.Sprintf("%s (weight %d, base %d)", stringValue(strategy.CapacityProvider), strategy.Weight, strategy.Base),
+		)
+	}
+
+	if service.NetworkConfiguration != nil && service.NetworkConfiguration.AwsvpcConfiguration != nil {
+		awsvpc := service.NetworkConfiguration.AwsvpcConfiguration
+		output.Network = &NetworkOutput{
+			Subnets:        awsvpc.Subnets,
+			SecurityGroups: awsvpc.SecurityGroups,
+			AssignPublicIp: string(awsvpc.AssignPublicIp),
+		}
+	}
+
+	for _, loadBalancer := range service.LoadBalancers {
+		lb := LoadBalancerOutput{
+			TargetGroup:      stringValue(loadBalancer.TargetGroupArn),
+			LoadBalancerName: stringValue(loadBalancer.LoadBalancerName),
+			Container:        stringValue(loadBalancer.ContainerName),
+		}
+		if loadBalancer.ContainerPort != nil {
+			lb.Port = *loadBalancer.ContainerPort
+		}
+		output.LoadBalancers = append(output.LoadBalancers, lb)
+	}
+
+	for _, deployment := range service.Deployments {
+		output.Deployments = append(output.Deployments, DeploymentOutput{
+			Id:                 stringValue(deployment.Id),
+			Status:             stringValue(deployment.Status),
+			TaskDefinition:     stringValue(deployment.TaskDefinition),
+			RolloutState:       string(deployment.RolloutState),
+			RolloutStateReason: stringValue(deployment.RolloutStateReason),
+			DesiredCount:       deployment.DesiredCount,
+			RunningCount:       deployment.RunningCount,
+			PendingCount:       deployment.PendingCount,
+			FailedTasks:        deployment.FailedTasks,
+			CreatedAt:          deployment.CreatedAt,
+		})
+	}
+
+	taskArns, err := client.ListTasks(ctx, *service.ClusterArn, *service.ServiceArn, types.DesiredStatusRunning)
+	if err != nil {
+		return output, err
+	}
+	if len(taskArns) > 0 {
+		tasks, err := client.DescribeTasks(ctx, *service.ClusterArn, taskArns)
+		if err != nil {
+			return output, err
+		}
+		for _, task := range tasks {
+			output.Tasks = append(output.Tasks, TaskOutput{
+				Task:             *task.TaskArn,
+				TaskDefinition:   stringValue(task.TaskDefinitionArn),
+				LastStatus:       stringValue(task.LastStatus),
+				HealthStatus:     string(task.HealthStatus),
+				AvailabilityZone: stringValue(task.AvailabilityZone),
+				PrivateIp:        taskPrivateIp(task),
+				StartedAt:        task.StartedAt,
+			})
+		}
+	}
+
+	for _, event := range service.Events {
+		if len(output.Events) == describeEventsLimit {
+			break
+		}
+		eventOutput := EventOutput{
+			Service: *service.ServiceArn,
+			Id:      stringValue(event.Id),
+			Message: stringValue(event.Message),
+		}
+		if event.CreatedAt != nil {
+			eventOutput.CreatedAt = *event.CreatedAt
+		}
+		output.Events = append(output.Events, eventOutput)
+	}
+
+	return output, nil
+}
+
+// taskPrivateIp returns the private IP address of the task's network
+// interface, if any.
+func taskPrivateIp(task types.Task) string {
+	for _, attachment := range task.Attachments {
+		for _, detail := range attachment.Details {
+			if stringValue(detail.Name) == "privateIPv4Address" {
+				return stringValue(detail.Value)
+			}
+		}
+	}
+	for _, container := range task.Containers {
+		for _, networkInterface := range container.NetworkInterfaces {
+			if networkInterface.PrivateIpv4Address != nil {
+				return *networkInterface.PrivateIpv4Address
+			}
+		}
+	}
+	return ""
+}
+
+func printDescribe(w io.Writer, output DescribeOutput, now time.Time) {
+	fmt.Fprintf(w, "%s %s\n", titleStyle.Render("Service:"), output.Service)
+	fmt.Fprintf(w, "%s %s\n", titleStyle.Render("Status:"), output.Status)
+	fmt.Fprintf(w, "%s %s\n", titleStyle.Render("Task definition:"), output.TaskDefinition)
+	fmt.Fprintf(
+		w,
+		"%s %d desired, %d running, %d pending\n",
+		titleStyle.Render("Tasks:"),
+		output.DesiredCount,
+		output.RunningCount,
+		output.PendingCount,
+	)
+	if output.LaunchType != "" {
+		fmt.Fprintf(w, "%s %s\n", titleStyle.Render("Launch type:"), output.LaunchType)
+	}
+	if len(output.CapacityProviders) > 0 {
+		fmt.Fprintf(w, "%s %s\n", titleStyle.Render("Capacity providers:"), strings.Join(output.CapacityProviders, ", "))
+	}
+	if output.Network != nil {
+		fmt.Fprintf(
+			w,
+			"%s subnets %s, security groups %s, public IP %s\n",
+			titleStyle.Render("Network:"),
+			strings.Join(output.Network.Subnets, ", "),
+			strings.Join(output.Network.SecurityGroups, ", "),
+			strings.ToLower(output.Network.AssignPublicIp),
+		)
+	}
+	for _, lb := range output.LoadBalancers {
+		target := lb.TargetGroup
+		if target == "" {
+			target = lb.LoadBalancerName
+		}
+		fmt.Fprintf(w, "%s %s -> %s:%d\n", titleStyle.Render("Load balancer:"), target, lb.Container, lb.Port)
+	}
+
+	fmt.Fprintf(w, "\n%s\n", titleStyle.Render("Deployments:"))
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "  STATUS\tTASK DEFINITION\tROLLOUT\tDESIRED\tRUNNING\tPENDING\tFAILED\tCREATED")
+	for _, deployment := range output.Deployments {
+		rollout := deployment.RolloutState
+		if rollout == "" {
+			rollout = "-"
+		}
+		created := "-"
+		if deployment.CreatedAt != nil {
+			created = relativeTime(*deployment.CreatedAt, now)
+		}
+		fmt.Fprintf(
+			tw,
+			"  %s\t%s\t%s\t%d\t%d\t%d\t%d\t%s\n",
+			deployment.Status,
+			shortTaskDefinition(&deployment.TaskDefinition),
+			rollout,
+			deployment.DesiredCount,
+			deployment.RunningCount,
+			deployment.PendingCount,
+			deployment.FailedTasks,
+			created,
+		)
+	}
+	tw.Flush()
+	for _, deployment := range output.Deployments {
+		if deployment.RolloutStateReason != "" {
+			fmt.Fprintf(w, "  %s: %s\n", deployment.Status, deployment.RolloutStateReason)
+		}
+	}
+
+	fmt.Fprintf(w, "\n%s\n", titleStyle.Render("Running tasks:"))
+	tw = tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "  TASK\tTASK DEFINITION\tSTATUS\tHEALTH\tAZ\tIP\tSTARTED")
+	for _, task := range output.Tasks {
+		started := "-"
+		if task.StartedAt != nil {
+			started = relativeTime(*task.StartedAt, now)
+		}
+		fmt.Fprintf(
+			tw,
+			"  %s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			task.Task[strings.LastIndex(task.Task, "/")+1:],
+			shortTaskDefinition(&task.TaskDefinition),
+			task.LastStatus,
+			task.HealthStatus,
+			valueOrDash(task.AvailabilityZone),
+			valueOrDash(task.PrivateIp),
+			started,
+		)
+	}
+	tw.Flush()
+
+	fmt.Fprintf(w, "\n%s\n", titleStyle.Render("Recent events:"))
+	for _, event := range output.Events {
+		fmt.Fprintf(w, "  %8s  %s\n", relativeTime(event.CreatedAt, now), event.Message)
+	}
+}
+
+func valueOrDash(value string) string {
+	if value == "" {
+		return "-"
+	}
+	return value
+}
+
+func init() {
+	rootCmd.AddCommand(describeCmd)
+}
@@ -0,0 +1,79 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ecs/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDescribeService(t *testing.T) {
+	now := time.Now()
+	taskArn := "arn:aws:ecs:us-east-1:123456789012:task/my-cluster/1234"
+	service := rolloutService(
+		rolloutDeployment("ecs-svc/2", "PRIMARY", "2", types.DeploymentRolloutStateCompleted, 2),
+	)
+	service.Status = aws.String("ACTIVE")
+	service.LaunchType = types.LaunchTypeFargate
+	service.NetworkConfiguration = &types.NetworkConfiguration{
+		AwsvpcConfiguration: &types.AwsVpcConfiguration{
+			Subnets:        []string{"subnet-1", "subnet-2"},
+			SecurityGroups: []string{"sg-1"},
+			AssignPublicIp: types.AssignPublicIpDisabled,
+		},
+	}
+	service.LoadBalancers = []types.LoadBalancer{
+		{TargetGroupArn: aws.String("arn:aws:elasticloadbalancing:tg/api"), ContainerName: aws.String("app"), ContainerPort: aws.Int32(8080)},
+	}
+	service.Events = []types.ServiceEvent{
+		{Id: aws.String("1"), CreatedAt: aws.Time(now.Add(-time.Minute)), Message: aws.String("(service api) has reached a steady state.")},
+	}
+
+	mockClient := new(MockClient)
+	mockClient.On("ListTasks", mock.Anything, *service.ClusterArn, *service.ServiceArn, types.DesiredStatusRunning).
+		Return([]string{taskArn}, nil)
+	mockClient.On("DescribeTasks", mock.Anything, *service.ClusterArn, []string{taskArn}).
+		Return([]types.Task{
+			{
+				TaskArn:           aws.String(taskArn),
+				TaskDefinitionArn: service.TaskDefinition,
+				LastStatus:        aws.String("RUNNING"),
+				HealthStatus:      types.HealthStatusHealthy,
+				AvailabilityZone:  aws.String("us-east-1a"),
+				StartedAt:         aws.Time(now.Add(-time.Hour)),
+				Attachments: []types.Attachment{
+					{Details: []types.KeyValuePair{
+						{Name: aws.String("subnetId"), Value: aws.String("subnet-1")},
+						{Name: aws.String("privateIPv4Address"), Value: aws.String("10.0.1.15")},
+					}},
+				},
+			},
+		}, nil)
+
+	output, err := describeService(context.Background(), mockClient, &service)
+	require.NoError(t, err)
+
+	assert.Equal(t, "FARGATE", output.LaunchType)
+	assert.Equal(t, "10.0.1.15", output.Tasks[0].PrivateIp)
+	assert.Equal(t, "COMPLETED", output.Deployments[0].RolloutState)
+
+	var human bytes.Buffer
+	printDescribe(&human, output, now)
+
+	assert.Contains(t, human.String(), "Network: subnets subnet-1, subnet-2, security groups sg-1, public IP disabled\n")
+	assert.Contains(t, human.String(), "Load balancer: arn:aws:elasticloadbalancing:tg/api -> app:8080\n")
+	assert.Contains(t, human.String(), "  PRIMARY  api:2            COMPLETED  2        2        0        0       -\n")
+	assert.Contains(t, human.String(), "  1234  api:2            RUNNING  HEALTHY  us-east-1a  10.0.1.15  1h ago\n")
+	assert.Contains(t, human.String(), "    1m ago  (service api) has reached a steady state.\n")
+
+	var structured bytes.Buffer
+	require.NoError(t, writeOutput(&structured, outputJSON, output))
+	assert.Contains(t, structured.String(), `"service":"arn:aws:ecs:us-east-1:123456789012:service/my-cluster/api"`)
+	assert.Contains(t, structured.String(), `"privateIp":"10.0.1.15"`)
+}
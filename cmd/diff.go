@@ -0,0 +1,159 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/service/ecs/types"
+	"github.com/fatih/color"
+)
+
+var (
+	diffAdded   = color.New(color.FgGreen)
+	diffRemoved = color.New(color.FgRed)
+	diffChanged = color.New(color.FgYellow)
+)
+
+// DiffLine is a setting that differs between two task definitions. Old is
+// nil for added settings and New is nil for removed ones, as settings such as
+// environment variables can be set to an empty value.
+type DiffLine struct {
+	Key string
+	Old *string
+	New *string
+}
+
+// taskDefinitionDiff compares the settings that matter when rolling out a
+// task definition: images, environment, secrets, CPU, memory, ports, log
+// configuration and health checks.
+func taskDefinitionDiff(current *types.TaskDefinition, target *types.TaskDefinition) []DiffLine {
+	currentSettings := taskDefinitionSettings(current)
+	targetSettings := taskDefinitionSettings(target)
+
+	keys := make([]string, 0, len(currentSettings)+len(targetSettings))
+	for key := range currentSettings {
+		keys = append(keys, key)
+	}
+	for key := range targetSettings {
+		if _, ok := currentSettings[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	var lines []DiffLine
+	for _, key := range keys {
+		currentValue, targetValue := currentSettings[key], targetSettings[key]
+		if currentValue == nil || targetValue == nil || *currentValue != *targetValue {
+			lines = append(lines, DiffLine{Key: key, Old: currentValue, New: targetValue})
+		}
+	}
+	return lines
+}
+
+// taskDefinitionSettings flattens the task definition into settings, leaving
+// out the ones that are not set.
+func taskDefinitionSettings(taskDefinition *types.TaskDefinition) map[string]*string {
+	settings := map[string]*string{}
+	setValue := func(key string, value string) {
+		settings[key] = &value
+	}
+	set := func(key string, value *string) {
+		if value != nil {
+			setValue(key, *value)
+		}
+	}
+	setInt := func(key string, value *int32) {
+		if value != nil {
+			setValue(key, strconv.FormatInt(int64(*value), 10))
+		}
+	}
+
+	set("cpu", taskDefinition.Cpu)
+	set("memory", taskDefinition.Memory)
+
+	for _, container := range taskDefinition.ContainerDefinitions {
+		prefix := *container.Name + "."
+
+		set(prefix+"image", container.Image)
+		if container.Cpu != 0 {
+			setValue(prefix+"cpu", strconv.FormatInt(int64(container.Cpu), 10))
+		}
+		setInt(prefix+"memory", container.Memory)
+		setInt(prefix+"memoryReservation", container.MemoryReservation)
+
+		for _, env := range container.Environment {
+			set(prefix+"environment."+*env.Name, env.Value)
+		}
+		for _, secret := range container.Secrets {
+			set(prefix+"secrets."+*secret.Name, secret.ValueFrom)
+		}
+
+		for _, port := range container.PortMappings {
+			if port.ContainerPort == nil {
+				continue
+			}
+			key := fmt.Sprintf("%sports.%d/%s", prefix, *port.ContainerPort, strings.ToLower(string(port.Protocol)))
+			value := "container"
+			if port.HostPort != nil && *port.HostPort != 0 {
+				value = fmt.Sprintf("host %d", *port.HostPort)
+			}
+			setValue(key, value)
+		}
+
+		if logConfiguration := container.LogConfiguration; logConfiguration != nil {
+			setValue(prefix+"logConfiguration.driver", string(logConfiguration.LogDriver))
+			for name, value := range logConfiguration.Options {
+				setValue(prefix+"logConfiguration.options."+name, value)
+			}
+		}
+
+		if healthCheck := container.HealthCheck; healthCheck != nil {
+			setValue(prefix+"healthCheck.command", strings.Join(healthCheck.Command, " "))
+			setInt(prefix+"healthCheck.interval", healthCheck.Interval)
+			setInt(prefix+"healthCheck.timeout", healthCheck.Timeout)
+			setInt(prefix+"healthCheck.retries", healthCheck.Retries)
+			setInt(prefix+"healthCheck.startPeriod", healthCheck.StartPeriod)
+		}
+	}
+
+	return settings
+}
+
+// printDiff renders the diff lines, one setting per line, in color unless
+// --no-colors is set.
+func printDiff(w io.Writer, lines []DiffLine) {
+	if len(lines) == 0 {
+		fmt.Fprintln(w, "  no changes")
+		return
+	}
+
+	printLine := func(c *color.Color, format string, a ...any) {
+		if noColorsFlag {
+			fmt.Fprintf(w, format, a...)
+		} else {
+			c.Fprintf(w, format, a...)
+		}
+	}
+	for _, line := range lines {
+		switch {
+		case line.Old == nil:
+			printLine(diffAdded, "  + %s: %s\n", line.Key, diffValue(*line.New))
+		case line.New == nil:
+			printLine(diffRemoved, "  - %s: %s\n", line.Key, diffValue(*line.Old))
+		default:
+			printLine(diffChanged, "  ~ %s: %s -> %s\n", line.Key, diffValue(*line.Old), diffValue(*line.New))
+		}
+	}
+}
+
+// diffValue quotes empty values, so they stand out.
+func diffValue(value string) string {
+	if value == "" {
+		return `""`
+	}
+	return value
+}
@@ -0,0 +1,134 @@
+package cmd
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ecs/types"
+	"github.com/fatih/color"
+	"github.com/stretchr/testify/assert"
+)
+
+func testTaskDefinition(revision string, image string) *types.TaskDefinition {
+	return &types.TaskDefinition{
+		TaskDefinitionArn: aws.String("arn:aws:ecs:us-east-1:123456789012:task-definition/api:" + revision),
+		Cpu:               aws.String("256"),
+		Memory:            aws.String("512"),
+		ContainerDefinitions: []types.ContainerDefinition{
+			{
+				Name:  aws.String("app"),
+				Image: aws.String(image),
+				Environment: []types.KeyValuePair{
+					{Name: aws.String("LOG_LEVEL"), Value: aws.String("info")},
+				},
+				Secrets: []types.Secret{
+					{Name: aws.String("DB_PASSWORD"), ValueFrom: aws.String("arn:aws:ssm:db-password")},
+				},
+				PortMappings: []types.PortMapping{
+					{ContainerPort: aws.Int32(8080), Protocol: types.TransportProtocolTcp},
+				},
+				LogConfiguration: &types.LogConfiguration{
+					LogDriver: types.LogDriverAwslogs,
+					Options:   map[string]string{"awslogs-group": "/ecs/api"},
+				},
+				HealthCheck: &types.HealthCheck{
+					Command:  []string{"CMD-SHELL", "curl -f http://localhost:8080/health"},
+					Interval: aws.Int32(30),
+				},
+			},
+		},
+	}
+}
+
+func TestTaskDefinitionDiff_NoChanges(t *testing.T) {
+	current := testTaskDefinition("1", "api:1.0.0")
+	target := testTaskDefinition("2", "api:1.0.0")
+
+	assert.Empty(t, taskDefinitionDiff(current, target))
+}
+
+func TestTaskDefinitionDiff(t *testing.T) {
+	current := testTaskDefinition("1", "api:1.0.0")
+	target := testTaskDefinition("2", "api:1.1.0")
+	target.Memory = aws.String("1024")
+	app := &target.ContainerDefinitions[0]
+	app.Environment = append(app.Environment, types.KeyValuePair{
+		Name:  aws.String("FEATURE_FLAG"),
+		Value: aws.String("true"),
+	})
+	app.Secrets = nil
+	app.PortMappings[0].HostPort = aws.Int32(80)
+	app.HealthCheck.Interval = aws.Int32(10)
+
+	assert.Equal(
+		t,
+		[]DiffLine{
+			{Key: "app.environment.FEATURE_FLAG", New: aws.String("true")},
+			{Key: "app.healthCheck.interval", Old: aws.String("30"), New: aws.String("10")},
+			{Key: "app.image", Old: aws.String("api:1.0.0"), New: aws.String("api:1.1.0")},
+			{Key: "app.ports.8080/tcp", Old: aws.String("container"), New: aws.String("host 80")},
+			{Key: "app.secrets.DB_PASSWORD", Old: aws.String("arn:aws:ssm:db-password")},
+			{Key: "memory", Old: aws.String("512"), New: aws.String("1024")},
+		},
+		taskDefinitionDiff(current, target),
+	)
+}
+
+func TestTaskDefinitionDiff_EmptyValues(t *testing.T) {
+	current := testTaskDefinition("1", "api:1.0.0")
+	current.ContainerDefinitions[0].Environment = []types.KeyValuePair{
+		{Name: aws.String("REMOVED"), Value: aws.String("")},
+		{Name: aws.String("CHANGED"), Value: aws.String("")},
+	}
+	target := testTaskDefinition("2", "api:1.0.0")
+	target.ContainerDefinitions[0].Environment = []types.KeyValuePair{
+		{Name: aws.String("ADDED"), Value: aws.String("")},
+		{Name: aws.String("CHANGED"), Value: aws.String("x")},
+	}
+
+	assert.Equal(
+		t,
+		[]DiffLine{
+			{Key: "app.environment.ADDED", New: aws.String("")},
+			{Key: "app.environment.CHANGED", Old: aws.String(""), New: aws.String("x")},
+			{Key: "app.environment.REMOVED", Old: aws.String("")},
+		},
+		taskDefinitionDiff(current, target),
+	)
+}
+
+func TestPrintDiff(t *testing.T) {
+	var output bytes.Buffer
+
+	printDiff(&output, []DiffLine{
+		{Key: "app.environment.FEATURE_FLAG", New: aws.String("true")},
+		{Key: "app.environment.EMPTY", Old: aws.String(""), New: aws.String("x")},
+		{Key: "app.image", Old: aws.String("api:1.0.0"), New: aws.String("api:1.1.0")},
+		{Key: "app.secrets.DB_PASSWORD", Old: aws.String("arn:aws:ssm:db-password")},
+	})
+
+	assert.Equal(
+		t,
+		"  + app.environment.FEATURE_FLAG: true\n"+
+			"  ~ app.environment.EMPTY: \"\" -> x\n"+
+			"  ~ app.image: api:1.0.0 -> api:1.1.0\n"+
+			"  - app.secrets.DB_PASSWORD: arn:aws:ssm:db-password\n",
+		output.String(),
+	)
+}
+
+func TestPrintDiff_NoColors(t *testing.T) {
+	noColor := color.NoColor
+	color.NoColor = false
+	noColorsFlag = true
+	t.Cleanup(func() {
+		color.NoColor = noColor
+		noColorsFlag = false
+	})
+
+	var output bytes.Buffer
+	printDiff(&output, []DiffLine{{Key: "app.image", New: aws.String("api:1.1.0")}})
+
+	assert.Equal(t, "  + app.image: api:1.1.0\n", output.String())
+}
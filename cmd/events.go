@@ -0,0 +1,201 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/ecs/types"
+	"github.com/fatih/color"
+	"github.com/sestrella/iecs/client"
+	"github.com/spf13/cobra"
+)
+
+const eventsPollInterval = 5 * time.Second
+
+// Keywords used by ECS in the events that need attention.
+var (
+	eventErrorKeywords = []string{
+		"unable",
+		"failed",
+		"error",
+		"unhealthy",
+		"insufficient",
+		"circuit breaker",
+		"rolling back",
+	}
+	eventWarningKeywords = []string{
+		"deregistered",
+		"draining",
+		"stopped",
+		"throttled",
+	}
+)
+
+var (
+	eventError   = color.New(color.FgRed)
+	eventWarning = color.New(color.FgYellow)
+	eventSuccess = color.New(color.FgGreen)
+)
+
+var eventsCmd = &cobra.Command{
+	Use:   "events",
+	Short: "Show the events of a service",
+	Example: `
+  iecs events
+  iecs events --follow
+  `,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		follow, err := cmd.Flags().GetBool("follow")
+		if err != nil {
+			return err
+		}
+
+		client, err := newClient(context.TODO())
+		if err != nil {
+			return err
+		}
+
+		selectors := newSelectors(client)
+
+		cluster, err := selectors.Cluster(context.TODO(), clusterFilter)
+		if err != nil {
+			return err
+		}
+
+		service, err := selectors.Service(context.TODO(), cluster, serviceFilter)
+		if err != nil {
+			return err
+		}
+
+		return runEvents(context.TODO(), os.Stdout, client, service, follow, eventsPollInterval)
+	},
+}
+
+// EventOutput is the machine-readable form of a service event.
+type EventOutput struct {
+	Service   string    `json:"service" yaml:"service"`
+	Id        string    `json:"id" yaml:"id"`
+	CreatedAt time.Time `json:"createdAt" yaml:"createdAt"`
+	Message   string    `json:"message" yaml:"message"`
+}
+
+func runEvents(
+	ctx context.Context,
+	w io.Writer,
+	client client.Client,
+	service *types.Service,
+	follow bool,
+	interval time.Duration,
+) error {
+	seen := map[string]bool{}
+	for {
+		if err := printEvents(w, service, seen, time.Now()); err != nil {
+			return err
+		}
+		if !follow {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(interval):
+		}
+
+		services, err := client.DescribeServices(ctx, *service.ClusterArn, []string{*service.ServiceArn})
+		if err != nil {
+			return err
+		}
+		if len(services) == 0 {
+			return fmt.Errorf("service %s not found", *service.ServiceArn)
+		}
+		service = &services[0]
+	}
+}
+
+// printEvents prints the events of the service not seen before, oldest first.
+func printEvents(w io.Writer, service *types.Service, seen map[string]bool, now time.Time) error {
+	// ECS returns the newest events first
+	for i := len(service.Events) - 1; i >= 0; i-- {
+		event := service.Events[i]
+		if event.Id == nil || seen[*event.Id] {
+			continue
+		}
+		seen[*event.Id] = true
+
+		if structuredOutput() {
+			output := EventOutput{
+				Service: *service.ServiceArn,
+				Id:      *event.Id,
+				Message: stringValue(event.Message),
+			}
+			if event.CreatedAt != nil {
+				output.CreatedAt = *event.CreatedAt
+			}
+			if err := writeOutput(w, outputFormat, output); err != nil {
+				return err
+			}
+			continue
+		}
+
+		message := stringValue(event.Message)
+		timestamp := ""
+		if event.CreatedAt != nil {
+			timestamp = relativeTime(*event.CreatedAt, now)
+		}
+		fmt.Fprintf(w, "%8s  ", timestamp)
+		if eventColor := eventSeverity(message); eventColor != nil {
+			eventColor.Fprintln(w, message)
+		} else {
+			fmt.Fprintln(w, message)
+		}
+	}
+	return nil
+}
+
+// eventSeverity returns the color matching the severity of an event message,
+// or nil for informational events.
+func eventSeverity(message string) *color.Color {
+	message = strings.ToLower(message)
+	for _, keyword := range eventErrorKeywords {
+		if strings.Contains(message, keyword) {
+			return eventError
+		}
+	}
+	for _, keyword := range eventWarningKeywords {
+		if strings.Contains(message, keyword) {
+			return eventWarning
+		}
+	}
+	if strings.Contains(message, "steady state") || strings.Contains(message, "deployment completed") {
+		return eventSuccess
+	}
+	return nil
+}
+
+// relativeTime describes how long ago t happened, e.g. "5m ago".
+func relativeTime(t time.Time, now time.Time) string {
+	elapsed := now.Sub(t)
+	switch {
+	case elapsed < time.Second:
+		return "now"
+	case elapsed < time.Minute:
+		return fmt.Sprintf("%ds ago", int(elapsed.Seconds()))
+	case elapsed < time.Hour:
+		return fmt.Sprintf("%dm ago", int(elapsed.Minutes()))
+	case elapsed < 24*time.Hour:
+		return fmt.Sprintf("%dh ago", int(elapsed.Hours()))
+	default:
+		return fmt.Sprintf("%dd ago", int(elapsed.Hours()/24))
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(eventsCmd)
+
+	eventsCmd.Flags().BoolP("follow", "f", false, "Keep polling the service and print new events")
+}
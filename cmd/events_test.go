@@ -0,0 +1,80 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ecs/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func eventsService(now time.Time, events ...string) types.Service {
+	service := types.Service{
+		ClusterArn: aws.String("arn:aws:ecs:us-east-1:123456789012:cluster/my-cluster"),
+		ServiceArn: aws.String("arn:aws:ecs:us-east-1:123456789012:service/my-cluster/api"),
+	}
+	// Newest first, as returned by ECS
+	for i := len(events) - 1; i >= 0; i-- {
+		service.Events = append(service.Events, types.ServiceEvent{
+			Id:        aws.String(events[i]),
+			CreatedAt: aws.Time(now.Add(-time.Duration(len(events)-i) * time.Minute)),
+			Message:   aws.String("(service api) " + events[i]),
+		})
+	}
+	return service
+}
+
+func TestPrintEvents(t *testing.T) {
+	now := time.Now()
+	service := eventsService(now, "has reached a steady state.", "has started 1 tasks")
+	seen := map[string]bool{"has reached a steady state.": true}
+
+	var output bytes.Buffer
+	err := printEvents(&output, &service, seen, now)
+
+	require.NoError(t, err)
+	assert.Equal(t, "  1m ago  (service api) has started 1 tasks\n", output.String())
+	assert.True(t, seen["has started 1 tasks"])
+}
+
+func TestRunEvents_Follow(t *testing.T) {
+	now := time.Now()
+	first := eventsService(now, "has started 1 tasks")
+	second := eventsService(now, "has started 1 tasks", "has reached a steady state.")
+	mockClient := new(MockClient)
+	mockClient.On("DescribeServices", mock.Anything, *first.ClusterArn, []string{*first.ServiceArn}).
+		Return([]types.Service{second}, nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	var output bytes.Buffer
+	err := runEvents(ctx, &output, mockClient, &first, true, time.Millisecond)
+
+	require.NoError(t, err)
+	assert.Equal(t, 1, bytes.Count(output.Bytes(), []byte("has started 1 tasks")))
+	assert.Equal(t, 1, bytes.Count(output.Bytes(), []byte("steady state")))
+}
+
+func TestEventSeverity(t *testing.T) {
+	assert.Equal(t, eventError, eventSeverity("(service api) is unable to consistently start tasks successfully."))
+	assert.Equal(t, eventError, eventSeverity("(service api) (task 1234) failed container health checks."))
+	assert.Equal(t, eventWarning, eventSeverity("(service api) has stopped 1 running tasks: (task 1234)."))
+	assert.Equal(t, eventSuccess, eventSeverity("(service api) has reached a steady state."))
+	assert.Nil(t, eventSeverity("(service api) has started 1 tasks: (task 1234)."))
+}
+
+func TestRelativeTime(t *testing.T) {
+	now := time.Now()
+
+	assert.Equal(t, "now", relativeTime(now, now))
+	assert.Equal(t, "42s ago", relativeTime(now.Add(-42*time.Second), now))
+	assert.Equal(t, "5m ago", relativeTime(now.Add(-5*time.Minute), now))
+	assert.Equal(t, "3h ago", relativeTime(now.Add(-3*time.Hour), now))
+	assert.Equal(t, "2d ago", relativeTime(now.Add(-50*time.Hour), now))
+}
@@ -2,12 +2,15 @@ package cmd
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"io"
 	"log"
 	"os"
+	"os/exec"
 	"os/signal"
 	"syscall"
 
-	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/service/ecs/types"
 	"github.com/sestrella/iecs/client"
 	"github.com/sestrella/iecs/selector"
@@ -17,8 +20,12 @@ import (
 const (
 	execCommandFlag     = "command"
 	execInteractiveFlag = "interactive"
+	execAllFlag         = "all"
+	execOutputFileFlag  = "output-file"
 )
 
+const execDefaultCommand = "/bin/bash"
+
 type ExecSelection struct {
 	cluster   *types.Cluster
 	service   *types.Service
@@ -32,6 +39,8 @@ var execCmd = &cobra.Command{
 	Example: `
   aws-vault exec <profile> -- iecs exec [flags] (recommended)
   env AWS_PROFILE=<profile> iecs exec [flags]
+  iecs exec --all --command "cat /etc/resolv.conf"
+  iecs exec --interactive=false --command "pg_dump app" --output-file app.sql
   `,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		command, err := cmd.Flags().GetString(execCommandFlag)
@@ -44,17 +53,80 @@ var execCmd = &cobra.Command{
 			return err
 		}
 
-		cfg, err := config.LoadDefaultConfig(context.TODO())
+		outputFile, err := cmd.Flags().GetString(execOutputFileFlag)
 		if err != nil {
 			return err
 		}
+		if outputFile != "" && interactive {
+			return fmt.Errorf("--%s requires --interactive=false", execOutputFileFlag)
+		}
 
-		awsClient := client.NewClient(cfg)
+		awsClient, err := newClient(context.TODO())
+		if err != nil {
+			return err
+		}
 
-		selection, err := execSelector(context.TODO(), selector.NewSelectors(awsClient, *theme))
+		all, err := cmd.Flags().GetBool(execAllFlag)
 		if err != nil {
 			return err
 		}
+		if all {
+			if !cmd.Flags().Changed(execCommandFlag) {
+				return fmt.Errorf("--command is required with --all")
+			}
+			if outputFile != "" {
+				return fmt.Errorf("--%s cannot be used with --all", execOutputFileFlag)
+			}
+
+			concurrency, err := cmd.Flags().GetInt("concurrency")
+			if err != nil {
+				return err
+			}
+
+			noColors, err := cmd.Flags().GetBool("no-colors")
+			if err != nil {
+				return err
+			}
+
+			selection, err := execAllSelector(context.TODO(), newSelectors(awsClient))
+			if err != nil {
+				return err
+			}
+
+			return runExecAll(
+				context.TODO(),
+				decorationOutput(),
+				noColors,
+				awsClient,
+				*selection,
+				command,
+				concurrency,
+			)
+		}
+
+		selection, err := execSelector(context.TODO(), newSelectors(awsClient))
+		if err != nil {
+			return err
+		}
+
+		if structuredOutput() {
+			if err = printOutput(selection.output()); err != nil {
+				return err
+			}
+		}
+
+		if !interactive {
+			var w io.Writer = os.Stdout
+			if outputFile != "" {
+				file, err := os.Create(outputFile)
+				if err != nil {
+					return err
+				}
+				defer file.Close()
+				w = file
+			}
+			return runExecCommand(context.TODO(), w, awsClient, *selection, command)
+		}
 
 		err = runExec(
 			context.TODO(),
@@ -89,48 +161,115 @@ func runExec(
 	if err != nil {
 		return err
 	}
+
+	return runSession(cmd)
+}
+
+// runExecCommand runs the command writing its output to w without the banners
+// of the session manager plugin. ECS only supports interactive sessions, so
+// the session is interactive regardless. The command runs in a shell that
+// reports its exit code, a non-zero one is returned as an ExitError.
+func runExecCommand(
+	ctx context.Context,
+	w io.Writer,
+	client client.Client,
+	selection ExecSelection,
+	command string,
+) error {
+	cmd, err := client.ExecuteCommand(
+		ctx,
+		selection.cluster,
+		*selection.task.TaskArn,
+		selection.container,
+		withExitCode(command),
+		true,
+	)
+	if err != nil {
+		return err
+	}
+
+	output := newSessionFilter(w)
+	cmd.Stdout = output
+	err = runSession(cmd)
+	if closeErr := output.Close(); err == nil {
+		err = closeErr
+	}
+
+	// The reported exit code takes precedence over the one of the session,
+	// which fails whenever the command does
+	if output.exitCode == nil {
+		if err != nil {
+			return err
+		}
+		return fmt.Errorf(
+			"the exit code of the command was not reported, is sh available in container %s?",
+			*selection.container.Name,
+		)
+	}
+	if *output.exitCode != 0 {
+		return &ExitError{
+			Code:    *output.exitCode,
+			Message: fmt.Sprintf("command exited with code %d", *output.exitCode),
+		}
+	}
+	return nil
+}
+
+// runSession runs a session-manager-plugin command attached to the terminal,
+// forwarding the signals received to it. Unless cmd.Stdout is already set, the
+// output goes to the terminal, or to stderr when printing structured results,
+// so the plugin banners don't mix with them. The exit code of the session is
+// returned as an ExitError, which the session already reported.
+func runSession(cmd *exec.Cmd) error {
 	cmd.Stdin = os.Stdin
-	cmd.Stdout = os.Stdout
+	if cmd.Stdout == nil {
+		cmd.Stdout = decorationOutput()
+	}
 	cmd.Stderr = os.Stderr
-	if err = cmd.Start(); err != nil {
+	if err := cmd.Start(); err != nil {
 		return err
 	}
 
 	sigs := make(chan os.Signal, 1)
 	signal.Notify(sigs, syscall.SIGHUP, syscall.SIGINT, syscall.SIGQUIT, syscall.SIGTERM)
+	defer signal.Stop(sigs)
 
 	// Reference: https://github.com/kubernetes/kubectl/blob/master/pkg/util/interrupt/interrupt.go
 	go func() {
 		sig := <-sigs
-		err = cmd.Process.Signal(sig)
-		if err != nil {
+		if err := cmd.Process.Signal(sig); err != nil {
 			log.Fatal(err)
 		}
 	}()
 
-	return cmd.Wait()
+	err := cmd.Wait()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() >= 0 {
+		return &ExitError{Code: exitErr.ExitCode()}
+	}
+	return err
 }
 
 func execSelector(
 	ctx context.Context,
 	selectors selector.Selectors,
 ) (*ExecSelection, error) {
-	cluster, err := selectors.Cluster(ctx, clusterRegex)
+	cluster, err := selectors.Cluster(ctx, clusterFilter)
 	if err != nil {
 		return nil, err
 	}
 
-	service, err := selectors.Service(ctx, cluster, serviceRegex)
+	service, err := selectors.Service(ctx, cluster, serviceFilter)
 	if err != nil {
 		return nil, err
 	}
 
-	task, err := selectors.Task(ctx, service)
+	task, err := selectors.Task(ctx, service, taskFilter)
 	if err != nil {
 		return nil, err
 	}
 
-	container, err := selectors.Container(ctx, task.Containers)
+	container, err := selectors.Container(ctx, task.Containers, containerFilter)
 	if err != nil {
 		return nil, err
 	}
@@ -146,6 +285,13 @@ func execSelector(
 func init() {
 	rootCmd.AddCommand(execCmd)
 
-	execCmd.Flags().StringP(execCommandFlag, "c", "/bin/bash", "command to run")
+	execCmd.Flags().StringP(execCommandFlag, "c", execDefaultCommand, "command to run")
 	execCmd.Flags().BoolP(execInteractiveFlag, "i", true, "toggles interactive mode")
+	execCmd.Flags().
+		Bool(execAllFlag, false, "Run the command on every selected task instead of a single one")
+	execCmd.Flags().
+		Int("concurrency", 5, "The number of tasks the command runs on at the same time, with --all")
+	execCmd.Flags().Bool("no-colors", false, "Disable output coloring, with --all")
+	execCmd.Flags().
+		String(execOutputFileFlag, "", "Write the output of the command to a file, with --interactive=false")
 }
@@ -0,0 +1,267 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+	"sync"
+	"text/tabwriter"
+
+	"github.com/aws/aws-sdk-go-v2/service/ecs/types"
+	"github.com/sestrella/iecs/client"
+	"github.com/sestrella/iecs/selector"
+)
+
+// ExecAllSelection holds the tasks a command is broadcast to, along with the
+// name of the container to run it in.
+type ExecAllSelection struct {
+	cluster   *types.Cluster
+	service   *types.Service
+	tasks     []types.Task
+	container string
+}
+
+// ExecResult is the machine-readable form of the outcome of a command run on
+// one of the tasks.
+type ExecResult struct {
+	Task      string   `json:"task" yaml:"task"`
+	Container string   `json:"container" yaml:"container"`
+	ExitCode  int      `json:"exitCode" yaml:"exitCode"`
+	Error     string   `json:"error,omitempty" yaml:"error,omitempty"`
+	Output    []string `json:"output" yaml:"output"`
+}
+
+func execAllSelector(
+	ctx context.Context,
+	selectors selector.Selectors,
+) (*ExecAllSelection, error) {
+	cluster, err := selectors.Cluster(ctx, clusterFilter)
+	if err != nil {
+		return nil, err
+	}
+
+	service, err := selectors.Service(ctx, cluster, serviceFilter)
+	if err != nil {
+		return nil, err
+	}
+
+	// Without prompts, the command runs on every matching task
+	var tasks []types.Task
+	if nonInteractive {
+		tasks, err = selectors.AllTasks(ctx, service, taskFilter)
+	} else {
+		tasks, err = selectors.Tasks(ctx, service, taskFilter, false)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// The tasks of a service share their containers, the first one is used to
+	// pick the container by name
+	container, err := selectors.Container(ctx, tasks[0].Containers, containerFilter)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ExecAllSelection{
+		cluster:   cluster,
+		service:   service,
+		tasks:     tasks,
+		container: *container.Name,
+	}, nil
+}
+
+// runExecAll runs the command on every selected task, at most concurrency at
+// a time. The output of each task is printed as it comes, prefixed with the
+// task ID, followed by a table with the exit code of each task.
+func runExecAll(
+	ctx context.Context,
+	w io.Writer,
+	noColors bool,
+	client client.Client,
+	selection ExecAllSelection,
+	command string,
+	concurrency int,
+) error {
+	results := make([]ExecResult, len(selection.tasks))
+	semaphore := make(chan struct{}, max(concurrency, 1))
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for index, task := range selection.tasks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			semaphore <- struct{}{}
+			defer func() { <-semaphore }()
+
+			output := &linePrefixer{
+				mu:      &mu,
+				printer: printerByIndex(noColors, index),
+				prefix:  shortTaskId(*task.TaskArn),
+				quiet:   structuredOutput(),
+			}
+			results[index] = execTask(ctx, client, selection, task, command, output)
+		}()
+	}
+	wg.Wait()
+
+	failures := 0
+	for _, result := range results {
+		if result.ExitCode != 0 {
+			failures++
+		}
+	}
+
+	if structuredOutput() {
+		for _, result := range results {
+			if err := printOutput(result); err != nil {
+				return err
+			}
+		}
+	} else {
+		printExecResults(w, results)
+	}
+
+	if failures > 0 {
+		return &ExitError{
+			Code:    1,
+			Message: fmt.Sprintf("command failed on %d of %d tasks", failures, len(results)),
+		}
+	}
+	return nil
+}
+
+func execTask(
+	ctx context.Context,
+	client client.Client,
+	selection ExecAllSelection,
+	task types.Task,
+	command string,
+	output *linePrefixer,
+) ExecResult {
+	result := ExecResult{
+		Task:      *task.TaskArn,
+		Container: selection.container,
+		ExitCode:  -1,
+		Output:    []string{},
+	}
+
+	var container *types.Container
+	for i := range task.Containers {
+		if stringValue(task.Containers[i].Name) == selection.container {
+			container = &task.Containers[i]
+		}
+	}
+	if container == nil {
+		result.Error = fmt.Sprintf("container %s not found", selection.container)
+		return result
+	}
+
+	cmd, err := client.ExecuteCommand(
+		ctx,
+		selection.cluster,
+		*task.TaskArn,
+		container,
+		withExitCode(command),
+		true,
+	)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	filter := newSessionFilter(output)
+	cmd.Stdout = filter
+	cmd.Stderr = filter
+	err = cmd.Run()
+	_ = filter.Close()
+	output.Flush()
+	result.Output = output.lines
+
+	var exitErr *exec.ExitError
+	switch {
+	case filter.exitCode != nil:
+		result.ExitCode = *filter.exitCode
+	case err == nil:
+		result.Error = "exit code not reported"
+	case errors.As(err, &exitErr):
+		result.ExitCode = exitErr.ExitCode()
+	default:
+		result.Error = err.Error()
+	}
+	return result
+}
+
+func printExecResults(w io.Writer, results []ExecResult) {
+	fmt.Fprintln(w)
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "TASK\tCONTAINER\tEXIT CODE\tERROR")
+	for _, result := range results {
+		exitCode := fmt.Sprint(result.ExitCode)
+		if result.ExitCode < 0 {
+			exitCode = "-"
+		}
+		fmt.Fprintf(
+			tw,
+			"%s\t%s\t%s\t%s\n",
+			shortTaskId(result.Task),
+			result.Container,
+			exitCode,
+			valueOrDash(result.Error),
+		)
+	}
+	tw.Flush()
+}
+
+func shortTaskId(taskArn string) string {
+	return taskArn[strings.LastIndex(taskArn, "/")+1:]
+}
+
+// linePrefixer prints every complete line written to it, prefixed with the
+// task ID. The lines are kept for the structured output, in which case they
+// are not printed.
+type linePrefixer struct {
+	mu      *sync.Mutex
+	printer Printer
+	prefix  string
+	quiet   bool
+	buf     []byte
+	lines   []string
+}
+
+func (p *linePrefixer) Write(data []byte) (int, error) {
+	p.buf = append(p.buf, data...)
+	for {
+		index := bytes.IndexByte(p.buf, '\n')
+		if index < 0 {
+			return len(data), nil
+		}
+		p.emit(string(p.buf[:index]))
+		p.buf = p.buf[index+1:]
+	}
+}
+
+// Flush prints the last line when it does not end with a newline.
+func (p *linePrefixer) Flush() {
+	if len(p.buf) > 0 {
+		p.emit(string(p.buf))
+		p.buf = nil
+	}
+}
+
+func (p *linePrefixer) emit(line string) {
+	// The session manager plugin writes terminal line endings
+	line = strings.TrimSuffix(line, "\r")
+	p.lines = append(p.lines, line)
+	if p.quiet {
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.printer("%s | %s\n", p.prefix, line)
+}
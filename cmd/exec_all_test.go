@@ -0,0 +1,112 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os/exec"
+	"sync"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ecs/types"
+	"github.com/charmbracelet/huh"
+	"github.com/sestrella/iecs/selector"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunExecAll(t *testing.T) {
+	cluster := &types.Cluster{ClusterArn: aws.String("cluster")}
+	containers := []types.Container{{Name: aws.String("app")}}
+	selection := ExecAllSelection{
+		cluster: cluster,
+		tasks: []types.Task{
+			{TaskArn: aws.String("arn:aws:ecs:us-east-1:123456789012:task/cluster/aaa"), Containers: containers},
+			{TaskArn: aws.String("arn:aws:ecs:us-east-1:123456789012:task/cluster/bbb"), Containers: containers},
+			{TaskArn: aws.String("arn:aws:ecs:us-east-1:123456789012:task/cluster/ccc"), Containers: containers},
+		},
+		container: "app",
+	}
+
+	mockClient := new(MockClient)
+	mockClient.On("ExecuteCommand", mock.Anything, cluster, *selection.tasks[0].TaskArn, mock.Anything, withExitCode("hostname"), true).
+		Return(exec.Command("sh", "-c", "echo aaa; echo "+exitCodeMarker+"0"), nil)
+	mockClient.On("ExecuteCommand", mock.Anything, cluster, *selection.tasks[1].TaskArn, mock.Anything, withExitCode("hostname"), true).
+		Return(exec.Command("sh", "-c", "echo bbb; echo "+exitCodeMarker+"3"), nil)
+	mockClient.On("ExecuteCommand", mock.Anything, cluster, *selection.tasks[2].TaskArn, mock.Anything, withExitCode("hostname"), true).
+		Return(nil, errors.New("execute command failed"))
+
+	var output bytes.Buffer
+	err := runExecAll(context.Background(), &output, true, mockClient, selection, "hostname", 2)
+
+	var exitErr *ExitError
+	require.ErrorAs(t, err, &exitErr)
+	assert.Equal(t, "command failed on 2 of 3 tasks", exitErr.Error())
+	assert.Equal(
+		t,
+		"\n"+
+			"TASK  CONTAINER  EXIT CODE  ERROR\n"+
+			"aaa   app        0          -\n"+
+			"bbb   app        3          -\n"+
+			"ccc   app        -          execute command failed\n",
+		output.String(),
+	)
+	mockClient.AssertExpectations(t)
+}
+
+func TestLinePrefixer(t *testing.T) {
+	var printed []string
+	prefixer := &linePrefixer{
+		mu:     &sync.Mutex{},
+		prefix: "aaa",
+		printer: func(format string, a ...any) {
+			printed = append(printed, a[1].(string))
+		},
+	}
+
+	_, _ = prefixer.Write([]byte("first\r\nsec"))
+	_, _ = prefixer.Write([]byte("ond\nthird"))
+	prefixer.Flush()
+
+	assert.Equal(t, []string{"first", "second", "third"}, printed)
+	assert.Equal(t, []string{"first", "second", "third"}, prefixer.lines)
+}
+
+func TestExecAllSelector_NonInteractive(t *testing.T) {
+	clusterArn := "arn:aws:ecs:us-east-1:123456789012:cluster/cluster"
+	serviceArn := "arn:aws:ecs:us-east-1:123456789012:service/cluster/api"
+	taskArns := []string{
+		"arn:aws:ecs:us-east-1:123456789012:task/cluster/aaa",
+		"arn:aws:ecs:us-east-1:123456789012:task/cluster/bbb",
+	}
+	containers := []types.Container{{Name: aws.String("app")}}
+
+	mockClient := new(MockClient)
+	mockClient.On("ListClusters", mock.Anything).Return([]string{clusterArn}, nil)
+	mockClient.On("DescribeClusters", mock.Anything, []string{clusterArn}).
+		Return([]types.Cluster{{ClusterArn: aws.String(clusterArn)}}, nil)
+	mockClient.On("ListServices", mock.Anything, clusterArn).Return([]string{serviceArn}, nil)
+	mockClient.On("DescribeServices", mock.Anything, clusterArn, []string{serviceArn}).
+		Return([]types.Service{{ClusterArn: aws.String(clusterArn), ServiceArn: aws.String(serviceArn)}}, nil)
+	mockClient.On("ListTasks", mock.Anything, clusterArn, serviceArn, types.DesiredStatusRunning).
+		Return(taskArns, nil)
+	mockClient.On("DescribeTasks", mock.Anything, clusterArn, taskArns).Return([]types.Task{
+		{TaskArn: aws.String(taskArns[0]), Containers: containers},
+		{TaskArn: aws.String(taskArns[1]), Containers: containers},
+	}, nil)
+
+	nonInteractive = true
+	t.Cleanup(func() { nonInteractive = false })
+
+	selection, err := execAllSelector(
+		context.Background(),
+		selector.NewSelectors(mockClient, huh.Theme{}, true, io.Discard),
+	)
+
+	require.NoError(t, err)
+	require.Len(t, selection.tasks, 2)
+	assert.Equal(t, "app", selection.container)
+}
@@ -0,0 +1,349 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/aws/aws-sdk-go-v2/service/ecs/types"
+	"github.com/fatih/color"
+	"github.com/sestrella/iecs/client"
+	"github.com/spf13/cobra"
+)
+
+// fargateExecPlatformVersion is the first Linux Fargate platform version
+// supporting ECS Exec.
+const fargateExecPlatformVersion = "1.4.0"
+
+const sessionManagerPluginInstallURL = "https://docs.aws.amazon.com/systems-manager/latest/userguide/session-manager-working-with-install-plugin.html"
+
+// execPermissionsRemediation lists what the task role needs for the agent to
+// open sessions.
+const execPermissionsRemediation = "Allow the ssmmessages:CreateControlChannel, ssmmessages:CreateDataChannel, " +
+	"ssmmessages:OpenControlChannel and ssmmessages:OpenDataChannel actions in the task role, " +
+	"and make sure the task can reach the ssmmessages endpoint"
+
+var (
+	checkPassed = color.New(color.FgGreen)
+	checkFailed = color.New(color.FgRed)
+)
+
+var execDoctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Check that a container is ready to run remote commands",
+	Long: `Runs the preflight checks of ECS Exec against the selected container:
+execute command enabled on the service and the task, the status of the exec
+agent, the task role, the platform version, the exec logging configuration of
+the cluster and the local session-manager-plugin. Each failed check comes with
+a remediation, and iecs exits with a non-zero code when any check fails.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		awsClient, err := newClient(context.TODO())
+		if err != nil {
+			return err
+		}
+
+		selection, err := execSelector(context.TODO(), newSelectors(awsClient))
+		if err != nil {
+			return err
+		}
+
+		return runExecDoctor(
+			context.TODO(),
+			os.Stdout,
+			awsClient,
+			*selection,
+			os.Getenv(client.SessionClientEnvVar) == "plugin",
+			sessionManagerPluginVersion,
+		)
+	},
+}
+
+// DoctorCheck is the outcome of a preflight check of ECS Exec.
+type DoctorCheck struct {
+	Name        string `json:"name" yaml:"name"`
+	Passed      bool   `json:"passed" yaml:"passed"`
+	Detail      string `json:"detail" yaml:"detail"`
+	Remediation string `json:"remediation,omitempty" yaml:"remediation,omitempty"`
+}
+
+// runExecDoctor runs the checks, failed checks are returned as an ExitError.
+func runExecDoctor(
+	ctx context.Context,
+	w io.Writer,
+	client client.Client,
+	selection ExecSelection,
+	usePlugin bool,
+	pluginVersion func() (string, error),
+) error {
+	taskDefinition, err := client.DescribeTaskDefinition(ctx, *selection.task.TaskDefinitionArn)
+	if err != nil {
+		return err
+	}
+
+	checks := doctorChecks(selection, taskDefinition, usePlugin, pluginVersion)
+
+	if structuredOutput() {
+		if err := printOutput(checks); err != nil {
+			return err
+		}
+	} else {
+		printDoctorChecks(w, checks)
+	}
+
+	failures := 0
+	for _, check := range checks {
+		if !check.Passed {
+			failures++
+		}
+	}
+	if failures > 0 {
+		return &ExitError{
+			Code:    1,
+			Message: fmt.Sprintf("%d of %d checks failed", failures, len(checks)),
+		}
+	}
+	return nil
+}
+
+func doctorChecks(
+	selection ExecSelection,
+	taskDefinition *types.TaskDefinition,
+	usePlugin bool,
+	pluginVersion func() (string, error),
+) []DoctorCheck {
+	return []DoctorCheck{
+		serviceExecCheck(selection.cluster, selection.service),
+		taskExecCheck(selection.task),
+		execAgentCheck(selection.container),
+		taskRoleCheck(selection.task, taskDefinition),
+		platformVersionCheck(selection.task),
+		execLoggingCheck(selection.cluster),
+		sessionManagerPluginCheck(selection.cluster, usePlugin, pluginVersion),
+	}
+}
+
+func serviceExecCheck(cluster *types.Cluster, service *types.Service) DoctorCheck {
+	check := DoctorCheck{Name: "Service execute command"}
+	if service.EnableExecuteCommand {
+		check.Passed = true
+		check.Detail = "enabled"
+		return check
+	}
+	check.Detail = "disabled"
+	check.Remediation = fmt.Sprintf(
+		"Run iecs exec enable --cluster %s --service %s to enable it and replace the tasks",
+		stringValue(cluster.ClusterName),
+		stringValue(service.ServiceName),
+	)
+	return check
+}
+
+func taskExecCheck(task *types.Task) DoctorCheck {
+	check := DoctorCheck{Name: "Task execute command"}
+	if task.EnableExecuteCommand {
+		check.Passed = true
+		check.Detail = "enabled"
+		return check
+	}
+	check.Detail = "disabled"
+	check.Remediation = "Tasks started before execute command was enabled keep it disabled, " +
+		"run iecs exec enable to replace them"
+	return check
+}
+
+func execAgentCheck(container *types.Container) DoctorCheck {
+	check := DoctorCheck{Name: "Exec agent"}
+	for _, agent := range container.ManagedAgents {
+		if agent.Name != types.ManagedAgentNameExecuteCommandAgent {
+			continue
+		}
+
+		status := stringValue(agent.LastStatus)
+		check.Detail = strings.ToLower(status)
+		if reason := stringValue(agent.Reason); reason != "" {
+			check.Detail += ": " + reason
+		}
+		if status == "RUNNING" {
+			check.Passed = true
+			return check
+		}
+		check.Remediation = execPermissionsRemediation
+		return check
+	}
+
+	check.Detail = fmt.Sprintf("not found in container %s", stringValue(container.Name))
+	check.Remediation = "The agent only runs in tasks started with execute command enabled, " +
+		"run iecs exec enable to replace them"
+	return check
+}
+
+func taskRoleCheck(task *types.Task, taskDefinition *types.TaskDefinition) DoctorCheck {
+	check := DoctorCheck{Name: "Task role"}
+	role := stringValue(taskDefinition.TaskRoleArn)
+	if task.Overrides != nil && stringValue(task.Overrides.TaskRoleArn) != "" {
+		role = *task.Overrides.TaskRoleArn
+	}
+	if role != "" {
+		check.Passed = true
+		check.Detail = role
+		return check
+	}
+	check.Detail = fmt.Sprintf("not set in %s", shortTaskDefinition(taskDefinition.TaskDefinitionArn))
+	check.Remediation = "Set a task role in the task definition. " + execPermissionsRemediation
+	return check
+}
+
+func platformVersionCheck(task *types.Task) DoctorCheck {
+	check := DoctorCheck{Name: "Platform version"}
+	if task.LaunchType != types.LaunchTypeFargate && stringValue(task.PlatformVersion) == "" {
+		check.Passed = true
+		check.Detail = "not running on Fargate"
+		return check
+	}
+
+	version := stringValue(task.PlatformVersion)
+	check.Detail = valueOrDash(version)
+	// Windows tasks support ECS Exec on every platform version
+	if strings.HasPrefix(stringValue(task.PlatformFamily), "Windows") ||
+		versionAtLeast(version, fargateExecPlatformVersion) {
+		check.Passed = true
+		return check
+	}
+	check.Remediation = fmt.Sprintf(
+		"Update the service to platform version %s or later (or LATEST)",
+		fargateExecPlatformVersion,
+	)
+	return check
+}
+
+func execLoggingCheck(cluster *types.Cluster) DoctorCheck {
+	check := DoctorCheck{Name: "Exec logging", Passed: true}
+
+	var configuration *types.ExecuteCommandConfiguration
+	if cluster.Configuration != nil {
+		configuration = cluster.Configuration.ExecuteCommandConfiguration
+	}
+	if configuration == nil || configuration.Logging == types.ExecuteCommandLoggingDefault {
+		check.Detail = "default, sessions are logged by the awslogs driver of the container"
+		return check
+	}
+	if configuration.Logging == types.ExecuteCommandLoggingNone {
+		check.Detail = "disabled"
+		return check
+	}
+
+	var destinations []string
+	if logConfiguration := configuration.LogConfiguration; logConfiguration != nil {
+		if logConfiguration.CloudWatchLogGroupName != nil {
+			destinations = append(destinations, "log group "+*logConfiguration.CloudWatchLogGroupName)
+		}
+		if logConfiguration.S3BucketName != nil {
+			destinations = append(destinations, "bucket "+*logConfiguration.S3BucketName)
+		}
+	}
+	if len(destinations) > 0 {
+		check.Detail = "sessions are logged to " + strings.Join(destinations, " and ")
+		return check
+	}
+
+	check.Passed = false
+	check.Detail = "overridden without a destination"
+	check.Remediation = "Set a CloudWatch log group or an S3 bucket in the execute command configuration " +
+		"of the cluster, or set its logging to DEFAULT or NONE"
+	return check
+}
+
+// sessionManagerPluginCheck checks the plugin when sessions need it, either
+// because IECS_SESSION_CLIENT=plugin asks for it or because they are
+// encrypted with KMS, which the built-in client does not support.
+func sessionManagerPluginCheck(
+	cluster *types.Cluster,
+	usePlugin bool,
+	pluginVersion func() (string, error),
+) DoctorCheck {
+	check := DoctorCheck{Name: "Session manager plugin"}
+	kmsKeyId := client.SessionKmsKeyId(cluster)
+
+	version, err := pluginVersion()
+	switch {
+	case err != nil && (usePlugin || kmsKeyId != ""):
+		check.Detail = fmt.Sprintf("not installed: %v", err)
+		check.Remediation = "Install session-manager-plugin, see " + sessionManagerPluginInstallURL
+	case err != nil:
+		check.Passed = true
+		check.Detail = "not installed, sessions use the built-in client"
+	case kmsKeyId != "" && !usePlugin:
+		check.Passed = true
+		check.Detail = fmt.Sprintf("version %s, used for the sessions encrypted with KMS key %s", version, kmsKeyId)
+	default:
+		check.Passed = true
+		check.Detail = "version " + version
+	}
+	return check
+}
+
+// sessionManagerPluginVersion returns the version of the installed plugin.
+func sessionManagerPluginVersion() (string, error) {
+	output, err := exec.Command("session-manager-plugin", "--version").Output()
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// versionAtLeast compares dotted numeric versions.
+func versionAtLeast(version string, minimum string) bool {
+	parts := strings.Split(version, ".")
+	for i, minimumPart := range strings.Split(minimum, ".") {
+		want, _ := strconv.Atoi(minimumPart)
+		if i >= len(parts) {
+			return want == 0
+		}
+		got, err := strconv.Atoi(parts[i])
+		if err != nil {
+			return false
+		}
+		if got != want {
+			return got > want
+		}
+	}
+	return true
+}
+
+func printDoctorChecks(w io.Writer, checks []DoctorCheck) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "STATUS\tCHECK\tDETAIL")
+	for _, check := range checks {
+		status := checkPassed.Sprint("PASS")
+		if !check.Passed {
+			status = checkFailed.Sprint("FAIL")
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", status, check.Name, check.Detail)
+	}
+	tw.Flush()
+
+	var remediations []DoctorCheck
+	for _, check := range checks {
+		if !check.Passed && check.Remediation != "" {
+			remediations = append(remediations, check)
+		}
+	}
+	if len(remediations) == 0 {
+		return
+	}
+
+	fmt.Fprintf(w, "\n%s\n", titleStyle.Render("Remediation:"))
+	for _, check := range remediations {
+		fmt.Fprintf(w, "  %s: %s\n", check.Name, check.Remediation)
+	}
+}
+
+func init() {
+	execCmd.AddCommand(execDoctorCmd)
+}
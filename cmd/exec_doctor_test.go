@@ -0,0 +1,264 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ecs/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func doctorSelection() ExecSelection {
+	return ExecSelection{
+		cluster: &types.Cluster{ClusterArn: aws.String("cluster"), ClusterName: aws.String("production")},
+		service: &types.Service{ServiceName: aws.String("api"), EnableExecuteCommand: true},
+		task: &types.Task{
+			TaskArn:              aws.String("task"),
+			TaskDefinitionArn:    aws.String("arn:aws:ecs:us-east-1:123456789012:task-definition/api:3"),
+			EnableExecuteCommand: true,
+			LaunchType:           types.LaunchTypeFargate,
+			PlatformVersion:      aws.String("1.4.0"),
+		},
+		container: &types.Container{
+			Name: aws.String("app"),
+			ManagedAgents: []types.ManagedAgent{{
+				Name:       types.ManagedAgentNameExecuteCommandAgent,
+				LastStatus: aws.String("RUNNING"),
+			}},
+		},
+	}
+}
+
+func pluginInstalled() (string, error) {
+	return "1.2.553.0", nil
+}
+
+func pluginMissing() (string, error) {
+	return "", errors.New(`exec: "session-manager-plugin": executable file not found in $PATH`)
+}
+
+func TestRunExecDoctor(t *testing.T) {
+	selection := doctorSelection()
+	mockClient := new(MockClient)
+	mockClient.On("DescribeTaskDefinition", mock.Anything, *selection.task.TaskDefinitionArn).
+		Return(&types.TaskDefinition{
+			TaskDefinitionArn: selection.task.TaskDefinitionArn,
+			TaskRoleArn:       aws.String("arn:aws:iam::123456789012:role/api"),
+		}, nil)
+
+	var output bytes.Buffer
+	err := runExecDoctor(context.Background(), &output, mockClient, selection, false, pluginInstalled)
+
+	require.NoError(t, err)
+	assert.Equal(
+		t,
+		"STATUS  CHECK                    DETAIL\n"+
+			"PASS    Service execute command  enabled\n"+
+			"PASS    Task execute command     enabled\n"+
+			"PASS    Exec agent               running\n"+
+			"PASS    Task role                arn:aws:iam::123456789012:role/api\n"+
+			"PASS    Platform version         1.4.0\n"+
+			"PASS    Exec logging             default, sessions are logged by the awslogs driver of the container\n"+
+			"PASS    Session manager plugin   version 1.2.553.0\n",
+		output.String(),
+	)
+	mockClient.AssertExpectations(t)
+}
+
+func TestRunExecDoctor_Failures(t *testing.T) {
+	selection := doctorSelection()
+	selection.service.EnableExecuteCommand = false
+	selection.task.EnableExecuteCommand = false
+	selection.task.PlatformVersion = aws.String("1.3.0")
+	selection.container.ManagedAgents = nil
+	selection.cluster.Configuration = &types.ClusterConfiguration{
+		ExecuteCommandConfiguration: &types.ExecuteCommandConfiguration{
+			Logging: types.ExecuteCommandLoggingOverride,
+		},
+	}
+
+	mockClient := new(MockClient)
+	mockClient.On("DescribeTaskDefinition", mock.Anything, *selection.task.TaskDefinitionArn).
+		Return(&types.TaskDefinition{TaskDefinitionArn: selection.task.TaskDefinitionArn}, nil)
+
+	var output bytes.Buffer
+	err := runExecDoctor(context.Background(), &output, mockClient, selection, true, pluginMissing)
+
+	var exitErr *ExitError
+	require.ErrorAs(t, err, &exitErr)
+	assert.Equal(t, "7 of 7 checks failed", exitErr.Message)
+	assert.Contains(t, output.String(), "FAIL    Exec agent               not found in container app\n")
+	assert.Contains(
+		t,
+		output.String(),
+		"\nRemediation:\n"+
+			"  Service execute command: Run iecs exec enable --cluster production --service api "+
+			"to enable it and replace the tasks\n",
+	)
+	assert.Contains(t, output.String(), "  Platform version: Update the service to platform version 1.4.0 or later (or LATEST)\n")
+	mockClient.AssertExpectations(t)
+}
+
+func TestExecAgentCheck(t *testing.T) {
+	container := &types.Container{
+		Name: aws.String("app"),
+		ManagedAgents: []types.ManagedAgent{{
+			Name:       types.ManagedAgentNameExecuteCommandAgent,
+			LastStatus: aws.String("STOPPED"),
+			Reason:     aws.String("TargetNotConnected"),
+		}},
+	}
+
+	check := execAgentCheck(container)
+
+	assert.False(t, check.Passed)
+	assert.Equal(t, "stopped: TargetNotConnected", check.Detail)
+	assert.Equal(t, execPermissionsRemediation, check.Remediation)
+}
+
+func TestPlatformVersionCheck(t *testing.T) {
+	tests := []struct {
+		name   string
+		task   types.Task
+		passed bool
+		detail string
+	}{
+		{
+			name:   "ec2",
+			task:   types.Task{LaunchType: types.LaunchTypeEc2},
+			passed: true,
+			detail: "not running on Fargate",
+		},
+		{
+			name:   "fargate",
+			task:   types.Task{LaunchType: types.LaunchTypeFargate, PlatformVersion: aws.String("1.10.0")},
+			passed: true,
+			detail: "1.10.0",
+		},
+		{
+			name:   "fargate outdated",
+			task:   types.Task{LaunchType: types.LaunchTypeFargate, PlatformVersion: aws.String("1.3.0")},
+			detail: "1.3.0",
+		},
+		{
+			name: "fargate windows",
+			task: types.Task{
+				LaunchType:      types.LaunchTypeFargate,
+				PlatformVersion: aws.String("1.0.0"),
+				PlatformFamily:  aws.String("Windows Server 2019 Core"),
+			},
+			passed: true,
+			detail: "1.0.0",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			check := platformVersionCheck(&test.task)
+
+			assert.Equal(t, test.passed, check.Passed)
+			assert.Equal(t, test.detail, check.Detail)
+		})
+	}
+}
+
+func TestExecLoggingCheck(t *testing.T) {
+	cluster := &types.Cluster{Configuration: &types.ClusterConfiguration{
+		ExecuteCommandConfiguration: &types.ExecuteCommandConfiguration{
+			Logging: types.ExecuteCommandLoggingOverride,
+			LogConfiguration: &types.ExecuteCommandLogConfiguration{
+				CloudWatchLogGroupName: aws.String("/ecs/exec"),
+				S3BucketName:           aws.String("exec-logs"),
+			},
+		},
+	}}
+
+	check := execLoggingCheck(cluster)
+
+	assert.True(t, check.Passed)
+	assert.Equal(t, "sessions are logged to log group /ecs/exec and bucket exec-logs", check.Detail)
+}
+
+func TestSessionManagerPluginCheck(t *testing.T) {
+	cluster := &types.Cluster{}
+	encrypted := &types.Cluster{Configuration: &types.ClusterConfiguration{
+		ExecuteCommandConfiguration: &types.ExecuteCommandConfiguration{KmsKeyId: aws.String("key")},
+	}}
+
+	tests := []struct {
+		name          string
+		cluster       *types.Cluster
+		usePlugin     bool
+		pluginVersion func() (string, error)
+		passed        bool
+		detail        string
+	}{
+		{
+			name:          "built-in client",
+			cluster:       cluster,
+			pluginVersion: pluginMissing,
+			passed:        true,
+			detail:        "not installed, sessions use the built-in client",
+		},
+		{
+			name:          "plugin",
+			cluster:       cluster,
+			usePlugin:     true,
+			pluginVersion: pluginInstalled,
+			passed:        true,
+			detail:        "version 1.2.553.0",
+		},
+		{
+			name:          "plugin missing",
+			cluster:       cluster,
+			usePlugin:     true,
+			pluginVersion: pluginMissing,
+			detail:        `not installed: exec: "session-manager-plugin": executable file not found in $PATH`,
+		},
+		{
+			name:          "encrypted",
+			cluster:       encrypted,
+			pluginVersion: pluginInstalled,
+			passed:        true,
+			detail:        "version 1.2.553.0, used for the sessions encrypted with KMS key key",
+		},
+		{
+			name:          "encrypted without the plugin",
+			cluster:       encrypted,
+			pluginVersion: pluginMissing,
+			detail:        `not installed: exec: "session-manager-plugin": executable file not found in $PATH`,
+		},
+		{
+			name:          "encrypted with the plugin",
+			cluster:       encrypted,
+			usePlugin:     true,
+			pluginVersion: pluginInstalled,
+			passed:        true,
+			detail:        "version 1.2.553.0",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			check := sessionManagerPluginCheck(test.cluster, test.usePlugin, test.pluginVersion)
+
+			assert.Equal(t, test.passed, check.Passed)
+			assert.Equal(t, test.detail, check.Detail)
+			assert.Equal(t, test.passed, check.Remediation == "")
+		})
+	}
+}
+
+func TestVersionAtLeast(t *testing.T) {
+	assert.True(t, versionAtLeast("1.4.0", "1.4.0"))
+	assert.True(t, versionAtLeast("1.10.0", "1.4.0"))
+	assert.True(t, versionAtLeast("2.0", "1.4.0"))
+	assert.False(t, versionAtLeast("1.3.0", "1.4.0"))
+	assert.False(t, versionAtLeast("LATEST", "1.4.0"))
+	assert.False(t, versionAtLeast("", "1.4.0"))
+}
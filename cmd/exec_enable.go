@@ -0,0 +1,264 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ecs/types"
+	"github.com/sestrella/iecs/client"
+	"github.com/spf13/cobra"
+)
+
+// execAgentPollInterval is how often a task is polled while waiting for its
+// exec agent to start.
+const execAgentPollInterval = 2 * time.Second
+
+var execEnableCmd = &cobra.Command{
+	Use:   "enable",
+	Short: "Enable execute command on a service and run a command on a fresh task",
+	Long: `Turns execute command on for the selected service and forces a new
+deployment, so its tasks are replaced by ones that accept remote commands. Once
+the deployment completes, the command runs on one of the new tasks.
+
+With --force-new-deployment=false, the running tasks are kept and only the
+tasks started afterwards accept remote commands. When the service and its
+running tasks already accept remote commands, the command runs right away.`,
+	Example: `
+  iecs exec enable
+  iecs exec enable --yes --command /bin/sh
+  `,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		command, err := cmd.Flags().GetString(execCommandFlag)
+		if err != nil {
+			return err
+		}
+
+		forceNewDeployment, err := cmd.Flags().GetBool("force-new-deployment")
+		if err != nil {
+			return err
+		}
+
+		awsClient, err := newClient(context.Background())
+		if err != nil {
+			return err
+		}
+
+		selectors := newSelectors(awsClient)
+
+		cluster, err := selectors.Cluster(context.Background(), clusterFilter)
+		if err != nil {
+			return err
+		}
+
+		service, err := selectors.Service(context.Background(), cluster, serviceFilter)
+		if err != nil {
+			return err
+		}
+
+		enabled, err := execEnabled(context.Background(), awsClient, service, forceNewDeployment)
+		if err != nil {
+			return err
+		}
+
+		w := decorationOutput()
+		if enabled {
+			fmt.Fprintf(w, "Execute command is already enabled on %s\n", *service.ServiceName)
+		} else {
+			printEnablePlan(w, *service, forceNewDeployment)
+
+			confirmed, err := confirm(selectors, "Enable execute command?", "enable execute command")
+			if err != nil || !confirmed {
+				return err
+			}
+
+			selection := enableExecSelection(*cluster, *service, forceNewDeployment)
+			if err = runUpdate(context.Background(), selection, awsClient, waitTimeoutFlag); err != nil {
+				return err
+			}
+
+			if !forceNewDeployment {
+				fmt.Fprintln(w, "Execute command applies to the tasks started from now on")
+				return nil
+			}
+		}
+
+		task, err := selectors.Task(context.Background(), service, taskFilter)
+		if err != nil {
+			return err
+		}
+
+		container, err := selectors.Container(context.Background(), task.Containers, containerFilter)
+		if err != nil {
+			return err
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), waitTimeoutFlag)
+		defer cancel()
+		task, container, err = waitForExecAgent(ctx, w, awsClient, task, *container.Name, execAgentPollInterval)
+		if err != nil {
+			return err
+		}
+
+		return runExec(
+			context.Background(),
+			awsClient,
+			ExecSelection{cluster: cluster, service: service, task: task, container: container},
+			command,
+			true,
+		)
+	},
+}
+
+// execEnabled reports whether execute command is already enabled on the
+// service and, when the tasks would be replaced, on every running task too.
+func execEnabled(
+	ctx context.Context,
+	client client.Client,
+	service *types.Service,
+	forceNewDeployment bool,
+) (bool, error) {
+	if !service.EnableExecuteCommand || !forceNewDeployment {
+		return service.EnableExecuteCommand, nil
+	}
+
+	taskArns, err := client.ListTasks(
+		ctx,
+		*service.ClusterArn,
+		*service.ServiceArn,
+		types.DesiredStatusRunning,
+	)
+	if err != nil {
+		return false, err
+	}
+	// Without running tasks, there is nothing to replace
+	if len(taskArns) == 0 {
+		return true, nil
+	}
+
+	tasks, err := client.DescribeTasks(ctx, *service.ClusterArn, taskArns)
+	if err != nil {
+		return false, err
+	}
+	for _, task := range tasks {
+		if !task.EnableExecuteCommand {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// enableExecSelection keeps the configuration of the service, turning
+// execute command on. The desired count is left as is, it may have changed
+// since the service was described.
+func enableExecSelection(
+	cluster types.Cluster,
+	service types.Service,
+	forceNewDeployment bool,
+) UpdateSelection {
+	return UpdateSelection{
+		cluster: cluster,
+		service: service,
+		serviceConfig: client.ServiceConfig{
+			TaskDefinitionArn:    *service.TaskDefinition,
+			ForceNewDeployment:   forceNewDeployment,
+			EnableExecuteCommand: aws.Bool(true),
+		},
+	}
+}
+
+func printEnablePlan(w io.Writer, service types.Service, forceNewDeployment bool) {
+	fmt.Fprintf(w, "%s %s\n", titleStyle.Render("Service:"), *service.ServiceName)
+	if service.EnableExecuteCommand {
+		fmt.Fprintf(w, "%s enabled\n", titleStyle.Render("Execute command:"))
+	} else {
+		fmt.Fprintf(w, "%s disabled -> enabled\n", titleStyle.Render("Execute command:"))
+	}
+	if forceNewDeployment {
+		fmt.Fprintf(w, "%s %d tasks replaced\n", titleStyle.Render("Deployment:"), service.DesiredCount)
+	} else {
+		fmt.Fprintf(w, "%s none, running tasks are kept\n", titleStyle.Render("Deployment:"))
+	}
+}
+
+// waitForExecAgent polls the task until the exec agent of the container is
+// running, which happens shortly after the task starts, printing the status
+// of the agent whenever it changes.
+func waitForExecAgent(
+	ctx context.Context,
+	w io.Writer,
+	client client.Client,
+	task *types.Task,
+	containerName string,
+	interval time.Duration,
+) (*types.Task, *types.Container, error) {
+	var lastStatus string
+	for {
+		index := slices.IndexFunc(task.Containers, func(container types.Container) bool {
+			return stringValue(container.Name) == containerName
+		})
+		if index < 0 {
+			return nil, nil, fmt.Errorf("container %s not found in task %s", containerName, *task.TaskArn)
+		}
+		container := &task.Containers[index]
+
+		status := "PENDING"
+		for _, agent := range container.ManagedAgents {
+			if agent.Name == types.ManagedAgentNameExecuteCommandAgent {
+				status = stringValue(agent.LastStatus)
+			}
+		}
+		if status != lastStatus {
+			fmt.Fprintf(w, "%s %s\n", titleStyle.Render("Exec agent:"), status)
+			lastStatus = status
+		}
+		if status == "RUNNING" {
+			return task, container, nil
+		}
+		if !task.EnableExecuteCommand {
+			return nil, nil, fmt.Errorf(
+				"execute command is not enabled on task %s",
+				shortTaskId(*task.TaskArn),
+			)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, nil, fmt.Errorf(
+				"stopped waiting for the exec agent of task %s: %w",
+				shortTaskId(*task.TaskArn),
+				ctx.Err(),
+			)
+		case <-time.After(interval):
+		}
+
+		tasks, err := client.DescribeTasks(ctx, stringValue(task.ClusterArn), []string{*task.TaskArn})
+		if err != nil {
+			return nil, nil, err
+		}
+		if len(tasks) == 0 {
+			return nil, nil, fmt.Errorf("task %s not found", *task.TaskArn)
+		}
+		task = &tasks[0]
+		if status := stringValue(task.LastStatus); status == "STOPPED" || status == "DEPROVISIONING" {
+			return nil, nil, fmt.Errorf("task %s is %s", shortTaskId(*task.TaskArn), strings.ToLower(status))
+		}
+	}
+}
+
+func init() {
+	execCmd.AddCommand(execEnableCmd)
+
+	execEnableCmd.Flags().StringP(execCommandFlag, "c", execDefaultCommand, "command to run")
+	execEnableCmd.Flags().
+		Bool("force-new-deployment", true, "Replace the running tasks so they accept remote commands")
+	execEnableCmd.Flags().
+		DurationVarP(&waitTimeoutFlag, "wait-timeout", "w", 5*time.Minute, "The wait time for the service to become available")
+	execEnableCmd.Flags().
+		BoolVarP(&yesFlag, "yes", "y", false, "Enable execute command without asking for confirmation")
+}
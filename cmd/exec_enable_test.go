@@ -0,0 +1,173 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ecs/types"
+	"github.com/sestrella/iecs/client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEnableExecSelection(t *testing.T) {
+	service := types.Service{
+		TaskDefinition: aws.String(testTaskDefinitionArn + "api:5"),
+		DesiredCount:   3,
+	}
+
+	selection := enableExecSelection(types.Cluster{}, service, true)
+
+	assert.Equal(t, client.ServiceConfig{
+		TaskDefinitionArn:    testTaskDefinitionArn + "api:5",
+		ForceNewDeployment:   true,
+		EnableExecuteCommand: aws.Bool(true),
+	}, selection.serviceConfig)
+}
+
+func TestExecEnabled(t *testing.T) {
+	service := &types.Service{
+		ClusterArn:           aws.String(testClusterArn),
+		ServiceArn:           aws.String(testServiceArn),
+		EnableExecuteCommand: true,
+	}
+	taskArns := []string{"task-1", "task-2"}
+
+	tests := []struct {
+		name               string
+		service            *types.Service
+		forceNewDeployment bool
+		tasks              []types.Task
+		enabled            bool
+	}{
+		{
+			name:               "disabled",
+			service:            &types.Service{},
+			forceNewDeployment: true,
+		},
+		{
+			name:    "kept tasks",
+			service: service,
+			enabled: true,
+		},
+		{
+			name:               "enabled on every task",
+			service:            service,
+			forceNewDeployment: true,
+			tasks:              []types.Task{{EnableExecuteCommand: true}, {EnableExecuteCommand: true}},
+			enabled:            true,
+		},
+		{
+			name:               "disabled on a task",
+			service:            service,
+			forceNewDeployment: true,
+			tasks:              []types.Task{{EnableExecuteCommand: true}, {}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			mockClient := new(MockClient)
+			if test.tasks != nil {
+				mockClient.On("ListTasks", mock.Anything, testClusterArn, testServiceArn, types.DesiredStatusRunning).
+					Return(taskArns, nil)
+				mockClient.On("DescribeTasks", mock.Anything, testClusterArn, taskArns).Return(test.tasks, nil)
+			}
+
+			enabled, err := execEnabled(context.Background(), mockClient, test.service, test.forceNewDeployment)
+
+			require.NoError(t, err)
+			assert.Equal(t, test.enabled, enabled)
+			mockClient.AssertExpectations(t)
+		})
+	}
+}
+
+func TestPrintEnablePlan(t *testing.T) {
+	service := types.Service{ServiceName: aws.String("api"), DesiredCount: 3}
+
+	var output bytes.Buffer
+	printEnablePlan(&output, service, true)
+
+	assert.Equal(
+		t,
+		"Service: api\nExecute command: disabled -> enabled\nDeployment: 3 tasks replaced\n",
+		output.String(),
+	)
+}
+
+func execAgentTask(status string) types.Task {
+	return types.Task{
+		TaskArn:              aws.String("arn:aws:ecs:us-east-1:123456789012:task/cluster/abc"),
+		ClusterArn:           aws.String("cluster"),
+		LastStatus:           aws.String("RUNNING"),
+		EnableExecuteCommand: true,
+		Containers: []types.Container{{
+			Name: aws.String("app"),
+			ManagedAgents: []types.ManagedAgent{{
+				Name:       types.ManagedAgentNameExecuteCommandAgent,
+				LastStatus: aws.String(status),
+			}},
+		}},
+	}
+}
+
+func TestWaitForExecAgent(t *testing.T) {
+	pending := execAgentTask("PENDING")
+	running := execAgentTask("RUNNING")
+
+	mockClient := new(MockClient)
+	mockClient.On("DescribeTasks", mock.Anything, "cluster", []string{*pending.TaskArn}).
+		Return([]types.Task{pending}, nil).Once()
+	mockClient.On("DescribeTasks", mock.Anything, "cluster", []string{*pending.TaskArn}).
+		Return([]types.Task{running}, nil).Once()
+
+	var output bytes.Buffer
+	task, container, err := waitForExecAgent(
+		context.Background(),
+		&output,
+		mockClient,
+		&pending,
+		"app",
+		time.Millisecond,
+	)
+
+	require.NoError(t, err)
+	assert.Equal(t, &running, task)
+	assert.Equal(t, "app", *container.Name)
+	assert.Equal(t, "Exec agent: PENDING\nExec agent: RUNNING\n", output.String())
+	mockClient.AssertExpectations(t)
+}
+
+func TestWaitForExecAgent_Disabled(t *testing.T) {
+	task := execAgentTask("PENDING")
+	task.EnableExecuteCommand = false
+	task.Containers[0].ManagedAgents = nil
+
+	var output bytes.Buffer
+	_, _, err := waitForExecAgent(
+		context.Background(),
+		&output,
+		new(MockClient),
+		&task,
+		"app",
+		time.Millisecond,
+	)
+
+	assert.EqualError(t, err, "execute command is not enabled on task abc")
+}
+
+func TestWaitForExecAgent_Timeout(t *testing.T) {
+	task := execAgentTask("PENDING")
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	var output bytes.Buffer
+	_, _, err := waitForExecAgent(ctx, &output, new(MockClient), &task, "app", time.Hour)
+
+	assert.EqualError(t, err, "stopped waiting for the exec agent of task abc: context canceled")
+}
@@ -0,0 +1,124 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// exitCodeMarker prefixes the line carrying the exit code of a remote
+// command, which the session manager plugin does not report.
+const exitCodeMarker = "__IECS_EXIT_CODE__="
+
+// Banners printed by the session manager plugin around the output of a
+// command.
+var sessionBanners = []string{
+	"Starting session with SessionId:",
+	"Exiting session with sessionId:",
+}
+
+// withExitCode wraps the command in a shell that prints its exit code once it
+// finishes. The command runs in a subshell, so the code is printed even when
+// it calls exit or exec.
+func withExitCode(command string) string {
+	script := fmt.Sprintf("(%s\n)\necho \"%s$?\"", command, exitCodeMarker)
+	return fmt.Sprintf("sh -c '%s'", strings.ReplaceAll(script, "'", `'\''`))
+}
+
+// sessionFilter writes the output of a command wrapped by withExitCode
+// without the session manager plugin banners, the blank lines the plugin
+// prints around them and the terminal line endings, and extracts the exit
+// code of the command. The blank lines of the command itself are kept, as
+// they come after the blank line following the starting banner and before
+// the exit code.
+type sessionFilter struct {
+	w         io.Writer
+	buf       []byte
+	blanks    int
+	skipBlank bool
+	exited    bool
+	exitCode  *int
+}
+
+func newSessionFilter(w io.Writer) *sessionFilter {
+	return &sessionFilter{w: w}
+}
+
+func (f *sessionFilter) Write(data []byte) (int, error) {
+	f.buf = append(f.buf, data...)
+	for {
+		index := bytes.IndexByte(f.buf, '\n')
+		if index < 0 {
+			return len(data), nil
+		}
+		line := string(f.buf[:index])
+		f.buf = f.buf[index+1:]
+		if err := f.line(line, true); err != nil {
+			return len(data), err
+		}
+	}
+}
+
+// Close writes the last line when it does not end with a newline.
+func (f *sessionFilter) Close() error {
+	if len(f.buf) == 0 {
+		return nil
+	}
+	line := string(f.buf)
+	f.buf = nil
+	return f.line(line, false)
+}
+
+func (f *sessionFilter) line(line string, newline bool) error {
+	line = strings.TrimSuffix(line, "\r")
+
+	for _, banner := range sessionBanners {
+		if strings.HasPrefix(line, banner) {
+			// The plugin prints blank lines before a banner and one after it
+			f.blanks = 0
+			f.skipBlank = true
+			return nil
+		}
+	}
+
+	if line == "" {
+		// Whatever follows the exit code comes from the plugin
+		if f.skipBlank || f.exited {
+			f.skipBlank = false
+			return nil
+		}
+		f.blanks++
+		return nil
+	}
+	f.skipBlank = false
+
+	// The marker follows the output right away when it does not end with a
+	// newline
+	if output, exitCodeStr, ok := strings.Cut(line, exitCodeMarker); ok {
+		if exitCode, err := strconv.Atoi(strings.TrimSpace(exitCodeStr)); err == nil {
+			f.exitCode = &exitCode
+			f.exited = true
+			if output == "" {
+				return f.flushBlanks()
+			}
+			line = output
+		}
+	}
+
+	if err := f.flushBlanks(); err != nil {
+		return err
+	}
+	if newline {
+		line += "\n"
+	}
+	_, err := io.WriteString(f.w, line)
+	return err
+}
+
+func (f *sessionFilter) flushBlanks() error {
+	_, err := io.WriteString(f.w, strings.Repeat("\n", f.blanks))
+	f.blanks = 0
+	return err
+}
@@ -1,13 +1,16 @@
 package cmd
 
 import (
+	"bytes"
 	"context"
 	"os/exec"
 	"testing"
 
+	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/ecs/types"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 )
 
 func TestRunExec(t *testing.T) {
@@ -75,3 +78,128 @@ func TestRunExec(t *testing.T) {
 	assert.NoError(t, err)
 	mockClient.AssertExpectations(t)
 }
+
+func TestRunExecCommand(t *testing.T) {
+	cluster := &types.Cluster{ClusterArn: aws.String("cluster")}
+	container := &types.Container{Name: aws.String("app")}
+	selection := ExecSelection{
+		cluster:   cluster,
+		task:      &types.Task{TaskArn: aws.String("task")},
+		container: container,
+	}
+
+	tests := []struct {
+		name     string
+		script   string
+		expected string
+		exitCode int
+		err      string
+	}{
+		{
+			name:     "success",
+			script:   `printf '\nStarting session with SessionId: ecs-execute-command-1\r\n\r\nhello\r\n` + exitCodeMarker + `0\r\n\r\n\r\nExiting session with sessionId: ecs-execute-command-1.\r\n\r\n'`,
+			expected: "hello\n",
+		},
+		{
+			name:     "failure",
+			script:   `printf 'Starting session with SessionId: ecs-execute-command-1\nnot found\n` + exitCodeMarker + `127\n'`,
+			expected: "not found\n",
+			exitCode: 127,
+			err:      "command exited with code 127",
+		},
+		{
+			name:     "session failure",
+			script:   `printf 'not found\n` + exitCodeMarker + `2\n'; exit 1`,
+			expected: "not found\n",
+			exitCode: 2,
+			err:      "command exited with code 2",
+		},
+		{
+			name:     "no exit code",
+			script:   `printf 'sh: not found\n'`,
+			expected: "sh: not found\n",
+			err:      "the exit code of the command was not reported, is sh available in container app?",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			mockClient := new(MockClient)
+			mockClient.On("ExecuteCommand", mock.Anything, cluster, "task", container, withExitCode("ls /missing"), true).
+				Return(exec.Command("sh", "-c", test.script), nil)
+
+			var output bytes.Buffer
+			err := runExecCommand(context.Background(), &output, mockClient, selection, "ls /missing")
+
+			assert.Equal(t, test.expected, output.String())
+			if test.err == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, test.err)
+			}
+			var exitErr *ExitError
+			if test.exitCode != 0 {
+				require.ErrorAs(t, err, &exitErr)
+				assert.Equal(t, test.exitCode, exitErr.Code)
+			}
+			mockClient.AssertExpectations(t)
+		})
+	}
+}
+
+func TestWithExitCode(t *testing.T) {
+	wrapped := withExitCode(`echo 'it''s' "$HOME"`)
+
+	assert.Equal(
+		t,
+		`sh -c '(echo '\''it'\'''\''s'\'' "$HOME"`+"\n)\n"+`echo "`+exitCodeMarker+`$?"'`,
+		wrapped,
+	)
+
+	for command, expected := range map[string]string{
+		"exit 4":          exitCodeMarker + "4\n",
+		"exec sh -c true": exitCodeMarker + "0\n",
+		"false # comment": exitCodeMarker + "1\n",
+	} {
+		output, err := exec.Command("sh", "-c", withExitCode(command)).Output()
+		require.NoError(t, err)
+		assert.Equal(t, expected, string(output), command)
+	}
+}
+
+func TestSessionFilter(t *testing.T) {
+	var output bytes.Buffer
+	filter := newSessionFilter(&output)
+
+	_, _ = filter.Write([]byte("\r\nStarting session with SessionId: ecs-execute-command-1\r\n\r\nfirst\r\n\r\nsec"))
+	_, _ = filter.Write([]byte("ond\r\nno newline" + exitCodeMarker + "2\r\n\r\n"))
+	_, _ = filter.Write([]byte("\r\nExiting session with sessionId: ecs-execute-command-1.\r\n\r\n"))
+	require.NoError(t, filter.Close())
+
+	assert.Equal(t, "first\n\nsecond\nno newline\n", output.String())
+	require.NotNil(t, filter.exitCode)
+	assert.Equal(t, 2, *filter.exitCode)
+}
+
+func TestSessionFilter_BlankLines(t *testing.T) {
+	var output bytes.Buffer
+	filter := newSessionFilter(&output)
+
+	_, _ = filter.Write([]byte("\r\nStarting session with SessionId: ecs-execute-command-1\r\n\r\n"))
+	_, _ = filter.Write([]byte("\r\n\r\nfirst\r\n\r\nlast\r\n\r\n" + exitCodeMarker + "0\r\n"))
+	_, _ = filter.Write([]byte("\r\n\r\nExiting session with sessionId: ecs-execute-command-1.\r\n\r\n"))
+	require.NoError(t, filter.Close())
+
+	assert.Equal(t, "\n\nfirst\n\nlast\n\n", output.String())
+	require.NotNil(t, filter.exitCode)
+	assert.Equal(t, 0, *filter.exitCode)
+}
+
+func TestRunSession_ExitCode(t *testing.T) {
+	err := runSession(exec.Command("sh", "-c", "exit 3"))
+
+	var exitErr *ExitError
+	require.ErrorAs(t, err, &exitErr)
+	assert.Equal(t, 3, exitErr.Code)
+	assert.Empty(t, exitErr.Error())
+}
@@ -1,14 +1,15 @@
 package cmd
 
 import (
+	"cmp"
 	"context"
 	"fmt"
 	"log"
+	"slices"
 	"strings"
 	"sync"
 	"time"
 
-	"github.com/aws/aws-sdk-go-v2/config"
 	logsTypes "github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs/types"
 	"github.com/aws/aws-sdk-go-v2/service/ecs/types"
 	"github.com/fatih/color"
@@ -31,6 +32,17 @@ type LogsSelection struct {
 	service    *types.Service
 	tasks      []types.Task
 	containers []types.ContainerDefinition
+	// revisions holds the selected containers of the task definitions run by
+	// the tasks, when they differ from the one of the service
+	revisions map[string][]types.ContainerDefinition
+}
+
+// LogsOptions controls which part of the log history is shown. A zero since
+// disables history, and a zero until means up to now.
+type LogsOptions struct {
+	since  time.Time
+	until  time.Time
+	follow bool
 }
 
 var logsCmd = &cobra.Command{
@@ -39,6 +51,8 @@ var logsCmd = &cobra.Command{
 	Example: `
   aws-vault exec <profile> -- iecs logs [flags] (recommended)
   env AWS_PROFILE=<profile> iecs logs [flags]
+  iecs logs --since 15m
+  iecs logs --since 2024-01-02T15:04:05Z --until 2024-01-02T16:04:05Z
   `,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		noColors, err := cmd.Flags().GetBool("no-colors")
@@ -46,14 +60,26 @@ var logsCmd = &cobra.Command{
 			return err
 		}
 
-		cfg, err := config.LoadDefaultConfig(context.TODO())
+		options, err := logsOptions(cmd, time.Now())
 		if err != nil {
 			return err
 		}
 
-		client := client.NewClient(cfg)
+		awsClient, err := newClient(context.TODO())
+		if err != nil {
+			return err
+		}
+
+		selection, err := logsSelector(
+			context.TODO(),
+			awsClient,
+			newSelectors(awsClient),
+		)
+		if err != nil {
+			return err
+		}
 
-		selection, err := logsSelector(context.TODO(), selector.NewSelectors(client, *theme))
+		clusterClient, err := client.ForCluster(awsClient, *selection.cluster.ClusterArn)
 		if err != nil {
 			return err
 		}
@@ -61,8 +87,9 @@ var logsCmd = &cobra.Command{
 		err = runLogs(
 			context.TODO(),
 			noColors,
-			client,
+			clusterClient,
 			*selection,
+			*options,
 		)
 		if err != nil {
 			return err
@@ -73,123 +100,447 @@ var logsCmd = &cobra.Command{
 	Aliases: []string{"tail"},
 }
 
+func logsOptions(cmd *cobra.Command, now time.Time) (*LogsOptions, error) {
+	sinceStr, err := cmd.Flags().GetString("since")
+	if err != nil {
+		return nil, err
+	}
+
+	untilStr, err := cmd.Flags().GetString("until")
+	if err != nil {
+		return nil, err
+	}
+
+	noFollow, err := cmd.Flags().GetBool("no-follow")
+	if err != nil {
+		return nil, err
+	}
+
+	since, err := parseLogsTime(sinceStr, now)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --since: %w", err)
+	}
+
+	until, err := parseLogsTime(untilStr, now)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --until: %w", err)
+	}
+
+	if !since.IsZero() && !until.IsZero() && !since.Before(until) {
+		return nil, fmt.Errorf("--since (%s) must be before --until (%s)", sinceStr, untilStr)
+	}
+
+	// Following only makes sense when the history reaches the present
+	follow := !noFollow && until.IsZero()
+
+	return &LogsOptions{since: since, until: until, follow: follow}, nil
+}
+
+// parseLogsTime parses either a duration relative to now (e.g. 15m) or an
+// RFC3339 timestamp.
+func parseLogsTime(value string, now time.Time) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+
+	if duration, err := time.ParseDuration(value); err == nil {
+		return now.Add(-duration), nil
+	}
+
+	return time.Parse(time.RFC3339, value)
+}
+
+// LogEvent is the machine-readable form of a log event.
+type LogEvent struct {
+	Task          string    `json:"task" yaml:"task"`
+	Container     string    `json:"container" yaml:"container"`
+	LogGroup      string    `json:"logGroup" yaml:"logGroup"`
+	LogStream     string    `json:"logStream" yaml:"logStream"`
+	Timestamp     time.Time `json:"timestamp" yaml:"timestamp"`
+	IngestionTime time.Time `json:"ingestionTime" yaml:"ingestionTime"`
+	Message       string    `json:"message" yaml:"message"`
+}
+
+func newLogEvent(
+	stream LogStream,
+	timestamp *int64,
+	ingestionTime *int64,
+	message *string,
+) LogEvent {
+	event := LogEvent{
+		Task:      stream.taskId,
+		Container: stream.containerName,
+		LogGroup:  stream.group,
+		LogStream: stream.name,
+	}
+	if timestamp != nil {
+		event.Timestamp = time.UnixMilli(*timestamp)
+	}
+	if ingestionTime != nil {
+		event.IngestionTime = time.UnixMilli(*ingestionTime)
+	}
+	if message != nil {
+		event.Message = *message
+	}
+	return event
+}
+
+type LogStream struct {
+	taskId        string
+	containerName string
+	group         string
+	name          string
+	printer       Printer
+	stopped       bool
+	createdAt     time.Time
+}
+
 func runLogs(
 	ctx context.Context,
 	noColors bool,
 	clients client.Client,
 	selection LogsSelection,
+	options LogsOptions,
 ) error {
-	type LogOptions struct {
-		containerName string
-		group         string
-		streamPrefix  string
-		printer       Printer
+	streams, err := logStreams(noColors, selection)
+	if err != nil {
+		return err
 	}
 
-	var allLogOptions []LogOptions
-	for index, container := range selection.containers {
-		if container.LogConfiguration == nil {
-			return fmt.Errorf("no log configuration found for container %s", *container.Name)
+	printEvent := func(stream LogStream, event LogEvent) {
+		if structuredOutput() {
+			if err := printOutput(event); err != nil {
+				log.Printf("Unable to print log event: %v", err)
+			}
+		} else if len(selection.tasks) > 1 {
+			stream.printer(
+				"%s | %s | %s | %s\n",
+				stream.taskId,
+				stream.containerName,
+				event.Timestamp,
+				event.Message,
+			)
+		} else if len(selection.containers) > 1 {
+			stream.printer(
+				"%s | %s | %s\n",
+				stream.containerName,
+				event.Timestamp,
+				event.Message,
+			)
+		} else {
+			stream.printer(
+				"%s | %s\n",
+				event.Timestamp,
+				event.Message,
+			)
 		}
-		options := container.LogConfiguration.Options
-		if options == nil {
-			return fmt.Errorf("no log options found for container %s", *container.Name)
+	}
+
+	// Stopped tasks can't be tailed, so their logs are always fetched from the
+	// history, starting from when the earliest of them was created.
+	since := options.since
+	var historyStreams, liveStreams []LogStream
+	for _, stream := range streams {
+		if stream.stopped {
+			historyStreams = append(historyStreams, stream)
+			if options.since.IsZero() && (since.IsZero() || stream.createdAt.Before(since)) {
+				since = stream.createdAt
+			}
+			continue
+		}
+		if !options.since.IsZero() {
+			historyStreams = append(historyStreams, stream)
 		}
-		allLogOptions = append(allLogOptions, LogOptions{
-			containerName: *container.Name,
-			group:         options["awslogs-group"],
-			streamPrefix:  options["awslogs-stream-prefix"],
-			printer:       printerByIndex(noColors, index),
-		})
+		if options.follow {
+			liveStreams = append(liveStreams, stream)
+		}
+	}
+	if len(historyStreams) == 0 && len(liveStreams) == 0 {
+		return fmt.Errorf("--since is required when not following the logs of running tasks")
 	}
 
+	// Live tail events are held back until the history has been printed, so
+	// both can be stitched together without gaps or duplicates.
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	gate := newLogGate(len(historyStreams) == 0)
+
+	var started sync.WaitGroup
 	var wg sync.WaitGroup
-	for _, task := range selection.tasks {
-		taskArnSlices := strings.Split(*task.TaskArn, "/")
-		taskId := taskArnSlices[len(taskArnSlices)-1]
+	for _, stream := range liveStreams {
+		started.Add(1)
+		wg.Add(1)
 
-		for _, logOptions := range allLogOptions {
-			streamName := fmt.Sprintf(
-				"%s/%s/%s",
-				logOptions.streamPrefix,
-				logOptions.containerName,
-				taskId,
-			)
-			wg.Add(1)
-
-			go func(taskId string, logOptions LogOptions) {
-				defer wg.Done()
-
-				err := clients.StartLiveTail(
-					ctx,
-					logOptions.group,
-					streamName,
-					client.LiveTailHandlers{
-						Start: func() {
-							log.Printf(
-								"Starting live tail for container '%s' running at task '%s'\n",
-								logOptions.containerName,
-								taskId,
-							)
-						},
-						Update: func(event logsTypes.LiveTailSessionLogEvent) {
-							timestamp := time.UnixMilli(*event.Timestamp)
-							if len(selection.tasks) > 1 {
-								logOptions.printer(
-									"%s | %s | %s | %s\n",
-									taskId,
-									logOptions.containerName,
-									timestamp,
-									*event.Message,
-								)
-							} else if len(selection.containers) > 1 {
-								logOptions.printer(
-									"%s | %s | %s\n",
-									logOptions.containerName,
-									timestamp,
-									*event.Message,
-								)
-							} else {
-								logOptions.printer(
-									"%s | %s\n",
-									timestamp,
-									*event.Message,
-								)
-							}
-						},
+		go func(stream LogStream) {
+			defer wg.Done()
+
+			var once sync.Once
+			defer once.Do(started.Done)
+
+			err := clients.StartLiveTail(
+				ctx,
+				stream.group,
+				stream.name,
+				client.LiveTailHandlers{
+					Start: func() {
+						log.Printf(
+							"Starting live tail for container '%s' running at task '%s'\n",
+							stream.containerName,
+							stream.taskId,
+						)
+						once.Do(started.Done)
+					},
+					Update: func(event logsTypes.LiveTailSessionLogEvent) {
+						key := logEventKey{
+							stream:    stream.name,
+							timestamp: *event.Timestamp,
+							message:   *event.Message,
+						}
+						gate.push(key, func() {
+							printEvent(stream, newLogEvent(
+								stream,
+								event.Timestamp,
+								event.IngestionTime,
+								event.Message,
+							))
+						})
 					},
+				},
+			)
+			if err != nil {
+				// Errors are reported apart from the events, which may be piped
+				log.Printf(
+					"Error live tailing logs of container '%s' running at task '%s': %v\n",
+					stream.containerName,
+					stream.taskId,
+					err,
 				)
-				if err != nil {
-					logOptions.printer("Error live tailing logs: %v", err)
-				}
-			}(taskId, logOptions)
+			}
+		}(stream)
+	}
+
+	if len(historyStreams) > 0 {
+		started.Wait()
+
+		until := options.until
+		if until.IsZero() {
+			until = time.Now()
 		}
+
+		history, err := logHistory(ctx, clients, historyStreams, since, until)
+		if err != nil {
+			// Stop the live tails, nothing would print their events
+			cancel()
+			wg.Wait()
+			return err
+		}
+		seen := map[logEventKey]bool{}
+		for _, event := range history {
+			seen[logEventKey{
+				stream:    *event.LogStreamName,
+				timestamp: *event.Timestamp,
+				message:   *event.Message,
+			}] = true
+			printEvent(event.stream, newLogEvent(
+				event.stream,
+				event.Timestamp,
+				event.IngestionTime,
+				event.Message,
+			))
+		}
+		gate.release(seen)
 	}
 	wg.Wait()
 
 	return nil
 }
 
+func logStreams(noColors bool, selection LogsSelection) ([]LogStream, error) {
+	var streams []LogStream
+	for _, task := range selection.tasks {
+		taskArnSlices := strings.Split(*task.TaskArn, "/")
+		taskId := taskArnSlices[len(taskArnSlices)-1]
+
+		stopped := task.LastStatus != nil && *task.LastStatus == "STOPPED"
+		var createdAt time.Time
+		if task.CreatedAt != nil {
+			createdAt = *task.CreatedAt
+		}
+
+		// Tasks running another revision than the service, such as the ones
+		// stopped before a deployment, log as that revision says
+		containers := selection.containers
+		if revisionContainers, ok := selection.revisions[stringValue(task.TaskDefinitionArn)]; ok {
+			containers = revisionContainers
+		}
+
+		for _, container := range containers {
+			if container.LogConfiguration == nil {
+				return nil, fmt.Errorf("no log configuration found for container %s", *container.Name)
+			}
+			options := container.LogConfiguration.Options
+			if options == nil {
+				return nil, fmt.Errorf("no log options found for container %s", *container.Name)
+			}
+
+			// Containers keep their color across revisions
+			index := slices.IndexFunc(selection.containers, func(selected types.ContainerDefinition) bool {
+				return *selected.Name == *container.Name
+			})
+
+			streams = append(streams, LogStream{
+				stopped:       stopped,
+				createdAt:     createdAt,
+				taskId:        taskId,
+				containerName: *container.Name,
+				group:         options["awslogs-group"],
+				name: fmt.Sprintf(
+					"%s/%s/%s",
+					options["awslogs-stream-prefix"],
+					*container.Name,
+					taskId,
+				),
+				printer: printerByIndex(noColors, max(index, 0)),
+			})
+		}
+	}
+
+	return streams, nil
+}
+
+type historyEvent struct {
+	logsTypes.FilteredLogEvent
+	stream LogStream
+}
+
+// logHistory fetches the events logged between since and until for all the
+// streams, ordered by timestamp across log groups.
+func logHistory(
+	ctx context.Context,
+	clients client.Client,
+	streams []LogStream,
+	since time.Time,
+	until time.Time,
+) ([]historyEvent, error) {
+	var groups []string
+	streamsByGroup := map[string]map[string]LogStream{}
+	for _, stream := range streams {
+		if _, ok := streamsByGroup[stream.group]; !ok {
+			groups = append(groups, stream.group)
+			streamsByGroup[stream.group] = map[string]LogStream{}
+		}
+		streamsByGroup[stream.group][stream.name] = stream
+	}
+
+	var history []historyEvent
+	for _, group := range groups {
+		var streamNames []string
+		for _, stream := range streams {
+			if stream.group == group {
+				streamNames = append(streamNames, stream.name)
+			}
+		}
+
+		events, err := clients.FilterLogEvents(ctx, group, streamNames, since, until)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch logs from '%s': %w", group, err)
+		}
+		for _, event := range events {
+			history = append(history, historyEvent{
+				FilteredLogEvent: event,
+				stream:           streamsByGroup[group][*event.LogStreamName],
+			})
+		}
+	}
+
+	slices.SortStableFunc(history, func(a, b historyEvent) int {
+		return cmp.Compare(*a.Timestamp, *b.Timestamp)
+	})
+
+	return history, nil
+}
+
+type logEventKey struct {
+	stream    string
+	timestamp int64
+	message   string
+}
+
+type pendingEvent struct {
+	key   logEventKey
+	print func()
+}
+
+// logGate buffers live tail events until released, dropping the ones already
+// printed as part of the history.
+type logGate struct {
+	mu      sync.Mutex
+	open    bool
+	seen    map[logEventKey]bool
+	pending []pendingEvent
+}
+
+func newLogGate(open bool) *logGate {
+	return &logGate{open: open}
+}
+
+func (g *logGate) push(key logEventKey, print func()) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.seen[key] {
+		return
+	}
+
+	if g.open {
+		print()
+	} else {
+		g.pending = append(g.pending, pendingEvent{key: key, print: print})
+	}
+}
+
+// release prints the buffered events that are not part of seen and lets any
+// further events through.
+func (g *logGate) release(seen map[logEventKey]bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.seen = seen
+	for _, event := range g.pending {
+		if !g.seen[event.key] {
+			event.print()
+		}
+	}
+	g.pending = nil
+	g.open = true
+}
+
 func logsSelector(
 	ctx context.Context,
+	client client.Client,
 	selectors selector.Selectors,
 ) (*LogsSelection, error) {
-	cluster, err := selectors.Cluster(ctx, clusterRegex)
+	cluster, err := selectors.Cluster(ctx, clusterFilter)
 	if err != nil {
 		return nil, err
 	}
 
-	service, err := selectors.Service(ctx, cluster, serviceRegex)
+	service, err := selectors.Service(ctx, cluster, serviceFilter)
 	if err != nil {
 		return nil, err
 	}
 
-	tasks, err := selectors.Tasks(ctx, service)
+	tasks, err := selectors.Tasks(ctx, service, taskFilter, true)
 	if err != nil {
 		return nil, err
 	}
 
-	containers, err := selectors.ContainerDefinitions(ctx, *service.TaskDefinition)
+	containers, err := selectors.ContainerDefinitions(ctx, *service.TaskDefinition, containerFilter)
+	if err != nil {
+		return nil, err
+	}
+
+	revisions, err := revisionContainers(ctx, client, service, tasks, containers)
 	if err != nil {
 		return nil, err
 	}
@@ -199,9 +550,49 @@ func logsSelector(
 		service:    service,
 		tasks:      tasks,
 		containers: containers,
+		revisions:  revisions,
 	}, nil
 }
 
+// revisionContainers describes the task definitions run by the tasks other
+// than the one of the service, keeping the containers selected by name.
+// Stopped tasks in particular may have run an older revision, with different
+// containers or log configuration.
+func revisionContainers(
+	ctx context.Context,
+	client client.Client,
+	service *types.Service,
+	tasks []types.Task,
+	containers []types.ContainerDefinition,
+) (map[string][]types.ContainerDefinition, error) {
+	revisions := map[string][]types.ContainerDefinition{}
+	for _, task := range tasks {
+		taskDefinitionArn := stringValue(task.TaskDefinitionArn)
+		if taskDefinitionArn == "" || taskDefinitionArn == *service.TaskDefinition {
+			continue
+		}
+		if _, ok := revisions[taskDefinitionArn]; ok {
+			continue
+		}
+
+		taskDefinition, err := client.DescribeTaskDefinition(ctx, taskDefinitionArn)
+		if err != nil {
+			return nil, err
+		}
+
+		revisionContainers := []types.ContainerDefinition{}
+		for _, container := range taskDefinition.ContainerDefinitions {
+			if slices.ContainsFunc(containers, func(selected types.ContainerDefinition) bool {
+				return *selected.Name == *container.Name
+			}) {
+				revisionContainers = append(revisionContainers, container)
+			}
+		}
+		revisions[taskDefinitionArn] = revisionContainers
+	}
+	return revisions, nil
+}
+
 func printerByIndex(noColors bool, index int) Printer {
 	if noColors {
 		return func(format string, a ...any) {
@@ -216,4 +607,9 @@ func init() {
 	rootCmd.AddCommand(logsCmd)
 
 	logsCmd.Flags().BoolP("no-colors", "", false, "Disable log coloring")
+	logsCmd.Flags().
+		String("since", "", "Show logs since a relative duration (e.g. 15m) or RFC3339 timestamp")
+	logsCmd.Flags().
+		String("until", "", "Show logs until a relative duration (e.g. 5m) or RFC3339 timestamp, implies --no-follow")
+	logsCmd.Flags().Bool("no-follow", false, "Exit after printing the logs history instead of live tailing")
 }
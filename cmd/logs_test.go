@@ -9,6 +9,7 @@ import (
 	logstypes "github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs/types"
 	ecsTypes "github.com/aws/aws-sdk-go-v2/service/ecs/types"
 	"github.com/sestrella/iecs/client"
+	"github.com/spf13/cobra"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 )
@@ -67,7 +68,7 @@ func TestRunLogs_Success(t *testing.T) {
 		service:    service,
 		tasks:      []ecsTypes.Task{task},
 		containers: []ecsTypes.ContainerDefinition{*containerDefinition},
-	})
+	}, LogsOptions{follow: true})
 
 	// Check assertions
 	assert.NoError(t, err)
@@ -115,7 +116,7 @@ func TestRunLogs_MissingLogConfiguration(t *testing.T) {
 		service:    service,
 		tasks:      []ecsTypes.Task{task},
 		containers: []ecsTypes.ContainerDefinition{*containerDefinition},
-	})
+	}, LogsOptions{follow: true})
 
 	// Check assertions
 	assert.Error(t, err)
@@ -170,7 +171,7 @@ func TestRunLogs_MissingLogOptions(t *testing.T) {
 		service:    service,
 		tasks:      []ecsTypes.Task{task},
 		containers: []ecsTypes.ContainerDefinition{*containerDefinition},
-	})
+	}, LogsOptions{follow: true})
 
 	// Check assertions
 	assert.Error(t, err)
@@ -233,7 +234,7 @@ func TestRunLogs_StartLiveTailError(t *testing.T) {
 		service:    service,
 		tasks:      []ecsTypes.Task{task},
 		containers: []ecsTypes.ContainerDefinition{*containerDefinition},
-	})
+	}, LogsOptions{follow: true})
 
 	// Check assertions
 	assert.NoError(t, err)
@@ -298,7 +299,7 @@ func TestRunLogs_HandlerBehavior(t *testing.T) {
 		service:    service,
 		tasks:      []ecsTypes.Task{task},
 		containers: []ecsTypes.ContainerDefinition{*containerDefinition},
-	})
+	}, LogsOptions{follow: true})
 	assert.NoError(t, err)
 
 	// Test that the function was called
@@ -323,3 +324,244 @@ func TestRunLogs_HandlerBehavior(t *testing.T) {
 	// we mainly verify that the handler doesn't panic and completes
 	// In a full implementation, you would verify the output format
 }
+
+func TestRunLogs_History(t *testing.T) {
+	// Create mock objects
+	mockClient := new(MockClient)
+
+	// Setup mock responses
+	taskArn := "arn:aws:ecs:us-east-1:123456789012:task/my-cluster/12345678-1234-1234-1234-123456789012"
+	containerDefinitionName := "my-container"
+	streamName := "ecs/my-container/12345678-1234-1234-1234-123456789012"
+	since := time.Now().Add(-15 * time.Minute)
+	until := time.Now().Add(-5 * time.Minute)
+
+	// Mock container definition with log configuration
+	containerDefinition := ecsTypes.ContainerDefinition{
+		Name: &containerDefinitionName,
+		LogConfiguration: &ecsTypes.LogConfiguration{
+			LogDriver: "awslogs",
+			Options: map[string]string{
+				"awslogs-group":         "/ecs/my-service",
+				"awslogs-stream-prefix": "ecs",
+			},
+		},
+	}
+
+	message := "past log message"
+	timestamp := since.UnixMilli()
+	mockClient.On("FilterLogEvents", mock.Anything, "/ecs/my-service", []string{streamName}, since, until).
+		Return([]logstypes.FilteredLogEvent{
+			{LogStreamName: &streamName, Message: &message, Timestamp: &timestamp},
+		}, nil)
+
+	// Test the function
+	err := runLogs(context.Background(), true, mockClient, LogsSelection{
+		tasks:      []ecsTypes.Task{{TaskArn: &taskArn}},
+		containers: []ecsTypes.ContainerDefinition{containerDefinition},
+	}, LogsOptions{since: since, until: until})
+
+	// Check assertions
+	assert.NoError(t, err)
+	mockClient.AssertExpectations(t)
+	// Live tail should not be started when not following
+	mockClient.AssertNotCalled(t, "StartLiveTail")
+}
+
+func TestLogGate_DropsHistoryDuplicates(t *testing.T) {
+	gate := newLogGate(false)
+	duplicate := logEventKey{stream: "stream", timestamp: 1, message: "duplicate"}
+	fresh := logEventKey{stream: "stream", timestamp: 2, message: "fresh"}
+
+	var printed []string
+	gate.push(duplicate, func() { printed = append(printed, "duplicate") })
+	gate.push(fresh, func() { printed = append(printed, "fresh") })
+
+	// Nothing is printed until the history has been printed
+	assert.Empty(t, printed)
+
+	gate.release(map[logEventKey]bool{duplicate: true})
+	assert.Equal(t, []string{"fresh"}, printed)
+
+	// Events arriving after the release are printed straight away
+	gate.push(logEventKey{stream: "stream", timestamp: 3}, func() {
+		printed = append(printed, "late")
+	})
+	assert.Equal(t, []string{"fresh", "late"}, printed)
+}
+
+func TestParseLogsTime(t *testing.T) {
+	now := time.Date(2024, 1, 2, 15, 4, 5, 0, time.UTC)
+
+	relative, err := parseLogsTime("15m", now)
+	assert.NoError(t, err)
+	assert.Equal(t, now.Add(-15*time.Minute), relative)
+
+	absolute, err := parseLogsTime("2024-01-02T14:00:00Z", now)
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2024, 1, 2, 14, 0, 0, 0, time.UTC), absolute)
+
+	empty, err := parseLogsTime("", now)
+	assert.NoError(t, err)
+	assert.True(t, empty.IsZero())
+
+	_, err = parseLogsTime("yesterday", now)
+	assert.Error(t, err)
+}
+
+func TestLogsOptions_SinceAfterUntil(t *testing.T) {
+	cmd := &cobra.Command{}
+	cmd.Flags().String("since", "5m", "")
+	cmd.Flags().String("until", "15m", "")
+	cmd.Flags().Bool("no-follow", false, "")
+
+	_, err := logsOptions(cmd, time.Now())
+
+	assert.EqualError(t, err, "--since (5m) must be before --until (15m)")
+}
+
+func TestRunLogs_HistoryErrorStopsLiveTail(t *testing.T) {
+	mockClient := new(MockClient)
+
+	taskArn := "arn:aws:ecs:us-east-1:123456789012:task/my-cluster/12345678-1234-1234-1234-123456789012"
+	containerDefinitionName := "my-container"
+	streamName := "ecs/my-container/12345678-1234-1234-1234-123456789012"
+	containerDefinition := ecsTypes.ContainerDefinition{
+		Name: &containerDefinitionName,
+		LogConfiguration: &ecsTypes.LogConfiguration{
+			LogDriver: "awslogs",
+			Options: map[string]string{
+				"awslogs-group":         "/ecs/my-service",
+				"awslogs-stream-prefix": "ecs",
+			},
+		},
+	}
+
+	// The live tail runs until its context is cancelled
+	tailStopped := make(chan struct{})
+	mockClient.On("StartLiveTail", mock.Anything, "/ecs/my-service", streamName, mock.AnythingOfType("client.LiveTailHandlers")).
+		Run(func(args mock.Arguments) {
+			args.Get(3).(client.LiveTailHandlers).Start()
+			<-args.Get(0).(context.Context).Done()
+			close(tailStopped)
+		}).
+		Return(nil)
+	mockClient.On("FilterLogEvents", mock.Anything, "/ecs/my-service", []string{streamName}, mock.Anything, mock.Anything).
+		Return([]logstypes.FilteredLogEvent(nil), errors.New("access denied"))
+
+	err := runLogs(context.Background(), true, mockClient, LogsSelection{
+		tasks:      []ecsTypes.Task{{TaskArn: &taskArn}},
+		containers: []ecsTypes.ContainerDefinition{containerDefinition},
+	}, LogsOptions{since: time.Now().Add(-15 * time.Minute), follow: true})
+
+	assert.EqualError(t, err, "failed to fetch logs from '/ecs/my-service': access denied")
+	select {
+	case <-tailStopped:
+	default:
+		t.Fatal("live tail still running")
+	}
+}
+
+func TestRunLogs_StoppedTask(t *testing.T) {
+	// Create mock objects
+	mockClient := new(MockClient)
+
+	// Setup mock responses
+	taskArn := "arn:aws:ecs:us-east-1:123456789012:task/my-cluster/12345678-1234-1234-1234-123456789012"
+	containerDefinitionName := "my-container"
+	streamName := "ecs/my-container/12345678-1234-1234-1234-123456789012"
+	createdAt := time.Now().Add(-30 * time.Minute)
+	stopped := "STOPPED"
+
+	// Mock container definition with log configuration
+	containerDefinition := ecsTypes.ContainerDefinition{
+		Name: &containerDefinitionName,
+		LogConfiguration: &ecsTypes.LogConfiguration{
+			LogDriver: "awslogs",
+			Options: map[string]string{
+				"awslogs-group":         "/ecs/my-service",
+				"awslogs-stream-prefix": "ecs",
+			},
+		},
+	}
+
+	mockClient.On("FilterLogEvents", mock.Anything, "/ecs/my-service", []string{streamName}, createdAt, mock.AnythingOfType("time.Time")).
+		Return([]logstypes.FilteredLogEvent{}, nil)
+
+	// Test the function
+	err := runLogs(context.Background(), true, mockClient, LogsSelection{
+		tasks: []ecsTypes.Task{
+			{TaskArn: &taskArn, LastStatus: &stopped, CreatedAt: &createdAt},
+		},
+		containers: []ecsTypes.ContainerDefinition{containerDefinition},
+	}, LogsOptions{follow: true})
+
+	// Check assertions
+	assert.NoError(t, err)
+	mockClient.AssertExpectations(t)
+	// Live tail is pointless for a stopped task
+	mockClient.AssertNotCalled(t, "StartLiveTail")
+}
+
+func TestLogStreams_StoppedTaskRevision(t *testing.T) {
+	mockClient := new(MockClient)
+
+	serviceTaskDefinitionArn := "arn:aws:ecs:us-east-1:123456789012:task-definition/my-task-def:2"
+	stoppedTaskDefinitionArn := "arn:aws:ecs:us-east-1:123456789012:task-definition/my-task-def:1"
+	runningTaskArn := "arn:aws:ecs:us-east-1:123456789012:task/my-cluster/running"
+	stoppedTaskArn := "arn:aws:ecs:us-east-1:123456789012:task/my-cluster/stopped"
+	stopped := "STOPPED"
+
+	containerDefinition := func(name string, group string, prefix string) ecsTypes.ContainerDefinition {
+		return ecsTypes.ContainerDefinition{
+			Name: &name,
+			LogConfiguration: &ecsTypes.LogConfiguration{
+				LogDriver: "awslogs",
+				Options: map[string]string{
+					"awslogs-group":         group,
+					"awslogs-stream-prefix": prefix,
+				},
+			},
+		}
+	}
+
+	service := &ecsTypes.Service{TaskDefinition: &serviceTaskDefinitionArn}
+	tasks := []ecsTypes.Task{
+		{TaskArn: &runningTaskArn, TaskDefinitionArn: &serviceTaskDefinitionArn},
+		{TaskArn: &stoppedTaskArn, TaskDefinitionArn: &stoppedTaskDefinitionArn, LastStatus: &stopped},
+	}
+	containers := []ecsTypes.ContainerDefinition{containerDefinition("app", "/ecs/api", "api")}
+
+	// The older revision logged to another group and had a sidecar
+	mockClient.On("DescribeTaskDefinition", mock.Anything, stoppedTaskDefinitionArn).
+		Return(&ecsTypes.TaskDefinition{
+			TaskDefinitionArn: &stoppedTaskDefinitionArn,
+			ContainerDefinitions: []ecsTypes.ContainerDefinition{
+				containerDefinition("app", "/ecs/legacy", "ecs"),
+				containerDefinition("sidecar", "/ecs/legacy", "ecs"),
+			},
+		}, nil).
+		Once()
+
+	revisions, err := revisionContainers(context.Background(), mockClient, service, tasks, containers)
+	assert.NoError(t, err)
+
+	streams, err := logStreams(true, LogsSelection{
+		service:    service,
+		tasks:      tasks,
+		containers: containers,
+		revisions:  revisions,
+	})
+	assert.NoError(t, err)
+
+	var names []string
+	for _, stream := range streams {
+		names = append(names, stream.group+" "+stream.name)
+	}
+	assert.Equal(t, []string{
+		"/ecs/api api/app/running",
+		"/ecs/legacy ecs/app/stopped",
+	}, names)
+	assert.True(t, streams[1].stopped)
+	mockClient.AssertExpectations(t)
+}
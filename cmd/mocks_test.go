@@ -5,6 +5,7 @@ import (
 	"os/exec"
 	"time"
 
+	logsTypes "github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs/types"
 	"github.com/aws/aws-sdk-go-v2/service/ecs/types"
 	"github.com/sestrella/iecs/client"
 	"github.com/stretchr/testify/mock"
@@ -54,25 +55,74 @@ func (m *MockClient) DescribeServices(
 	return args.Get(0).([]types.Service), args.Error(1)
 }
 
+func (m *MockClient) RunTask(
+	ctx context.Context,
+	service *types.Service,
+	overrides []types.ContainerOverride,
+) (*types.Task, error) {
+	args := m.Called(ctx, service, overrides)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*types.Task), args.Error(1)
+}
+
+func (m *MockClient) StopTask(
+	ctx context.Context,
+	clusterArn string,
+	taskArn string,
+	reason string,
+) (*types.Task, error) {
+	args := m.Called(ctx, clusterArn, taskArn, reason)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*types.Task), args.Error(1)
+}
+
 func (m *MockClient) UpdateService(
 	ctx context.Context,
 	service *types.Service,
 	input client.ServiceConfig,
-	waitTimeout time.Duration,
 ) (*types.Service, error) {
-	args := m.Called(ctx, service, input, waitTimeout)
+	args := m.Called(ctx, service, input)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
 	return args.Get(0).(*types.Service), args.Error(1)
 }
 
+func (m *MockClient) ListServiceDeployments(
+	ctx context.Context,
+	clusterArn string,
+	serviceArn string,
+	statuses []types.ServiceDeploymentStatus,
+) ([]types.ServiceDeploymentBrief, error) {
+	args := m.Called(ctx, clusterArn, serviceArn, statuses)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]types.ServiceDeploymentBrief), args.Error(1)
+}
+
+func (m *MockClient) DescribeServiceRevisions(
+	ctx context.Context,
+	serviceRevisionArns []string,
+) ([]types.ServiceRevision, error) {
+	args := m.Called(ctx, serviceRevisionArns)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]types.ServiceRevision), args.Error(1)
+}
+
 func (m *MockClient) ListTasks(
 	ctx context.Context,
 	clusterArn string,
 	serviceArn string,
+	desiredStatus types.DesiredStatus,
 ) ([]string, error) {
-	args := m.Called(ctx, clusterArn, serviceArn)
+	args := m.Called(ctx, clusterArn, serviceArn, desiredStatus)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
@@ -112,6 +162,20 @@ func (m *MockClient) StartLiveTail(
 	return args.Error(0)
 }
 
+func (m *MockClient) FilterLogEvents(
+	ctx context.Context,
+	logGroupName string,
+	streamNames []string,
+	startTime time.Time,
+	endTime time.Time,
+) ([]logsTypes.FilteredLogEvent, error) {
+	args := m.Called(ctx, logGroupName, streamNames, startTime, endTime)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]logsTypes.FilteredLogEvent), args.Error(1)
+}
+
 func (m *MockClient) ExecuteCommand(
 	ctx context.Context,
 	cluster *types.Cluster,
@@ -127,6 +191,20 @@ func (m *MockClient) ExecuteCommand(
 	return args.Get(0).(*exec.Cmd), args.Error(1)
 }
 
+func (m *MockClient) StartPortForwardingSession(
+	ctx context.Context,
+	cluster *types.Cluster,
+	taskArn string,
+	container *types.Container,
+	config client.PortForwardingConfig,
+) (*exec.Cmd, error) {
+	args := m.Called(ctx, cluster, taskArn, container, config)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*exec.Cmd), args.Error(1)
+}
+
 func (m *MockClient) DescribeTaskDefinition(
 	ctx context.Context,
 	taskDefinitionArn string,
@@ -137,3 +215,14 @@ func (m *MockClient) DescribeTaskDefinition(
 	}
 	return args.Get(0).(*types.TaskDefinition), args.Error(1)
 }
+
+func (m *MockClient) RegisterTaskDefinition(
+	ctx context.Context,
+	taskDefinition *types.TaskDefinition,
+) (*types.TaskDefinition, error) {
+	args := m.Called(ctx, taskDefinition)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*types.TaskDefinition), args.Error(1)
+}
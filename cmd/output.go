@@ -0,0 +1,131 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/service/ecs/types"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	outputTable = "table"
+	outputJSON  = "json"
+	outputYAML  = "yaml"
+)
+
+var outputFormats = []string{outputTable, outputJSON, outputYAML}
+
+// outputMu serializes the documents written by concurrent goroutines, such as
+// the log events of several streams.
+var outputMu sync.Mutex
+
+// SelectionOutput is the machine-readable form of the selected resources.
+type SelectionOutput struct {
+	Cluster    string   `json:"cluster" yaml:"cluster"`
+	Service    string   `json:"service,omitempty" yaml:"service,omitempty"`
+	Task       string   `json:"task,omitempty" yaml:"task,omitempty"`
+	Tasks      []string `json:"tasks,omitempty" yaml:"tasks,omitempty"`
+	Container  string   `json:"container,omitempty" yaml:"container,omitempty"`
+	Containers []string `json:"containers,omitempty" yaml:"containers,omitempty"`
+}
+
+// structuredOutput reports whether results are printed as JSON or YAML rather
+// than as human readable text.
+func structuredOutput() bool {
+	return outputFormat == outputJSON || outputFormat == outputYAML
+}
+
+// decorationOutput returns where the human oriented output goes, which is
+// stderr when stdout is reserved for structured output.
+func decorationOutput() io.Writer {
+	if structuredOutput() {
+		return os.Stderr
+	}
+	return os.Stdout
+}
+
+// printOutput writes v to stdout as a JSON line or a YAML document.
+func printOutput(v any) error {
+	outputMu.Lock()
+	defer outputMu.Unlock()
+
+	return writeOutput(os.Stdout, outputFormat, v)
+}
+
+func writeOutput(w io.Writer, format string, v any) error {
+	switch format {
+	case outputJSON:
+		return json.NewEncoder(w).Encode(v)
+	case outputYAML:
+		if _, err := io.WriteString(w, "---\n"); err != nil {
+			return err
+		}
+		encoder := yaml.NewEncoder(w)
+		encoder.SetIndent(2)
+		if err := encoder.Encode(v); err != nil {
+			return err
+		}
+		return encoder.Close()
+	default:
+		return fmt.Errorf("unsupported output \"%s\"", format)
+	}
+}
+
+func (s ExecSelection) output() SelectionOutput {
+	return SelectionOutput{
+		Cluster:   *s.cluster.ClusterArn,
+		Service:   *s.service.ServiceArn,
+		Task:      *s.task.TaskArn,
+		Container: *s.container.Name,
+	}
+}
+
+func (s LogsSelection) output() SelectionOutput {
+	output := SelectionOutput{
+		Cluster: *s.cluster.ClusterArn,
+		Service: *s.service.ServiceArn,
+	}
+	for _, task := range s.tasks {
+		output.Tasks = append(output.Tasks, *task.TaskArn)
+	}
+	for _, container := range s.containers {
+		output.Containers = append(output.Containers, *container.Name)
+	}
+	return output
+}
+
+// ServiceOutput is the machine-readable form of a service after an update.
+type ServiceOutput struct {
+	Cluster        string `json:"cluster" yaml:"cluster"`
+	Service        string `json:"service" yaml:"service"`
+	Status         string `json:"status" yaml:"status"`
+	TaskDefinition string `json:"taskDefinition" yaml:"taskDefinition"`
+	DesiredCount   int32  `json:"desiredCount" yaml:"desiredCount"`
+	RunningCount   int32  `json:"runningCount" yaml:"runningCount"`
+	PendingCount   int32  `json:"pendingCount" yaml:"pendingCount"`
+}
+
+func serviceOutput(service *types.Service) ServiceOutput {
+	output := ServiceOutput{
+		DesiredCount: service.DesiredCount,
+		RunningCount: service.RunningCount,
+		PendingCount: service.PendingCount,
+	}
+	if service.ClusterArn != nil {
+		output.Cluster = *service.ClusterArn
+	}
+	if service.ServiceArn != nil {
+		output.Service = *service.ServiceArn
+	}
+	if service.Status != nil {
+		output.Status = *service.Status
+	}
+	if service.TaskDefinition != nil {
+		output.TaskDefinition = *service.TaskDefinition
+	}
+	return output
+}
@@ -0,0 +1,44 @@
+package cmd
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteOutput_JSON(t *testing.T) {
+	var out bytes.Buffer
+
+	err := writeOutput(&out, outputJSON, SelectionOutput{
+		Cluster:   "arn:aws:ecs:us-east-1:123456789012:cluster/my-cluster",
+		Container: "my-container",
+	})
+
+	require.NoError(t, err)
+	assert.Equal(
+		t,
+		`{"cluster":"arn:aws:ecs:us-east-1:123456789012:cluster/my-cluster","container":"my-container"}`+"\n",
+		out.String(),
+	)
+}
+
+func TestWriteOutput_YAML(t *testing.T) {
+	var out bytes.Buffer
+
+	err := writeOutput(&out, outputYAML, PortForwardOutput{
+		SelectionOutput: SelectionOutput{Cluster: "my-cluster"},
+		LocalPort:       8080,
+		RemotePort:      80,
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, "---\ncluster: my-cluster\nlocalPort: 8080\nremotePort: 80\n", out.String())
+}
+
+func TestWriteOutput_Unsupported(t *testing.T) {
+	err := writeOutput(&bytes.Buffer{}, "xml", SelectionOutput{})
+
+	assert.Error(t, err)
+}
@@ -0,0 +1,160 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/sestrella/iecs/client"
+	"github.com/spf13/cobra"
+)
+
+const (
+	portForwardLocalPortFlag  = "local-port"
+	portForwardRemotePortFlag = "remote-port"
+	portForwardRemoteHostFlag = "remote-host"
+)
+
+var portForwardCmd = &cobra.Command{
+	Use:   "port-forward",
+	Short: "Forward a local port to a container",
+	Example: `
+  iecs port-forward --remote-port 8080
+  iecs port-forward --local-port 5433 --remote-port 5432 --remote-host db.internal
+  `,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		config, err := portForwardingConfig(cmd)
+		if err != nil {
+			return err
+		}
+
+		awsClient, err := newClient(context.TODO())
+		if err != nil {
+			return err
+		}
+
+		selection, err := execSelector(
+			context.TODO(),
+			newSelectors(awsClient),
+		)
+		if err != nil {
+			return err
+		}
+
+		return runPortForward(
+			context.TODO(),
+			awsClient,
+			*selection,
+			config,
+		)
+	},
+	Aliases: []string{"pf"},
+}
+
+// portForwardingConfig reads the ports and host to forward from the flags.
+// The local port defaults to the remote one; ports that are not positive are
+// rejected before a session is started.
+func portForwardingConfig(cmd *cobra.Command) (client.PortForwardingConfig, error) {
+	remotePort, err := cmd.Flags().GetInt(portForwardRemotePortFlag)
+	if err != nil {
+		return client.PortForwardingConfig{}, err
+	}
+	if remotePort <= 0 {
+		return client.PortForwardingConfig{}, fmt.Errorf(
+			"--%s must be greater than 0, got %d",
+			portForwardRemotePortFlag,
+			remotePort,
+		)
+	}
+
+	localPort, err := cmd.Flags().GetInt(portForwardLocalPortFlag)
+	if err != nil {
+		return client.PortForwardingConfig{}, err
+	}
+	if !cmd.Flags().Changed(portForwardLocalPortFlag) {
+		localPort = remotePort
+	} else if localPort <= 0 {
+		return client.PortForwardingConfig{}, fmt.Errorf(
+			"--%s must be greater than 0, got %d",
+			portForwardLocalPortFlag,
+			localPort,
+		)
+	}
+
+	remoteHost, err := cmd.Flags().GetString(portForwardRemoteHostFlag)
+	if err != nil {
+		return client.PortForwardingConfig{}, err
+	}
+
+	return client.PortForwardingConfig{
+		LocalPort:  localPort,
+		RemotePort: remotePort,
+		RemoteHost: remoteHost,
+	}, nil
+}
+
+// PortForwardOutput is the machine-readable form of a port forwarding session.
+type PortForwardOutput struct {
+	SelectionOutput `yaml:",inline"`
+	LocalPort       int    `json:"localPort" yaml:"localPort"`
+	RemotePort      int    `json:"remotePort" yaml:"remotePort"`
+	RemoteHost      string `json:"remoteHost,omitempty" yaml:"remoteHost,omitempty"`
+}
+
+func runPortForward(
+	ctx context.Context,
+	client client.Client,
+	selection ExecSelection,
+	config client.PortForwardingConfig,
+) error {
+	cmd, err := client.StartPortForwardingSession(
+		ctx,
+		selection.cluster,
+		*selection.task.TaskArn,
+		selection.container,
+		config,
+	)
+	if err != nil {
+		return err
+	}
+
+	if structuredOutput() {
+		err = printOutput(PortForwardOutput{
+			SelectionOutput: selection.output(),
+			LocalPort:       config.LocalPort,
+			RemotePort:      config.RemotePort,
+			RemoteHost:      config.RemoteHost,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	target := "container"
+	if config.RemoteHost != "" {
+		target = config.RemoteHost
+	}
+	fmt.Fprintf(
+		decorationOutput(),
+		"%s localhost:%d -> %s:%d\n",
+		titleStyle.Render("Forwarding:"),
+		config.LocalPort,
+		target,
+		config.RemotePort,
+	)
+
+	return runSession(cmd)
+}
+
+func init() {
+	rootCmd.AddCommand(portForwardCmd)
+
+	portForwardCmd.Flags().
+		Int(portForwardLocalPortFlag, 0, "local port to listen on (defaults to the remote port)")
+	portForwardCmd.Flags().
+		Int(portForwardRemotePortFlag, 0, "port to forward to on the container or remote host")
+	portForwardCmd.Flags().
+		String(portForwardRemoteHostFlag, "", "host reachable from the container to forward to")
+	if err := portForwardCmd.MarkFlagRequired(portForwardRemotePortFlag); err != nil {
+		panic(err)
+	}
+}
@@ -0,0 +1,102 @@
+package cmd
+
+import (
+	"context"
+	"os/exec"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/ecs/types"
+	"github.com/sestrella/iecs/client"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunPortForward(t *testing.T) {
+	// Create mock objects
+	mockClient := new(MockClient)
+
+	// Setup mock responses
+	clusterArn := "arn:aws:ecs:us-east-1:123456789012:cluster/my-cluster"
+	clusterName := "my-cluster"
+	taskArn := "arn:aws:ecs:us-east-1:123456789012:task/my-cluster/12345678-1234-1234-1234-123456789012"
+	containerName := "my-container"
+	containerRuntimeId := "12345678abcdef"
+
+	cluster := &types.Cluster{
+		ClusterArn:  &clusterArn,
+		ClusterName: &clusterName,
+	}
+	container := &types.Container{
+		Name:      &containerName,
+		RuntimeId: &containerRuntimeId,
+	}
+	task := &types.Task{
+		TaskArn:    &taskArn,
+		Containers: []types.Container{*container},
+	}
+	config := client.PortForwardingConfig{
+		LocalPort:  5433,
+		RemotePort: 5432,
+		RemoteHost: "db.internal",
+	}
+
+	// Mock StartPortForwardingSession response
+	mockClient.On("StartPortForwardingSession",
+		mock.Anything,
+		cluster,
+		taskArn,
+		container,
+		config,
+	).Return(exec.Command("echo"), nil)
+
+	// Test the function
+	err := runPortForward(
+		context.Background(),
+		mockClient,
+		ExecSelection{cluster: cluster, task: task, container: container},
+		config,
+	)
+
+	// Check assertions
+	assert.NoError(t, err)
+	mockClient.AssertExpectations(t)
+}
+
+func newPortForwardTestCommand(t *testing.T, args ...string) *cobra.Command {
+	cmd := &cobra.Command{Use: "port-forward"}
+	cmd.Flags().Int(portForwardLocalPortFlag, 0, "")
+	cmd.Flags().Int(portForwardRemotePortFlag, 0, "")
+	cmd.Flags().String(portForwardRemoteHostFlag, "", "")
+	require.NoError(t, cmd.Flags().Parse(args))
+	return cmd
+}
+
+func TestPortForwardingConfig(t *testing.T) {
+	config, err := portForwardingConfig(newPortForwardTestCommand(t, "--remote-port", "8080"))
+	require.NoError(t, err)
+	assert.Equal(t, client.PortForwardingConfig{LocalPort: 8080, RemotePort: 8080}, config)
+
+	config, err = portForwardingConfig(newPortForwardTestCommand(
+		t,
+		"--local-port", "5433",
+		"--remote-port", "5432",
+		"--remote-host", "db.internal",
+	))
+	require.NoError(t, err)
+	assert.Equal(t, client.PortForwardingConfig{
+		LocalPort:  5433,
+		RemotePort: 5432,
+		RemoteHost: "db.internal",
+	}, config)
+
+	_, err = portForwardingConfig(newPortForwardTestCommand(t, "--remote-port", "0"))
+	assert.EqualError(t, err, "--remote-port must be greater than 0, got 0")
+
+	_, err = portForwardingConfig(newPortForwardTestCommand(t, "--remote-port", "-1"))
+	assert.EqualError(t, err, "--remote-port must be greater than 0, got -1")
+
+	_, err = portForwardingConfig(newPortForwardTestCommand(t, "--local-port", "0", "--remote-port", "8080"))
+	assert.EqualError(t, err, "--local-port must be greater than 0, got 0")
+}
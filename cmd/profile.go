@@ -0,0 +1,219 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/sestrella/iecs/client"
+)
+
+// knownRegions lists the commercial regions offered by the region picker.
+var knownRegions = []string{
+	"af-south-1",
+	"ap-east-1",
+	"ap-northeast-1",
+	"ap-northeast-2",
+	"ap-northeast-3",
+	"ap-south-1",
+	"ap-south-2",
+	"ap-southeast-1",
+	"ap-southeast-2",
+	"ap-southeast-3",
+	"ap-southeast-4",
+	"ca-central-1",
+	"ca-west-1",
+	"eu-central-1",
+	"eu-central-2",
+	"eu-north-1",
+	"eu-south-1",
+	"eu-south-2",
+	"eu-west-1",
+	"eu-west-2",
+	"eu-west-3",
+	"il-central-1",
+	"me-central-1",
+	"me-south-1",
+	"sa-east-1",
+	"us-east-1",
+	"us-east-2",
+	"us-west-1",
+	"us-west-2",
+}
+
+// newClient returns the client used by the commands. When several profiles or
+// regions are given, the clusters of all of them are listed and the calls that
+// follow go to the profile and region of the selected cluster.
+func newClient(ctx context.Context) (client.Client, error) {
+	if len(awsProfiles) == 0 && len(awsRegions) == 0 {
+		cfg, err := loadAWSConfig(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return client.NewClient(cfg), nil
+	}
+
+	profiles := awsProfiles
+	if len(profiles) == 0 {
+		profiles = []string{awsProfile}
+	}
+	regions := awsRegions
+	if len(regions) == 0 {
+		regions = []string{awsRegion}
+	}
+
+	var targets []client.Target
+	for _, profile := range profiles {
+		for _, region := range regions {
+			var optFns []func(*config.LoadOptions) error
+			if profile != "" {
+				optFns = append(optFns, config.WithSharedConfigProfile(profile))
+			}
+			if region != "" {
+				optFns = append(optFns, config.WithRegion(region))
+			}
+
+			cfg, err := config.LoadDefaultConfig(ctx, optFns...)
+			if err != nil {
+				return nil, fmt.Errorf("unable to load profile \"%s\": %w", profile, err)
+			}
+
+			targets = append(targets, client.Target{
+				Profile: profile,
+				Region:  cfg.Region,
+				Client:  client.NewClient(cfg),
+			})
+		}
+	}
+
+	return client.NewMultiClient(targets), nil
+}
+
+// loadAWSConfig loads the AWS configuration for the selected profile and
+// region. When neither is given, the user is asked to pick a profile if
+// several are available. A region is asked for when none is configured.
+func loadAWSConfig(ctx context.Context) (aws.Config, error) {
+	selectors := newSelectors(nil)
+	interactive := !nonInteractive && awsProfile == "" && awsRegion == ""
+
+	profile := awsProfile
+	if interactive && !awsEnvProfile() {
+		profiles, err := sharedConfigProfiles()
+		if err != nil {
+			return aws.Config{}, err
+		}
+		if len(profiles) > 1 {
+			if profile, err = selectors.Profile(profiles); err != nil {
+				return aws.Config{}, err
+			}
+		}
+	}
+
+	var optFns []func(*config.LoadOptions) error
+	if profile != "" {
+		optFns = append(optFns, config.WithSharedConfigProfile(profile))
+	}
+	if awsRegion != "" {
+		optFns = append(optFns, config.WithRegion(awsRegion))
+	}
+
+	cfg, err := config.LoadDefaultConfig(ctx, optFns...)
+	if err != nil {
+		return aws.Config{}, err
+	}
+
+	if !nonInteractive && cfg.Region == "" {
+		if cfg.Region, err = selectors.Region(knownRegions); err != nil {
+			return aws.Config{}, err
+		}
+	}
+
+	return cfg, nil
+}
+
+// awsEnvProfile reports whether the environment already determines the
+// credentials to use, e.g. when running under aws-vault.
+func awsEnvProfile() bool {
+	for _, name := range []string{"AWS_PROFILE", "AWS_DEFAULT_PROFILE", "AWS_ACCESS_KEY_ID"} {
+		if os.Getenv(name) != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// sharedConfigProfiles returns the profiles defined in the shared config and
+// credentials files, sorted alphabetically.
+func sharedConfigProfiles() ([]string, error) {
+	configPath := os.Getenv("AWS_CONFIG_FILE")
+	if configPath == "" {
+		configPath = config.DefaultSharedConfigFilename()
+	}
+	credentialsPath := os.Getenv("AWS_SHARED_CREDENTIALS_FILE")
+	if credentialsPath == "" {
+		credentialsPath = config.DefaultSharedCredentialsFilename()
+	}
+
+	seen := map[string]bool{}
+	for _, file := range []struct {
+		path        string
+		credentials bool
+	}{
+		{configPath, false},
+		{credentialsPath, true},
+	} {
+		f, err := os.Open(file.path)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		names, err := parseProfileNames(f, file.credentials)
+		f.Close()
+		if err != nil {
+			return nil, err
+		}
+		for _, name := range names {
+			seen[name] = true
+		}
+	}
+
+	profiles := make([]string, 0, len(seen))
+	for name := range seen {
+		profiles = append(profiles, name)
+	}
+	sort.Strings(profiles)
+	return profiles, nil
+}
+
+// parseProfileNames extracts the profile names from the section headers of a
+// shared config file. In the config file profiles other than the default one
+// are declared as "[profile name]", whereas the credentials file uses "[name]".
+func parseProfileNames(r io.Reader, credentials bool) ([]string, error) {
+	var names []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if !strings.HasPrefix(line, "[") || !strings.HasSuffix(line, "]") {
+			continue
+		}
+
+		section := strings.Fields(line[1 : len(line)-1])
+		switch {
+		case len(section) == 1 && (credentials || section[0] == "default"):
+			names = append(names, section[0])
+		case len(section) == 2 && !credentials && section[0] == "profile":
+			names = append(names, section[1])
+		}
+	}
+	return names, scanner.Err()
+}
@@ -0,0 +1,55 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseProfileNames_Config(t *testing.T) {
+	names, err := parseProfileNames(strings.NewReader(`
+[default]
+region = us-east-1
+
+[profile staging]
+region = eu-west-1
+
+[sso-session company]
+sso_region = us-east-1
+
+[services local]
+`), false)
+
+	require.NoError(t, err)
+	assert.Equal(t, []string{"default", "staging"}, names)
+}
+
+func TestParseProfileNames_Credentials(t *testing.T) {
+	names, err := parseProfileNames(strings.NewReader(`
+[default]
+aws_access_key_id = AKIA
+
+[production]
+aws_access_key_id = AKIA
+`), true)
+
+	require.NoError(t, err)
+	assert.Equal(t, []string{"default", "production"}, names)
+}
+
+func TestSharedConfigProfiles(t *testing.T) {
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "config")
+	require.NoError(t, os.WriteFile(configPath, []byte("[default]\n[profile staging]\n"), 0o600))
+	t.Setenv("AWS_CONFIG_FILE", configPath)
+	t.Setenv("AWS_SHARED_CREDENTIALS_FILE", filepath.Join(dir, "missing"))
+
+	profiles, err := sharedConfigProfiles()
+
+	require.NoError(t, err)
+	assert.Equal(t, []string{"default", "staging"}, profiles)
+}
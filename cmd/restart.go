@@ -0,0 +1,75 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/ecs/types"
+	"github.com/sestrella/iecs/client"
+	"github.com/spf13/cobra"
+)
+
+var restartCmd = &cobra.Command{
+	Use:   "restart",
+	Short: "Replace the tasks of a service by forcing a new deployment",
+	Long: `Forces a new deployment of the service with its current task definition
+and desired count, then follows it until it completes.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		awsClient, err := newClient(context.Background())
+		if err != nil {
+			return err
+		}
+
+		selectors := newSelectors(awsClient)
+
+		cluster, err := selectors.Cluster(context.Background(), clusterFilter)
+		if err != nil {
+			return err
+		}
+
+		service, err := selectors.Service(context.Background(), cluster, serviceFilter)
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintf(
+			decorationOutput(),
+			"%s %s (%d tasks, %s)\n",
+			titleStyle.Render("Restart:"),
+			*service.ServiceName,
+			service.DesiredCount,
+			shortTaskDefinition(service.TaskDefinition),
+		)
+
+		confirmed, err := confirm(selectors, "Restart the service?", "restart the service")
+		if err != nil || !confirmed {
+			return err
+		}
+
+		return runUpdate(context.Background(), restartSelection(*cluster, *service), awsClient, waitTimeoutFlag)
+	},
+}
+
+// restartSelection keeps the configuration of the service, forcing its tasks
+// to be replaced. The desired count is left as is, it may have changed since
+// the service was described.
+func restartSelection(cluster types.Cluster, service types.Service) UpdateSelection {
+	return UpdateSelection{
+		cluster: cluster,
+		service: service,
+		serviceConfig: client.ServiceConfig{
+			TaskDefinitionArn:  *service.TaskDefinition,
+			ForceNewDeployment: true,
+		},
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(restartCmd)
+
+	restartCmd.Flags().
+		DurationVarP(&waitTimeoutFlag, "wait-timeout", "w", 5*time.Minute, "The wait time for the service to become available")
+	restartCmd.Flags().
+		BoolVarP(&yesFlag, "yes", "y", false, "Restart without asking for confirmation")
+}
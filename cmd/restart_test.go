@@ -0,0 +1,24 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ecs/types"
+	"github.com/sestrella/iecs/client"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRestartSelection(t *testing.T) {
+	service := types.Service{
+		TaskDefinition: aws.String(testTaskDefinitionArn + "api:5"),
+		DesiredCount:   3,
+	}
+
+	selection := restartSelection(types.Cluster{}, service)
+
+	assert.Equal(t, client.ServiceConfig{
+		TaskDefinitionArn:  testTaskDefinitionArn + "api:5",
+		ForceNewDeployment: true,
+	}, selection.serviceConfig)
+}
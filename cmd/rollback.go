@@ -0,0 +1,192 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/ecs/types"
+	"github.com/sestrella/iecs/client"
+	"github.com/spf13/cobra"
+)
+
+var rollbackCmd = &cobra.Command{
+	Use:   "rollback",
+	Short: "Roll a service back to its previous task definition",
+	Long: `Updates the service to the task definition it was running before the
+current one. While a deployment is in progress, that is the task definition of
+the deployment being replaced, otherwise the task definition of the most recent
+successful deployment that differs from the current one, taken from the service
+deployment history. Without such a deployment, the next lower active revision
+of the task definition family is used.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		awsClient, err := newClient(context.Background())
+		if err != nil {
+			return err
+		}
+
+		selectors := newSelectors(awsClient)
+
+		cluster, err := selectors.Cluster(context.Background(), clusterFilter)
+		if err != nil {
+			return err
+		}
+
+		service, err := selectors.Service(context.Background(), cluster, serviceFilter)
+		if err != nil {
+			return err
+		}
+
+		clusterClient, err := client.ForCluster(awsClient, *cluster.ClusterArn)
+		if err != nil {
+			return err
+		}
+
+		taskDefinitionArn, err := previousTaskDefinition(context.Background(), clusterClient, service)
+		if err != nil {
+			return err
+		}
+
+		selection := UpdateSelection{
+			cluster: *cluster,
+			service: *service,
+			serviceConfig: client.ServiceConfig{
+				TaskDefinitionArn: taskDefinitionArn,
+			},
+		}
+
+		err = printUpdatePlan(context.Background(), decorationOutput(), awsClient, selection)
+		if err != nil {
+			return err
+		}
+
+		confirmed, err := confirmUpdate(selectors)
+		if err != nil || !confirmed {
+			return err
+		}
+
+		return runUpdate(context.Background(), selection, awsClient, waitTimeoutFlag)
+	},
+}
+
+// previousTaskDefinition returns the task definition the service ran before
+// the current one. Deployments being replaced are preferred, as they hold the
+// revision that is still running. Otherwise, the task definition of the most
+// recent successful deployment that differs from the current one is used, so
+// rolling back twice returns to where it started. Services without such a
+// deployment fall back to the next lower active revision of the same family.
+func previousTaskDefinition(
+	ctx context.Context,
+	client client.Client,
+	service *types.Service,
+) (string, error) {
+	var previous *types.Deployment
+	for i, deployment := range service.Deployments {
+		if deployment.Status == nil || *deployment.Status != "ACTIVE" ||
+			deployment.TaskDefinition == nil || *deployment.TaskDefinition == *service.TaskDefinition {
+			continue
+		}
+		if previous == nil || deployment.CreatedAt != nil && previous.CreatedAt != nil &&
+			deployment.CreatedAt.After(*previous.CreatedAt) {
+			previous = &service.Deployments[i]
+		}
+	}
+	if previous != nil {
+		return *previous.TaskDefinition, nil
+	}
+
+	serviceDeployments, err := client.ListServiceDeployments(
+		ctx,
+		*service.ClusterArn,
+		*service.ServiceArn,
+		[]types.ServiceDeploymentStatus{types.ServiceDeploymentStatusSuccessful},
+	)
+	if err != nil {
+		return "", err
+	}
+
+	var serviceRevisionArns []string
+	for _, serviceDeployment := range serviceDeployments {
+		if serviceDeployment.TargetServiceRevisionArn != nil {
+			serviceRevisionArns = append(serviceRevisionArns, *serviceDeployment.TargetServiceRevisionArn)
+		}
+	}
+
+	serviceRevisions, err := client.DescribeServiceRevisions(ctx, serviceRevisionArns)
+	if err != nil {
+		return "", err
+	}
+
+	taskDefinitions := map[string]string{}
+	for _, serviceRevision := range serviceRevisions {
+		if serviceRevision.ServiceRevisionArn != nil && serviceRevision.TaskDefinition != nil {
+			taskDefinitions[*serviceRevision.ServiceRevisionArn] = *serviceRevision.TaskDefinition
+		}
+	}
+
+	// Deployments are listed newest first
+	for _, serviceRevisionArn := range serviceRevisionArns {
+		taskDefinitionArn, ok := taskDefinitions[serviceRevisionArn]
+		if ok && taskDefinitionArn != *service.TaskDefinition {
+			return taskDefinitionArn, nil
+		}
+	}
+
+	family, revision, err := parseTaskDefinitionArn(*service.TaskDefinition)
+	if err != nil {
+		return "", err
+	}
+
+	taskDefinitionArns, err := client.ListTaskDefinitions(ctx, family)
+	if err != nil {
+		return "", err
+	}
+
+	// Revisions are listed in descending order
+	for _, taskDefinitionArn := range taskDefinitionArns {
+		candidateFamily, candidateRevision, err := parseTaskDefinitionArn(taskDefinitionArn)
+		if err != nil {
+			return "", err
+		}
+		// The family is used as a prefix, so other families may be listed
+		if candidateFamily == family && candidateRevision < revision {
+			return taskDefinitionArn, nil
+		}
+	}
+
+	return "", fmt.Errorf(
+		"no previous deployment of %s and no active revision of %s older than %d",
+		*service.ServiceName,
+		family,
+		revision,
+	)
+}
+
+// parseTaskDefinitionArn returns the family and revision of a task definition
+// ARN.
+func parseTaskDefinitionArn(taskDefinitionArn string) (string, int, error) {
+	familyRevision := taskDefinitionArn[strings.LastIndex(taskDefinitionArn, "/")+1:]
+	family, revisionStr, ok := strings.Cut(familyRevision, ":")
+	if !ok {
+		return "", 0, fmt.Errorf("invalid task definition %s", taskDefinitionArn)
+	}
+
+	revision, err := strconv.Atoi(revisionStr)
+	if err != nil {
+		return "", 0, fmt.Errorf("invalid task definition %s: %w", taskDefinitionArn, err)
+	}
+
+	return family, revision, nil
+}
+
+func init() {
+	rootCmd.AddCommand(rollbackCmd)
+
+	rollbackCmd.Flags().
+		DurationVarP(&waitTimeoutFlag, "wait-timeout", "w", 5*time.Minute, "The wait time for the service to become available")
+	rollbackCmd.Flags().
+		BoolVarP(&yesFlag, "yes", "y", false, "Roll back without asking for confirmation")
+	rollbackCmd.Flags().BoolVar(&noColorsFlag, "no-colors", false, "Disable diff coloring")
+}
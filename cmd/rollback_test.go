@@ -0,0 +1,156 @@
+package cmd
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ecs/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testTaskDefinitionArn  = "arn:aws:ecs:us-east-1:123456789012:task-definition/"
+	testServiceRevisionArn = "arn:aws:ecs:us-east-1:123456789012:service-revision/cluster/api/"
+	testClusterArn         = "arn:aws:ecs:us-east-1:123456789012:cluster/cluster"
+	testServiceArn         = "arn:aws:ecs:us-east-1:123456789012:service/cluster/api"
+)
+
+func TestPreviousTaskDefinition_ActiveDeployment(t *testing.T) {
+	service := &types.Service{
+		TaskDefinition: aws.String(testTaskDefinitionArn + "api:5"),
+		Deployments: []types.Deployment{
+			{Status: aws.String("PRIMARY"), TaskDefinition: aws.String(testTaskDefinitionArn + "api:5")},
+			{
+				Status:         aws.String("ACTIVE"),
+				TaskDefinition: aws.String(testTaskDefinitionArn + "api:3"),
+				CreatedAt:      aws.Time(time.Now().Add(-time.Hour)),
+			},
+			{
+				Status:         aws.String("ACTIVE"),
+				TaskDefinition: aws.String(testTaskDefinitionArn + "api:4"),
+				CreatedAt:      aws.Time(time.Now().Add(-time.Minute)),
+			},
+		},
+	}
+
+	taskDefinitionArn, err := previousTaskDefinition(context.Background(), new(MockClient), service)
+
+	require.NoError(t, err)
+	assert.Equal(t, testTaskDefinitionArn+"api:4", taskDefinitionArn)
+}
+
+func TestPreviousTaskDefinition_DeploymentHistory(t *testing.T) {
+	mockClient := new(MockClient)
+	mockClient.On(
+		"ListServiceDeployments",
+		mock.Anything,
+		testClusterArn,
+		testServiceArn,
+		[]types.ServiceDeploymentStatus{types.ServiceDeploymentStatusSuccessful},
+	).Return([]types.ServiceDeploymentBrief{
+		{TargetServiceRevisionArn: aws.String(testServiceRevisionArn + "3")},
+		{TargetServiceRevisionArn: aws.String(testServiceRevisionArn + "2")},
+		{TargetServiceRevisionArn: aws.String(testServiceRevisionArn + "1")},
+	}, nil)
+	mockClient.On("DescribeServiceRevisions", mock.Anything, []string{
+		testServiceRevisionArn + "3",
+		testServiceRevisionArn + "2",
+		testServiceRevisionArn + "1",
+	}).Return([]types.ServiceRevision{
+		{
+			ServiceRevisionArn: aws.String(testServiceRevisionArn + "1"),
+			TaskDefinition:     aws.String(testTaskDefinitionArn + "api:2"),
+		},
+		{
+			ServiceRevisionArn: aws.String(testServiceRevisionArn + "2"),
+			TaskDefinition:     aws.String(testTaskDefinitionArn + "api:7"),
+		},
+		{
+			ServiceRevisionArn: aws.String(testServiceRevisionArn + "3"),
+			TaskDefinition:     aws.String(testTaskDefinitionArn + "api:9"),
+		},
+	}, nil)
+	service := &types.Service{
+		ClusterArn:     aws.String(testClusterArn),
+		ServiceArn:     aws.String(testServiceArn),
+		ServiceName:    aws.String("api"),
+		TaskDefinition: aws.String(testTaskDefinitionArn + "api:9"),
+		Deployments: []types.Deployment{
+			{Status: aws.String("PRIMARY"), TaskDefinition: aws.String(testTaskDefinitionArn + "api:9")},
+		},
+	}
+
+	taskDefinitionArn, err := previousTaskDefinition(context.Background(), mockClient, service)
+
+	require.NoError(t, err)
+	assert.Equal(t, testTaskDefinitionArn+"api:7", taskDefinitionArn)
+}
+
+func TestPreviousTaskDefinition_LowerRevision(t *testing.T) {
+	mockClient := new(MockClient)
+	mockClient.On("ListServiceDeployments", mock.Anything, testClusterArn, testServiceArn, mock.Anything).
+		Return([]types.ServiceDeploymentBrief{
+			{TargetServiceRevisionArn: aws.String(testServiceRevisionArn + "1")},
+		}, nil)
+	mockClient.On("DescribeServiceRevisions", mock.Anything, []string{testServiceRevisionArn + "1"}).
+		Return([]types.ServiceRevision{
+			{
+				ServiceRevisionArn: aws.String(testServiceRevisionArn + "1"),
+				TaskDefinition:     aws.String(testTaskDefinitionArn + "api:5"),
+			},
+		}, nil)
+	mockClient.On("ListTaskDefinitions", mock.Anything, "api").Return([]string{
+		testTaskDefinitionArn + "api-worker:9",
+		testTaskDefinitionArn + "api:5",
+		testTaskDefinitionArn + "api:2",
+		testTaskDefinitionArn + "api:1",
+	}, nil)
+	service := &types.Service{
+		ClusterArn:     aws.String(testClusterArn),
+		ServiceArn:     aws.String(testServiceArn),
+		ServiceName:    aws.String("api"),
+		TaskDefinition: aws.String(testTaskDefinitionArn + "api:5"),
+		Deployments: []types.Deployment{
+			{Status: aws.String("PRIMARY"), TaskDefinition: aws.String(testTaskDefinitionArn + "api:5")},
+		},
+	}
+
+	taskDefinitionArn, err := previousTaskDefinition(context.Background(), mockClient, service)
+
+	require.NoError(t, err)
+	assert.Equal(t, testTaskDefinitionArn+"api:2", taskDefinitionArn)
+}
+
+func TestPreviousTaskDefinition_FirstRevision(t *testing.T) {
+	mockClient := new(MockClient)
+	mockClient.On("ListServiceDeployments", mock.Anything, testClusterArn, testServiceArn, mock.Anything).
+		Return([]types.ServiceDeploymentBrief{}, nil)
+	mockClient.On("DescribeServiceRevisions", mock.Anything, []string(nil)).
+		Return([]types.ServiceRevision{}, nil)
+	mockClient.On("ListTaskDefinitions", mock.Anything, "api").
+		Return([]string{testTaskDefinitionArn + "api:1"}, nil)
+	service := &types.Service{
+		ClusterArn:     aws.String(testClusterArn),
+		ServiceArn:     aws.String(testServiceArn),
+		ServiceName:    aws.String("api"),
+		TaskDefinition: aws.String(testTaskDefinitionArn + "api:1"),
+	}
+
+	_, err := previousTaskDefinition(context.Background(), mockClient, service)
+
+	assert.EqualError(t, err, "no previous deployment of api and no active revision of api older than 1")
+}
+
+func TestParseTaskDefinitionArn(t *testing.T) {
+	family, revision, err := parseTaskDefinitionArn(testTaskDefinitionArn + "api:12")
+	require.NoError(t, err)
+	assert.Equal(t, "api", family)
+	assert.Equal(t, 12, revision)
+
+	_, _, err = parseTaskDefinitionArn(testTaskDefinitionArn + "api")
+	assert.Error(t, err)
+}
@@ -0,0 +1,268 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/ecs/types"
+	"github.com/fatih/color"
+	"github.com/sestrella/iecs/client"
+)
+
+const (
+	rolloutPollInterval = 5 * time.Second
+	rolloutEventsLimit  = 5
+)
+
+var errRolloutTimeout = errors.New("timed out waiting for the deployment to complete")
+
+var (
+	rolloutSucceeded = color.New(color.FgGreen, color.Bold)
+	rolloutFailed    = color.New(color.FgRed, color.Bold)
+)
+
+// rolloutWatcher follows a service deployment by polling DescribeServices
+// until the deployment started by the update completes or fails.
+type rolloutWatcher struct {
+	client       client.Client
+	output       *frameWriter
+	interval     time.Duration
+	deploymentId string
+	startedAt    time.Time
+}
+
+// watchRollout waits for the deployment started by an update of the service
+// to complete, rendering its progress to w. It fails when the deployment fails
+// or does not complete before timeout.
+func watchRollout(
+	ctx context.Context,
+	client client.Client,
+	service *types.Service,
+	timeout time.Duration,
+	w io.Writer,
+) (*types.Service, error) {
+	watcher := rolloutWatcher{
+		client:    client,
+		output:    newFrameWriter(w),
+		interval:  rolloutPollInterval,
+		startedAt: time.Now(),
+	}
+	if deployment := primaryDeployment(service); deployment != nil && deployment.Id != nil {
+		watcher.deploymentId = *deployment.Id
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	return watcher.run(ctx, service)
+}
+
+func (r rolloutWatcher) run(ctx context.Context, service *types.Service) (*types.Service, error) {
+	for {
+		services, err := r.client.DescribeServices(ctx, *service.ClusterArn, []string{*service.ServiceArn})
+		if err != nil {
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				return nil, r.failed(errRolloutTimeout)
+			}
+			return nil, err
+		}
+		if len(services) == 0 {
+			return nil, fmt.Errorf("service %s not found", *service.ServiceArn)
+		}
+		service = &services[0]
+
+		r.output.render(r.frame(service))
+
+		done, err := r.done(service)
+		if err != nil {
+			return service, r.failed(err)
+		}
+		if done {
+			rolloutSucceeded.Fprintf(
+				r.output.w,
+				"Deployment completed: %s, %d/%d tasks running\n",
+				shortTaskDefinition(service.TaskDefinition),
+				service.RunningCount,
+				service.DesiredCount,
+			)
+			return service, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return service, r.failed(errRolloutTimeout)
+		case <-time.After(r.interval):
+		}
+	}
+}
+
+func (r rolloutWatcher) failed(err error) error {
+	rolloutFailed.Fprintf(r.output.w, "Deployment failed: %s\n", err)
+	return fmt.Errorf("deployment failed: %w", err)
+}
+
+// done reports whether the deployment being watched has completed, failing if
+// it was rolled back or replaced.
+func (r rolloutWatcher) done(service *types.Service) (bool, error) {
+	if r.deploymentId == "" {
+		// Services using an external deployment controller report no
+		// deployments, so fall back to comparing the task counts
+		return len(service.Deployments) <= 1 &&
+			service.RunningCount == service.DesiredCount &&
+			service.PendingCount == 0, nil
+	}
+
+	index := slices.IndexFunc(service.Deployments, func(deployment types.Deployment) bool {
+		return deployment.Id != nil && *deployment.Id == r.deploymentId
+	})
+	if index == -1 {
+		return false, fmt.Errorf("deployment %s was replaced", r.deploymentId)
+	}
+	deployment := service.Deployments[index]
+
+	switch deployment.RolloutState {
+	case types.DeploymentRolloutStateFailed:
+		reason := "rollout failed"
+		if deployment.RolloutStateReason != nil {
+			reason = *deployment.RolloutStateReason
+		}
+		return false, fmt.Errorf("%s", reason)
+	case types.DeploymentRolloutStateCompleted:
+		return true, nil
+	}
+
+	if deployment.Status != nil && *deployment.Status != "PRIMARY" {
+		return false, fmt.Errorf("deployment %s was replaced", r.deploymentId)
+	}
+
+	// Deployments without a rollout state complete once the previous ones
+	// are drained and all tasks are running
+	return deployment.RolloutState == "" &&
+		len(service.Deployments) == 1 &&
+		deployment.RunningCount == deployment.DesiredCount &&
+		deployment.PendingCount == 0, nil
+}
+
+func (r rolloutWatcher) frame(service *types.Service) string {
+	var b strings.Builder
+
+	fmt.Fprintf(
+		&b,
+		"%s %s (%d/%d running, %d pending)\n",
+		titleStyle.Render("Service:"),
+		*service.ServiceArn,
+		service.RunningCount,
+		service.DesiredCount,
+		service.PendingCount,
+	)
+
+	fmt.Fprintln(&b, titleStyle.Render("Deployments:"))
+	for _, deployment := range service.Deployments {
+		fmt.Fprintf(
+			&b,
+			"  %-7s %s %s %d/%d running, %d pending, %d failed\n",
+			stringValue(deployment.Status),
+			shortTaskDefinition(deployment.TaskDefinition),
+			rolloutState(deployment),
+			deployment.RunningCount,
+			deployment.DesiredCount,
+			deployment.PendingCount,
+			deployment.FailedTasks,
+		)
+	}
+
+	var events []types.ServiceEvent
+	for _, event := range service.Events {
+		if event.CreatedAt != nil && event.CreatedAt.Before(r.startedAt) {
+			continue
+		}
+		events = append(events, event)
+		if len(events) == rolloutEventsLimit {
+			break
+		}
+	}
+	if len(events) > 0 {
+		fmt.Fprintln(&b, titleStyle.Render("Events:"))
+		// ECS returns the newest events first
+		for i := len(events) - 1; i >= 0; i-- {
+			fmt.Fprintf(
+				&b,
+				"  %s %s\n",
+				events[i].CreatedAt.Local().Format(time.TimeOnly),
+				stringValue(events[i].Message),
+			)
+		}
+	}
+
+	return b.String()
+}
+
+func primaryDeployment(service *types.Service) *types.Deployment {
+	for i, deployment := range service.Deployments {
+		if deployment.Status != nil && *deployment.Status == "PRIMARY" {
+			return &service.Deployments[i]
+		}
+	}
+	return nil
+}
+
+func rolloutState(deployment types.Deployment) string {
+	if deployment.RolloutState == "" {
+		return "-"
+	}
+	return string(deployment.RolloutState)
+}
+
+// shortTaskDefinition returns the family:revision part of a task definition
+// ARN.
+func shortTaskDefinition(taskDefinitionArn *string) string {
+	if taskDefinitionArn == nil {
+		return "-"
+	}
+	return (*taskDefinitionArn)[strings.LastIndex(*taskDefinitionArn, "/")+1:]
+}
+
+func stringValue(value *string) string {
+	if value == nil {
+		return ""
+	}
+	return *value
+}
+
+// frameWriter redraws a multi-line frame in place when writing to a terminal.
+// Otherwise, frames are only written when they change.
+type frameWriter struct {
+	w        io.Writer
+	terminal bool
+	lines    int
+	last     string
+}
+
+func newFrameWriter(w io.Writer) *frameWriter {
+	terminal := false
+	if file, ok := w.(*os.File); ok {
+		if info, err := file.Stat(); err == nil {
+			terminal = info.Mode()&os.ModeCharDevice != 0
+		}
+	}
+	return &frameWriter{w: w, terminal: terminal}
+}
+
+func (f *frameWriter) render(frame string) {
+	if frame == f.last {
+		return
+	}
+	if f.terminal && f.lines > 0 {
+		// Move the cursor to the start of the previous frame and clear it
+		fmt.Fprintf(f.w, "\x1b[%dA\x1b[J", f.lines)
+	}
+	fmt.Fprint(f.w, frame)
+	f.lines = strings.Count(frame, "\n")
+	f.last = frame
+}
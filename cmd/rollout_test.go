@@ -0,0 +1,151 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ecs/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func rolloutService(deployments ...types.Deployment) types.Service {
+	service := types.Service{
+		ClusterArn:     aws.String("arn:aws:ecs:us-east-1:123456789012:cluster/my-cluster"),
+		ServiceArn:     aws.String("arn:aws:ecs:us-east-1:123456789012:service/my-cluster/api"),
+		TaskDefinition: aws.String("arn:aws:ecs:us-east-1:123456789012:task-definition/api:2"),
+		DesiredCount:   2,
+		Deployments:    deployments,
+	}
+	for _, deployment := range deployments {
+		service.RunningCount += deployment.RunningCount
+		service.PendingCount += deployment.PendingCount
+	}
+	return service
+}
+
+func rolloutDeployment(
+	id string,
+	status string,
+	revision string,
+	state types.DeploymentRolloutState,
+	running int32,
+) types.Deployment {
+	return types.Deployment{
+		Id:             aws.String(id),
+		Status:         aws.String(status),
+		TaskDefinition: aws.String("arn:aws:ecs:us-east-1:123456789012:task-definition/api:" + revision),
+		RolloutState:   state,
+		DesiredCount:   2,
+		RunningCount:   running,
+	}
+}
+
+func newTestRolloutWatcher(mockClient *MockClient, output *bytes.Buffer) rolloutWatcher {
+	return rolloutWatcher{
+		client:       mockClient,
+		output:       newFrameWriter(output),
+		deploymentId: "ecs-svc/2",
+		startedAt:    time.Now().Add(-time.Minute),
+	}
+}
+
+func TestRolloutWatcher_Completed(t *testing.T) {
+	mockClient := new(MockClient)
+	inProgress := rolloutService(
+		rolloutDeployment("ecs-svc/2", "PRIMARY", "2", types.DeploymentRolloutStateInProgress, 1),
+		rolloutDeployment("ecs-svc/1", "ACTIVE", "1", types.DeploymentRolloutStateCompleted, 2),
+	)
+	inProgress.Events = []types.ServiceEvent{
+		{CreatedAt: aws.Time(time.Now()), Message: aws.String("(service api) has started 1 tasks")},
+		{CreatedAt: aws.Time(time.Now().Add(-time.Hour)), Message: aws.String("(service api) has reached a steady state.")},
+	}
+	completed := rolloutService(
+		rolloutDeployment("ecs-svc/2", "PRIMARY", "2", types.DeploymentRolloutStateCompleted, 2),
+	)
+	mockClient.On("DescribeServices", mock.Anything, *inProgress.ClusterArn, []string{*inProgress.ServiceArn}).
+		Return([]types.Service{inProgress}, nil).
+		Once()
+	mockClient.On("DescribeServices", mock.Anything, *inProgress.ClusterArn, []string{*inProgress.ServiceArn}).
+		Return([]types.Service{completed}, nil).
+		Once()
+
+	var output bytes.Buffer
+	service, err := newTestRolloutWatcher(mockClient, &output).run(context.Background(), &inProgress)
+
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), service.RunningCount)
+	assert.Contains(t, output.String(), "  PRIMARY api:2 IN_PROGRESS 1/2 running, 0 pending, 0 failed\n")
+	assert.Contains(t, output.String(), "  ACTIVE  api:1 COMPLETED 2/2 running, 0 pending, 0 failed\n")
+	assert.Contains(t, output.String(), "(service api) has started 1 tasks\n")
+	assert.NotContains(t, output.String(), "steady state")
+	assert.Contains(t, output.String(), "Deployment completed: api:2, 2/2 tasks running\n")
+	mockClient.AssertExpectations(t)
+}
+
+func TestRolloutWatcher_Failed(t *testing.T) {
+	mockClient := new(MockClient)
+	failed := rolloutDeployment("ecs-svc/2", "ACTIVE", "2", types.DeploymentRolloutStateFailed, 0)
+	failed.FailedTasks = 3
+	failed.RolloutStateReason = aws.String("ECS deployment circuit breaker: tasks failed to start.")
+	service := rolloutService(
+		rolloutDeployment("ecs-svc/3", "PRIMARY", "1", types.DeploymentRolloutStateInProgress, 2),
+		failed,
+	)
+	mockClient.On("DescribeServices", mock.Anything, *service.ClusterArn, []string{*service.ServiceArn}).
+		Return([]types.Service{service}, nil)
+
+	var output bytes.Buffer
+	_, err := newTestRolloutWatcher(mockClient, &output).run(context.Background(), &service)
+
+	assert.EqualError(t, err, "deployment failed: ECS deployment circuit breaker: tasks failed to start.")
+	assert.Contains(t, output.String(), "3 failed\n")
+	assert.Contains(t, output.String(), "Deployment failed: ECS deployment circuit breaker")
+}
+
+func TestRolloutWatcher_Timeout(t *testing.T) {
+	mockClient := new(MockClient)
+	service := rolloutService(
+		rolloutDeployment("ecs-svc/2", "PRIMARY", "2", types.DeploymentRolloutStateInProgress, 0),
+	)
+	mockClient.On("DescribeServices", mock.Anything, *service.ClusterArn, []string{*service.ServiceArn}).
+		Return([]types.Service{service}, nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	var output bytes.Buffer
+	watcher := newTestRolloutWatcher(mockClient, &output)
+	watcher.interval = time.Millisecond
+	_, err := watcher.run(ctx, &service)
+
+	assert.ErrorIs(t, err, errRolloutTimeout)
+	assert.Equal(t, 1, bytes.Count(output.Bytes(), []byte("Deployments:")))
+}
+
+func TestRolloutWatcher_WithoutDeployments(t *testing.T) {
+	watcher := rolloutWatcher{}
+	service := rolloutService()
+
+	done, err := watcher.done(&service)
+	require.NoError(t, err)
+	assert.False(t, done)
+
+	service.RunningCount = 2
+	done, err = watcher.done(&service)
+	require.NoError(t, err)
+	assert.True(t, done)
+}
+
+func TestShortTaskDefinition(t *testing.T) {
+	assert.Equal(
+		t,
+		"api:2",
+		shortTaskDefinition(aws.String("arn:aws:ecs:us-east-1:123456789012:task-definition/api:2")),
+	)
+	assert.Equal(t, "-", shortTaskDefinition(nil))
+}
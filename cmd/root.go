@@ -2,11 +2,15 @@ package cmd
 
 import (
 	_ "embed"
+	"errors"
 	"fmt"
-	"regexp"
+	"slices"
 	"strings"
 
 	"github.com/charmbracelet/huh"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/sestrella/iecs/client"
+	"github.com/sestrella/iecs/selector"
 	"github.com/spf13/cobra"
 )
 
@@ -15,11 +19,26 @@ var (
 	themeStr        string
 	theme           *huh.Theme
 	clusterStr      string
-	clusterRegex    *regexp.Regexp
+	clusterFilter   *selector.Filter
 	serviceStr      string
-	serviceRegex    *regexp.Regexp
+	serviceFilter   *selector.Filter
+	taskStr         string
+	taskFilter      *selector.Filter
+	containerStr    string
+	containerFilter *selector.Filter
+	nonInteractive  bool
+	outputFormat    string
+	configPath      string
+	contextName     string
+	configFile      *ConfigFile
+	awsProfile      string
+	awsRegion       string
+	awsProfiles     []string
+	awsRegions      []string
 )
 
+var titleStyle = lipgloss.NewStyle().Bold(true)
+
 var themes = map[string]*huh.Theme{
 	"base":       huh.ThemeBase(),
 	"base16":     huh.ThemeBase16(),
@@ -33,23 +52,80 @@ var rootCmd = &cobra.Command{
 	Short: "An interactive CLI for ECS",
 	Long:  "Performs commons tasks on ECS, such as getting remote access or viewing logs",
 	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		var err error
+		configRequired := configPath != ""
+		if !configRequired {
+			if configPath, err = defaultConfigPath(); err != nil {
+				return err
+			}
+		}
+		if configFile, err = loadConfigFile(configPath, configRequired); err != nil {
+			return err
+		}
+
+		// The context commands don't use the selected context, and must keep
+		// working to fix a current context that no longer exists
+		selectedContext := &Context{}
+		if !managesContexts(cmd) {
+			if selectedContext, err = configFile.lookupContext(contextName); err != nil {
+				return err
+			}
+		}
+		if err = applyConfigFile(cmd, configFile, selectedContext); err != nil {
+			return err
+		}
+
 		if selectedTheme, ok := themes[themeStr]; ok {
 			theme = selectedTheme
 		} else {
 			return fmt.Errorf("unsupported theme \"%s\" expecting one of: %s", themeStr, availableThemes)
 		}
 
-		if clusterStr != "" {
-			clusterRegex = regexp.MustCompile(clusterStr)
+		if !slices.Contains(outputFormats, outputFormat) {
+			return fmt.Errorf(
+				"unsupported output \"%s\" expecting one of: %s",
+				outputFormat,
+				strings.Join(outputFormats, ", "),
+			)
+		}
+
+		if clusterFilter, err = selector.NewFilter(clusterStr); err != nil {
+			return fmt.Errorf("invalid cluster \"%s\": %w", clusterStr, err)
+		}
+
+		if serviceFilter, err = selector.NewFilter(serviceStr); err != nil {
+			return fmt.Errorf("invalid service \"%s\": %w", serviceStr, err)
+		}
+
+		if taskFilter, err = selector.NewFilter(taskStr); err != nil {
+			return fmt.Errorf("invalid task \"%s\": %w", taskStr, err)
 		}
 
-		if serviceStr != "" {
-			serviceRegex = regexp.MustCompile(serviceStr)
+		if containerFilter, err = selector.NewFilter(containerStr); err != nil {
+			return fmt.Errorf("invalid container \"%s\": %w", containerStr, err)
 		}
 
 		return nil
 	},
 	SilenceUsage: true,
+	// Errors are printed by Execute
+	SilenceErrors: true,
+}
+
+// ExitError makes iecs exit with the given code, such as the exit code of a
+// remote command. Without a message, nothing is printed.
+type ExitError struct {
+	Code    int
+	Message string
+}
+
+func (e *ExitError) Error() string {
+	return e.Message
+}
+
+// newSelectors returns the selectors configured by the global flags.
+func newSelectors(client client.Client) selector.Selectors {
+	return selector.NewSelectors(client, *theme, nonInteractive, decorationOutput())
 }
 
 func Execute(version string) error {
@@ -70,12 +146,44 @@ func Execute(version string) error {
 			),
 		)
 	rootCmd.PersistentFlags().
-		StringVar(&clusterStr, "cluster", "", "A regex pattern for filtering clusters")
+		StringVar(&clusterStr, "cluster", "", "The cluster name, ARN or a regex pattern for filtering clusters")
+	rootCmd.PersistentFlags().
+		StringVar(&serviceStr, "service", "", "The service name, ARN or a regex pattern for filtering services")
 	rootCmd.PersistentFlags().
-		StringVar(&serviceStr, "service", "", "A regex pattern for filtering services")
+		StringVar(&taskStr, "task", "", "The task ID, ARN or a regex pattern for filtering tasks")
+	rootCmd.PersistentFlags().
+		StringVar(&containerStr, "container", "", "The container name or a regex pattern for filtering containers")
+	rootCmd.PersistentFlags().
+		BoolVar(&nonInteractive, "non-interactive", false, "Fail instead of prompting when more than one resource matches")
+	rootCmd.PersistentFlags().
+		StringVarP(
+			&outputFormat,
+			"output",
+			"o",
+			outputTable,
+			fmt.Sprintf("The output format. Available formats are: %s", strings.Join(outputFormats, ", ")),
+		)
+	rootCmd.PersistentFlags().
+		StringVar(&configPath, "config", "", "The config file to use (default $XDG_CONFIG_HOME/iecs/config.yaml)")
+	rootCmd.PersistentFlags().
+		StringVar(&contextName, "context", "", "The context from the config file to use instead of the current one")
+	rootCmd.PersistentFlags().
+		StringVar(&awsProfile, "profile", "", "The AWS profile to use instead of picking one from the shared config")
+	rootCmd.PersistentFlags().
+		StringVar(&awsRegion, "region", "", "The AWS region to use instead of the one configured for the profile")
+	rootCmd.PersistentFlags().
+		StringSliceVar(&awsProfiles, "profiles", nil, "The AWS profiles to look for clusters in")
+	rootCmd.PersistentFlags().
+		StringSliceVar(&awsRegions, "regions", nil, "The AWS regions to look for clusters in")
 	rootCmd.Version = version
 
 	if err := rootCmd.Execute(); err != nil {
+		// An exit code without a message, such as the one of a session, is
+		// all there is to report
+		var exitErr *ExitError
+		if !errors.As(err, &exitErr) || exitErr.Message != "" {
+			rootCmd.PrintErrln(rootCmd.ErrPrefix(), err.Error())
+		}
 		return err
 	}
 
@@ -0,0 +1,341 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/ecs/types"
+	"github.com/sestrella/iecs/client"
+	"github.com/spf13/cobra"
+)
+
+const (
+	runPollInterval = 5 * time.Second
+	// runLogsFlushDelay gives the last log events of a stopped task time to
+	// be delivered before the logs stop being tailed.
+	runLogsFlushDelay = 5 * time.Second
+)
+
+var runCmd = &cobra.Command{
+	Use:   "run [flags] [-- command...]",
+	Short: "Run a one-off task from the task definition of a service",
+	Long: `Starts a standalone task with the task definition, network configuration
+and launch type or capacity provider strategy of a service. The command and the
+environment of the selected container can be overridden. The logs of the
+container are streamed until the task stops, and iecs exits with the exit code
+of the container.`,
+	Example: `
+  iecs run -- bin/rails db:migrate
+  iecs run --container app --env RAILS_ENV=production --env worker:QUEUE=admin -- bin/rake reindex
+  `,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		envValues, err := cmd.Flags().GetStringArray("env")
+		if err != nil {
+			return err
+		}
+
+		client, err := newClient(context.Background())
+		if err != nil {
+			return err
+		}
+
+		selectors := newSelectors(client)
+
+		cluster, err := selectors.Cluster(context.Background(), clusterFilter)
+		if err != nil {
+			return err
+		}
+
+		service, err := selectors.Service(context.Background(), cluster, serviceFilter)
+		if err != nil {
+			return err
+		}
+
+		taskDefinition, err := client.DescribeTaskDefinition(context.Background(), *service.TaskDefinition)
+		if err != nil {
+			return err
+		}
+
+		container, err := selectors.ContainerDefinition(
+			context.Background(),
+			taskDefinition.ContainerDefinitions,
+			containerFilter,
+		)
+		if err != nil {
+			return err
+		}
+
+		overrides, err := runOverrides(taskDefinition, *container.Name, args, envValues)
+		if err != nil {
+			return err
+		}
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+
+		return runTask(
+			ctx,
+			decorationOutput(),
+			client,
+			service,
+			*container,
+			overrides,
+			runPollInterval,
+			runLogsFlushDelay,
+		)
+	},
+}
+
+// RunOutput is the machine-readable form of a stopped one-off task.
+type RunOutput struct {
+	Cluster       string `json:"cluster" yaml:"cluster"`
+	Task          string `json:"task" yaml:"task"`
+	Container     string `json:"container" yaml:"container"`
+	ExitCode      *int32 `json:"exitCode" yaml:"exitCode"`
+	StoppedReason string `json:"stoppedReason,omitempty" yaml:"stoppedReason,omitempty"`
+}
+
+// runOverrides returns the overrides of the task. The command applies to the
+// given container, as do the environment variables given as KEY=VALUE;
+// container:KEY=VALUE sets a variable on another container.
+func runOverrides(
+	taskDefinition *types.TaskDefinition,
+	containerName string,
+	command []string,
+	envValues []string,
+) ([]types.ContainerOverride, error) {
+	var overrides []types.ContainerOverride
+	override := func(name string) (*types.ContainerOverride, error) {
+		index := slices.IndexFunc(overrides, func(override types.ContainerOverride) bool {
+			return *override.Name == name
+		})
+		if index >= 0 {
+			return &overrides[index], nil
+		}
+		known := slices.ContainsFunc(
+			taskDefinition.ContainerDefinitions,
+			func(containerDefinition types.ContainerDefinition) bool {
+				return *containerDefinition.Name == name
+			},
+		)
+		if !known {
+			return nil, fmt.Errorf("unknown container %s", name)
+		}
+		overrides = append(overrides, types.ContainerOverride{Name: &name})
+		return &overrides[len(overrides)-1], nil
+	}
+
+	if len(command) > 0 {
+		containerOverride, err := override(containerName)
+		if err != nil {
+			return nil, err
+		}
+		containerOverride.Command = command
+	}
+
+	for _, value := range envValues {
+		key, envValue, ok := strings.Cut(value, "=")
+		if !ok || key == "" {
+			return nil, fmt.Errorf("invalid --env %s, expecting [container:]KEY=VALUE", value)
+		}
+		name := containerName
+		if container, envKey, ok := strings.Cut(key, ":"); ok {
+			name = container
+			key = envKey
+		}
+		containerOverride, err := override(name)
+		if err != nil {
+			return nil, err
+		}
+		containerOverride.Environment = append(
+			containerOverride.Environment,
+			types.KeyValuePair{Name: &key, Value: &envValue},
+		)
+	}
+
+	return overrides, nil
+}
+
+// runTask starts the task, streams the logs of the container while it runs
+// and waits for it to stop. A non-zero exit code of the container is returned
+// as an ExitError.
+func runTask(
+	ctx context.Context,
+	w io.Writer,
+	awsClient client.Client,
+	service *types.Service,
+	container types.ContainerDefinition,
+	overrides []types.ContainerOverride,
+	interval time.Duration,
+	flushDelay time.Duration,
+) error {
+	// The logs are not tied to an ARN, so they are fetched by the client of
+	// the cluster
+	clusterClient, err := client.ForCluster(awsClient, *service.ClusterArn)
+	if err != nil {
+		return err
+	}
+
+	task, err := awsClient.RunTask(ctx, service, overrides)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "%s %s\n", titleStyle.Render("Started:"), *task.TaskArn)
+
+	watcher := taskWatcher{
+		client:     awsClient,
+		output:     w,
+		clusterArn: *service.ClusterArn,
+		taskArn:    *task.TaskArn,
+		interval:   interval,
+	}
+
+	task, err = watcher.waitFor(ctx, "RUNNING", "STOPPED")
+	if err != nil {
+		return err
+	}
+
+	selection := LogsSelection{
+		cluster:    &types.Cluster{ClusterArn: service.ClusterArn},
+		service:    service,
+		tasks:      []types.Task{*task},
+		containers: []types.ContainerDefinition{container},
+	}
+
+	if stringValue(task.LastStatus) == "STOPPED" {
+		// The task stopped before it could be tailed, only its history is left
+		sleep(ctx, flushDelay)
+		if err := runLogs(ctx, false, clusterClient, selection, LogsOptions{}); err != nil {
+			log.Printf("Unable to fetch the logs: %v", err)
+		}
+	} else {
+		options := LogsOptions{since: time.Now(), follow: true}
+		if task.CreatedAt != nil {
+			options.since = *task.CreatedAt
+		}
+
+		logsCtx, cancelLogs := context.WithCancel(ctx)
+		logsDone := make(chan struct{})
+		go func() {
+			defer close(logsDone)
+			if err := runLogs(logsCtx, false, clusterClient, selection, options); err != nil {
+				log.Printf("Unable to stream the logs: %v", err)
+			}
+		}()
+
+		task, err = watcher.waitFor(ctx, "STOPPED")
+		if err == nil {
+			sleep(ctx, flushDelay)
+		}
+		cancelLogs()
+		<-logsDone
+		if err != nil {
+			return err
+		}
+	}
+
+	return taskExitCode(task, *container.Name)
+}
+
+// taskExitCode reports how the container of the stopped task exited.
+func taskExitCode(task *types.Task, containerName string) error {
+	index := slices.IndexFunc(task.Containers, func(container types.Container) bool {
+		return stringValue(container.Name) == containerName
+	})
+	if index < 0 {
+		return fmt.Errorf("container %s not found in task %s", containerName, *task.TaskArn)
+	}
+	container := task.Containers[index]
+
+	if structuredOutput() {
+		err := printOutput(RunOutput{
+			Cluster:       stringValue(task.ClusterArn),
+			Task:          *task.TaskArn,
+			Container:     containerName,
+			ExitCode:      container.ExitCode,
+			StoppedReason: stringValue(task.StoppedReason),
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	if container.ExitCode == nil {
+		reason := stringValue(container.Reason)
+		if reason == "" {
+			reason = stringValue(task.StoppedReason)
+		}
+		return fmt.Errorf("container %s stopped without an exit code: %s", containerName, reason)
+	}
+	if *container.ExitCode != 0 {
+		return &ExitError{
+			Code:    int(*container.ExitCode),
+			Message: fmt.Sprintf("container %s exited with code %d", containerName, *container.ExitCode),
+		}
+	}
+	return nil
+}
+
+// taskWatcher polls a task, printing its status whenever it changes.
+type taskWatcher struct {
+	client     client.Client
+	output     io.Writer
+	clusterArn string
+	taskArn    string
+	interval   time.Duration
+	lastStatus string
+}
+
+// waitFor returns the task once it reaches one of the given statuses.
+func (w *taskWatcher) waitFor(ctx context.Context, statuses ...string) (*types.Task, error) {
+	for {
+		tasks, err := w.client.DescribeTasks(ctx, w.clusterArn, []string{w.taskArn})
+		if err != nil {
+			return nil, err
+		}
+		if len(tasks) == 0 {
+			return nil, fmt.Errorf("task %s not found", w.taskArn)
+		}
+		task := &tasks[0]
+
+		status := stringValue(task.LastStatus)
+		if status != w.lastStatus {
+			fmt.Fprintf(w.output, "%s %s\n", titleStyle.Render("Status:"), status)
+			w.lastStatus = status
+		}
+		if slices.Contains(statuses, status) {
+			return task, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf(
+				"stopped waiting, task %s keeps running: %w",
+				w.taskArn[strings.LastIndex(w.taskArn, "/")+1:],
+				ctx.Err(),
+			)
+		case <-time.After(w.interval):
+		}
+	}
+}
+
+func sleep(ctx context.Context, duration time.Duration) {
+	select {
+	case <-ctx.Done():
+	case <-time.After(duration):
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(runCmd)
+
+	runCmd.Flags().
+		StringArray("env", nil, "Set an environment variable, as KEY=VALUE or container:KEY=VALUE")
+}
@@ -0,0 +1,167 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	logstypes "github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs/types"
+	"github.com/aws/aws-sdk-go-v2/service/ecs/types"
+	"github.com/sestrella/iecs/client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunOverrides(t *testing.T) {
+	taskDefinition := &types.TaskDefinition{
+		ContainerDefinitions: []types.ContainerDefinition{
+			{Name: aws.String("app")},
+			{Name: aws.String("worker")},
+		},
+	}
+
+	overrides, err := runOverrides(
+		taskDefinition,
+		"app",
+		[]string{"bin/rails", "db:migrate"},
+		[]string{"RAILS_ENV=production", "worker:QUEUE=admin", "DSN=postgres://db?sslmode=require"},
+	)
+
+	require.NoError(t, err)
+	assert.Equal(t, []types.ContainerOverride{
+		{
+			Name:    aws.String("app"),
+			Command: []string{"bin/rails", "db:migrate"},
+			Environment: []types.KeyValuePair{
+				{Name: aws.String("RAILS_ENV"), Value: aws.String("production")},
+				{Name: aws.String("DSN"), Value: aws.String("postgres://db?sslmode=require")},
+			},
+		},
+		{
+			Name: aws.String("worker"),
+			Environment: []types.KeyValuePair{
+				{Name: aws.String("QUEUE"), Value: aws.String("admin")},
+			},
+		},
+	}, overrides)
+}
+
+func TestRunOverrides_Errors(t *testing.T) {
+	taskDefinition := &types.TaskDefinition{
+		ContainerDefinitions: []types.ContainerDefinition{{Name: aws.String("app")}},
+	}
+
+	_, err := runOverrides(taskDefinition, "app", nil, []string{"sidecar:KEY=value"})
+	assert.EqualError(t, err, "unknown container sidecar")
+
+	_, err = runOverrides(taskDefinition, "app", nil, []string{"KEY"})
+	assert.EqualError(t, err, "invalid --env KEY, expecting [container:]KEY=VALUE")
+}
+
+func TestRunTask(t *testing.T) {
+	service := &types.Service{
+		ClusterArn:     aws.String("cluster"),
+		ServiceArn:     aws.String("service"),
+		TaskDefinition: aws.String(testTaskDefinitionArn + "api:3"),
+	}
+	overrides := []types.ContainerOverride{{Name: aws.String("app"), Command: []string{"migrate"}}}
+
+	mockClient := new(MockClient)
+	mockClient.On("RunTask", mock.Anything, service, overrides).
+		Return(&types.Task{TaskArn: aws.String("task"), LastStatus: aws.String("PROVISIONING")}, nil)
+	mockClient.On("DescribeTasks", mock.Anything, "cluster", []string{"task"}).
+		Return([]types.Task{{TaskArn: aws.String("task"), LastStatus: aws.String("PENDING")}}, nil).
+		Once()
+	mockClient.On("DescribeTasks", mock.Anything, "cluster", []string{"task"}).
+		Return([]types.Task{{
+			TaskArn:    aws.String("task"),
+			LastStatus: aws.String("STOPPED"),
+			Containers: []types.Container{{Name: aws.String("app"), ExitCode: aws.Int32(3)}},
+		}}, nil).
+		Once()
+
+	var output bytes.Buffer
+	err := runTask(
+		context.Background(),
+		&output,
+		mockClient,
+		service,
+		types.ContainerDefinition{Name: aws.String("app")},
+		overrides,
+		0,
+		0,
+	)
+
+	var exitErr *ExitError
+	require.ErrorAs(t, err, &exitErr)
+	assert.Equal(t, 3, exitErr.Code)
+	assert.Equal(t, "container app exited with code 3", exitErr.Error())
+	assert.Equal(t, "Started: task\nStatus: PENDING\nStatus: STOPPED\n", output.String())
+	mockClient.AssertExpectations(t)
+}
+
+func TestRunTask_MultiClient(t *testing.T) {
+	clusterArn := "arn:aws:ecs:us-east-1:123456789012:cluster/my-cluster"
+	taskArn := "arn:aws:ecs:us-east-1:123456789012:task/my-cluster/abcd"
+	service := &types.Service{
+		ClusterArn:     aws.String(clusterArn),
+		ServiceArn:     aws.String("arn:aws:ecs:us-east-1:123456789012:service/my-cluster/api"),
+		TaskDefinition: aws.String(testTaskDefinitionArn + "api:3"),
+	}
+	container := types.ContainerDefinition{
+		Name: aws.String("app"),
+		LogConfiguration: &types.LogConfiguration{
+			LogDriver: "awslogs",
+			Options: map[string]string{
+				"awslogs-group":         "/ecs/api",
+				"awslogs-stream-prefix": "ecs",
+			},
+		},
+	}
+	createdAt := time.Now().Add(-time.Minute)
+
+	mockClient := new(MockClient)
+	mockClient.On("ListClusters", mock.Anything).Return([]string{clusterArn}, nil)
+	mockClient.On("RunTask", mock.Anything, service, []types.ContainerOverride(nil)).
+		Return(&types.Task{TaskArn: aws.String(taskArn), LastStatus: aws.String("PROVISIONING")}, nil)
+	mockClient.On("DescribeTasks", mock.Anything, clusterArn, []string{taskArn}).
+		Return([]types.Task{{
+			TaskArn:    aws.String(taskArn),
+			LastStatus: aws.String("STOPPED"),
+			CreatedAt:  &createdAt,
+			Containers: []types.Container{{Name: aws.String("app"), ExitCode: aws.Int32(0)}},
+		}}, nil)
+	mockClient.On("FilterLogEvents", mock.Anything, "/ecs/api", []string{"ecs/app/abcd"}, createdAt, mock.Anything).
+		Return([]logstypes.FilteredLogEvent{}, nil)
+
+	multiClient := client.NewMultiClient([]client.Target{{Profile: "dev", Client: mockClient}})
+	_, err := multiClient.ListClusters(context.Background())
+	require.NoError(t, err)
+
+	var output bytes.Buffer
+	err = runTask(context.Background(), &output, multiClient, service, container, nil, 0, 0)
+
+	assert.NoError(t, err)
+	mockClient.AssertExpectations(t)
+}
+
+func TestTaskExitCode(t *testing.T) {
+	task := &types.Task{
+		TaskArn:       aws.String("task"),
+		StoppedReason: aws.String("Essential container in task exited"),
+		Containers: []types.Container{
+			{Name: aws.String("app"), ExitCode: aws.Int32(0)},
+			{Name: aws.String("worker"), Reason: aws.String("CannotPullContainerError")},
+		},
+	}
+
+	assert.NoError(t, taskExitCode(task, "app"))
+	assert.EqualError(
+		t,
+		taskExitCode(task, "worker"),
+		"container worker stopped without an exit code: CannotPullContainerError",
+	)
+}
@@ -0,0 +1,113 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"sync/atomic"
+	"syscall"
+
+	"github.com/charmbracelet/x/term"
+	"github.com/sestrella/iecs/client"
+	"github.com/sestrella/iecs/session"
+	"github.com/spf13/cobra"
+)
+
+var sessionClientCmd = &cobra.Command{
+	Use:    client.SessionClientCommand,
+	Short:  "Run a session started by ExecuteCommand",
+	Long:   "Runs the session given by the IECS_SESSION environment variable, as session-manager-plugin would.",
+	Hidden: true,
+	Args:   cobra.NoArgs,
+	// The session was started with the configuration of the parent process
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		return nil
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var s session.Session
+		if err := json.Unmarshal([]byte(os.Getenv(session.EnvVar)), &s); err != nil {
+			return fmt.Errorf("invalid %s: %w", session.EnvVar, err)
+		}
+
+		exitCode, err := runSessionClient(context.Background(), s)
+		if errors.Is(err, session.ErrEncryptionNotSupported) {
+			return fmt.Errorf("%w, install session-manager-plugin to run sessions encrypted with KMS", err)
+		}
+		if err != nil {
+			return err
+		}
+		if exitCode != 0 {
+			return &ExitError{Code: exitCode}
+		}
+		return nil
+	},
+}
+
+// runSessionClient attaches the terminal to the session, in raw mode when
+// stdin is a terminal. Interrupts are sent to the remote command as control
+// characters, other termination signals end the session with the exit code of
+// a process killed by them.
+func runSessionClient(ctx context.Context, s session.Session) (int, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	options := session.Options{Stdout: os.Stdout, Stderr: os.Stderr}
+
+	fd := os.Stdin.Fd()
+	if term.IsTerminal(fd) {
+		state, err := term.MakeRaw(fd)
+		if err != nil {
+			return 0, err
+		}
+		defer term.Restore(fd, state)
+
+		resize := make(chan session.Size, 1)
+		notifyResize(ctx, fd, resize)
+		options.Resize = resize
+	}
+
+	input, inputWriter := io.Pipe()
+	options.Stdin = input
+	go func() {
+		_, err := io.Copy(inputWriter, os.Stdin)
+		inputWriter.CloseWithError(err)
+	}()
+
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGHUP, syscall.SIGINT, syscall.SIGQUIT, syscall.SIGTERM)
+	defer signal.Stop(sigs)
+
+	var terminated atomic.Int32
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case sig := <-sigs:
+				switch sig {
+				case syscall.SIGINT:
+					_, _ = inputWriter.Write([]byte{0x03})
+				case syscall.SIGQUIT:
+					_, _ = inputWriter.Write([]byte{0x1c})
+				default:
+					terminated.Store(int32(sig.(syscall.Signal)))
+					cancel()
+				}
+			}
+		}
+	}()
+
+	exitCode, err := session.Run(ctx, s, options)
+	if sig := terminated.Load(); sig != 0 {
+		return 128 + int(sig), nil
+	}
+	return exitCode, err
+}
+
+func init() {
+	rootCmd.AddCommand(sessionClientCmd)
+}
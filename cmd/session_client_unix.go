@@ -0,0 +1,39 @@
+//go:build !windows
+
+package cmd
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/charmbracelet/x/term"
+	"github.com/sestrella/iecs/session"
+)
+
+// notifyResize delivers the size of the terminal, and its new size whenever
+// it changes, until the context is done.
+func notifyResize(ctx context.Context, fd uintptr, resize chan<- session.Size) {
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGWINCH)
+
+	go func() {
+		defer signal.Stop(sigs)
+		for {
+			if cols, rows, err := term.GetSize(fd); err == nil {
+				select {
+				case resize <- session.Size{Cols: cols, Rows: rows}:
+				case <-ctx.Done():
+					return
+				}
+			}
+
+			select {
+			case <-sigs:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
@@ -0,0 +1,18 @@
+//go:build windows
+
+package cmd
+
+import (
+	"context"
+
+	"github.com/charmbracelet/x/term"
+	"github.com/sestrella/iecs/session"
+)
+
+// notifyResize delivers the size of the terminal, Windows does not signal
+// when it changes.
+func notifyResize(ctx context.Context, fd uintptr, resize chan<- session.Size) {
+	if cols, rows, err := term.GetSize(fd); err == nil {
+		resize <- session.Size{Cols: cols, Rows: rows}
+	}
+}
@@ -0,0 +1,120 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/aws/aws-sdk-go-v2/service/ecs/types"
+	"github.com/sestrella/iecs/client"
+	"github.com/spf13/cobra"
+)
+
+var stopCmd = &cobra.Command{
+	Use:   "stop",
+	Short: "Stop one or more tasks of a service",
+	Long: `Stops the selected tasks, recording the reason on them. The service
+scheduler starts replacement tasks to keep the desired count.`,
+	Example: `
+  iecs stop
+  iecs stop --task 0123456789abcdef --reason "Stuck on a database lock"
+  `,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		reason, err := cmd.Flags().GetString("reason")
+		if err != nil {
+			return err
+		}
+
+		client, err := newClient(context.Background())
+		if err != nil {
+			return err
+		}
+
+		selectors := newSelectors(client)
+
+		cluster, err := selectors.Cluster(context.Background(), clusterFilter)
+		if err != nil {
+			return err
+		}
+
+		service, err := selectors.Service(context.Background(), cluster, serviceFilter)
+		if err != nil {
+			return err
+		}
+
+		tasks, err := selectors.Tasks(context.Background(), service, taskFilter, false)
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintln(decorationOutput(), titleStyle.Render("Tasks to stop:"))
+		for _, task := range tasks {
+			fmt.Fprintf(decorationOutput(), "  %s\n", *task.TaskArn)
+		}
+
+		confirmed, err := confirm(
+			selectors,
+			fmt.Sprintf("Stop %d task(s)?", len(tasks)),
+			"stop the tasks",
+		)
+		if err != nil || !confirmed {
+			return err
+		}
+
+		return runStop(context.Background(), decorationOutput(), client, *cluster.ClusterArn, tasks, reason)
+	},
+}
+
+// StoppedTaskOutput is the machine-readable form of a task being stopped.
+type StoppedTaskOutput struct {
+	Cluster       string `json:"cluster" yaml:"cluster"`
+	Task          string `json:"task" yaml:"task"`
+	LastStatus    string `json:"lastStatus" yaml:"lastStatus"`
+	DesiredStatus string `json:"desiredStatus" yaml:"desiredStatus"`
+	StoppedReason string `json:"stoppedReason,omitempty" yaml:"stoppedReason,omitempty"`
+}
+
+// runStop stops every task, carrying on when one of them fails.
+func runStop(
+	ctx context.Context,
+	w io.Writer,
+	client client.Client,
+	clusterArn string,
+	tasks []types.Task,
+	reason string,
+) error {
+	var errs []error
+	for _, task := range tasks {
+		stopped, err := client.StopTask(ctx, clusterArn, *task.TaskArn, reason)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("unable to stop %s: %w", *task.TaskArn, err))
+			continue
+		}
+
+		if structuredOutput() {
+			err = printOutput(StoppedTaskOutput{
+				Cluster:       clusterArn,
+				Task:          *stopped.TaskArn,
+				LastStatus:    stringValue(stopped.LastStatus),
+				DesiredStatus: stringValue(stopped.DesiredStatus),
+				StoppedReason: stringValue(stopped.StoppedReason),
+			})
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		fmt.Fprintf(w, "Stopping %s\n", *stopped.TaskArn)
+	}
+	return errors.Join(errs...)
+}
+
+func init() {
+	rootCmd.AddCommand(stopCmd)
+
+	stopCmd.Flags().String("reason", "Stopped with iecs", "The reason recorded on the stopped tasks")
+	stopCmd.Flags().
+		BoolVarP(&yesFlag, "yes", "y", false, "Stop the tasks without asking for confirmation")
+}
@@ -0,0 +1,36 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ecs/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestRunStop(t *testing.T) {
+	mockClient := new(MockClient)
+	mockClient.On("StopTask", mock.Anything, "cluster", "task-1", "wedged").
+		Return(&types.Task{TaskArn: aws.String("task-1")}, nil)
+	mockClient.On("StopTask", mock.Anything, "cluster", "task-2", "wedged").
+		Return(nil, errors.New("access denied"))
+	mockClient.On("StopTask", mock.Anything, "cluster", "task-3", "wedged").
+		Return(&types.Task{TaskArn: aws.String("task-3")}, nil)
+
+	tasks := []types.Task{
+		{TaskArn: aws.String("task-1")},
+		{TaskArn: aws.String("task-2")},
+		{TaskArn: aws.String("task-3")},
+	}
+
+	var output bytes.Buffer
+	err := runStop(context.Background(), &output, mockClient, "cluster", tasks, "wedged")
+
+	assert.EqualError(t, err, "unable to stop task-2: access denied")
+	assert.Equal(t, "Stopping task-1\nStopping task-3\n", output.String())
+	mockClient.AssertExpectations(t)
+}
@@ -0,0 +1,197 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/ecs/types"
+	"github.com/sestrella/iecs/client"
+	"github.com/sestrella/iecs/ui"
+	"github.com/spf13/cobra"
+)
+
+var uiCmd = &cobra.Command{
+	Use:   "ui",
+	Short: "Browse clusters, services, tasks and containers in a full-screen dashboard",
+	Long: `Shows clusters, services, tasks and containers in navigable panes that
+refresh periodically. From any row, exec, logs, events and update can be
+triggered for the selected resources; the dashboard comes back once they finish.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		refresh, err := cmd.Flags().GetDuration("refresh")
+		if err != nil {
+			return err
+		}
+		if refresh <= 0 {
+			return fmt.Errorf("--refresh must be greater than 0, got %s", refresh)
+		}
+
+		command, err := uiExecCommand(cmd, configFile)
+		if err != nil {
+			return err
+		}
+
+		awsClient, err := newClient(context.Background())
+		if err != nil {
+			return err
+		}
+
+		return ui.Run(context.Background(), awsClient, uiRunner(awsClient, command), refresh)
+	},
+	Aliases: []string{"dashboard"},
+}
+
+// uiExecCommand returns the command run by the exec action. Unless given
+// explicitly, the command configured as a default for exec is used, so both
+// open the same shell.
+func uiExecCommand(cmd *cobra.Command, configFile *ConfigFile) (string, error) {
+	command, err := cmd.Flags().GetString(execCommandFlag)
+	if err != nil {
+		return "", err
+	}
+	if cmd.Flags().Changed(execCommandFlag) || configFile == nil {
+		return command, nil
+	}
+	if execCommand := configFile.Commands[execCmd.Name()][execCommandFlag]; execCommand != "" {
+		return execCommand, nil
+	}
+	return command, nil
+}
+
+// uiRunner runs the actions triggered from the dashboard with the same
+// functions used by the standalone commands. Exec runs command.
+func uiRunner(awsClient client.Client, command string) ui.Runner {
+	return func(action ui.Action, selection ui.Selection) error {
+		switch action {
+		case ui.ActionExec:
+			// Interrupts are forwarded to the remote command by runSession
+			return runExec(context.Background(), awsClient, ExecSelection{
+				cluster:   selection.Cluster,
+				service:   selection.Service,
+				task:      selection.Task,
+				container: selection.Container,
+			}, command, true)
+
+		case ui.ActionLogs:
+			ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+			defer stop()
+
+			logsSelection, err := uiLogsSelection(ctx, awsClient, selection)
+			if err != nil {
+				return err
+			}
+			clusterClient, err := client.ForCluster(awsClient, *selection.Cluster.ClusterArn)
+			if err != nil {
+				return err
+			}
+			return runLogs(ctx, false, clusterClient, *logsSelection, LogsOptions{follow: true})
+
+		case ui.ActionEvents:
+			ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+			defer stop()
+
+			return runEvents(ctx, os.Stdout, awsClient, selection.Service, true, eventsPollInterval)
+
+		case ui.ActionUpdate:
+			err := uiUpdate(context.Background(), awsClient, selection)
+			if err != nil {
+				return err
+			}
+			// Leave the rollout summary on screen until the user is done with it
+			fmt.Print("Press enter to return to the dashboard")
+			_, err = bufio.NewReader(os.Stdin).ReadString('\n')
+			return err
+		}
+
+		return fmt.Errorf("unknown action %s", action)
+	}
+}
+
+// uiLogsSelection returns the tasks and containers to tail. Without a
+// selected task, the logs of every running task of the service are shown.
+func uiLogsSelection(
+	ctx context.Context,
+	client client.Client,
+	selection ui.Selection,
+) (*LogsSelection, error) {
+	var tasks []types.Task
+	if selection.Task != nil {
+		tasks = []types.Task{*selection.Task}
+	} else {
+		taskArns, err := client.ListTasks(
+			ctx,
+			*selection.Service.ClusterArn,
+			*selection.Service.ServiceArn,
+			types.DesiredStatusRunning,
+		)
+		if err != nil {
+			return nil, err
+		}
+		if len(taskArns) == 0 {
+			return nil, fmt.Errorf("no running tasks for service %s", *selection.Service.ServiceName)
+		}
+		tasks, err = client.DescribeTasks(ctx, *selection.Service.ClusterArn, taskArns)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	taskDefinition, err := client.DescribeTaskDefinition(ctx, *tasks[0].TaskDefinitionArn)
+	if err != nil {
+		return nil, err
+	}
+
+	var containers []types.ContainerDefinition
+	for _, container := range taskDefinition.ContainerDefinitions {
+		if selection.Container == nil || *container.Name == *selection.Container.Name {
+			containers = append(containers, container)
+		}
+	}
+
+	return &LogsSelection{
+		cluster:    selection.Cluster,
+		service:    selection.Service,
+		tasks:      tasks,
+		containers: containers,
+	}, nil
+}
+
+func uiUpdate(ctx context.Context, awsClient client.Client, selection ui.Selection) error {
+	selectors := newSelectors(awsClient)
+
+	serviceConfig, err := selectors.ServiceConfig(ctx, selection.Service, nil, nil)
+	if err != nil {
+		return err
+	}
+
+	updateSelection := UpdateSelection{
+		cluster:       *selection.Cluster,
+		service:       *selection.Service,
+		serviceConfig: *serviceConfig,
+	}
+
+	err = printUpdatePlan(ctx, os.Stdout, awsClient, updateSelection)
+	if err != nil {
+		return err
+	}
+
+	confirmed, err := confirmUpdate(selectors)
+	if err != nil || !confirmed {
+		return err
+	}
+
+	return runUpdate(ctx, updateSelection, awsClient, waitTimeoutFlag)
+}
+
+func init() {
+	rootCmd.AddCommand(uiCmd)
+
+	uiCmd.Flags().Duration("refresh", 5*time.Second, "How often the dashboard is refreshed")
+	uiCmd.Flags().
+		StringP(execCommandFlag, "c", execDefaultCommand, "The command run by the exec action, defaults to the one configured for exec")
+	uiCmd.Flags().
+		DurationVarP(&waitTimeoutFlag, "wait-timeout", "w", 5*time.Minute, "The wait time for the service to become available after an update")
+}
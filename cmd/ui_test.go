@@ -0,0 +1,34 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newUiTestCommand(t *testing.T, args ...string) *cobra.Command {
+	cmd := &cobra.Command{Use: "ui"}
+	cmd.Flags().StringP(execCommandFlag, "c", execDefaultCommand, "")
+	require.NoError(t, cmd.Flags().Parse(args))
+	return cmd
+}
+
+func TestUiExecCommand(t *testing.T) {
+	configFile := &ConfigFile{Commands: map[string]map[string]string{
+		"exec": {"command": "/bin/sh"},
+	}}
+
+	command, err := uiExecCommand(newUiTestCommand(t), nil)
+	require.NoError(t, err)
+	assert.Equal(t, "/bin/bash", command)
+
+	command, err = uiExecCommand(newUiTestCommand(t), configFile)
+	require.NoError(t, err)
+	assert.Equal(t, "/bin/sh", command)
+
+	command, err = uiExecCommand(newUiTestCommand(t, "--command", "/bin/zsh"), configFile)
+	require.NoError(t, err)
+	assert.Equal(t, "/bin/zsh", command)
+}
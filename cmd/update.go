@@ -2,16 +2,23 @@ package cmd
 
 import (
 	"context"
+	"fmt"
+	"io"
 	"time"
 
-	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/service/ecs/types"
 	"github.com/sestrella/iecs/client"
 	"github.com/sestrella/iecs/selector"
 	"github.com/spf13/cobra"
 )
 
-var waitTimeoutFlag time.Duration
+var (
+	waitTimeoutFlag    time.Duration
+	taskDefinitionFlag string
+	desiredCountFlag   int32
+	yesFlag            bool
+	noColorsFlag       bool
+)
 
 type UpdateSelection struct {
 	cluster       types.Cluster
@@ -23,22 +30,43 @@ var updateCmd = &cobra.Command{
 	Use:   "update",
 	Short: "Updates a serice configuration",
 	RunE: func(cmd *cobra.Command, args []string) error {
-		cfg, err := config.LoadDefaultConfig(context.Background())
+		client, err := newClient(context.Background())
 		if err != nil {
 			return err
 		}
 
-		client := client.NewClient(cfg)
-		selectors := selector.NewSelectors(client, *theme)
+		taskDefinitionFilter, err := selector.NewFilter(taskDefinitionFlag)
+		if err != nil {
+			return err
+		}
+
+		var desiredCount *int32
+		if cmd.Flags().Changed("desired-count") {
+			desiredCount = &desiredCountFlag
+		}
+
+		selectors := newSelectors(client)
 
 		selection, err := updateSelector(
 			context.Background(),
 			selectors,
+			taskDefinitionFilter,
+			desiredCount,
 		)
 		if err != nil {
 			return err
 		}
 
+		err = printUpdatePlan(context.Background(), decorationOutput(), client, *selection)
+		if err != nil {
+			return err
+		}
+
+		confirmed, err := confirmUpdate(selectors)
+		if err != nil || !confirmed {
+			return err
+		}
+
 		err = runUpdate(context.Background(), *selection, client, waitTimeoutFlag)
 		if err != nil {
 			return err
@@ -51,18 +79,20 @@ var updateCmd = &cobra.Command{
 func updateSelector(
 	ctx context.Context,
 	selectors selector.Selectors,
+	taskDefinitionFilter *selector.Filter,
+	desiredCount *int32,
 ) (*UpdateSelection, error) {
-	cluster, err := selectors.Cluster(ctx, clusterRegex)
+	cluster, err := selectors.Cluster(ctx, clusterFilter)
 	if err != nil {
 		return nil, err
 	}
 
-	service, err := selectors.Service(ctx, cluster, serviceRegex)
+	service, err := selectors.Service(ctx, cluster, serviceFilter)
 	if err != nil {
 		return nil, err
 	}
 
-	serviceConfig, err := selectors.ServiceConfig(ctx, service)
+	serviceConfig, err := selectors.ServiceConfig(ctx, service, taskDefinitionFilter, desiredCount)
 	if err != nil {
 		return nil, err
 	}
@@ -74,22 +104,102 @@ func updateSelector(
 	}, nil
 }
 
+// confirmUpdate asks for confirmation before updating the service, unless
+// --yes is given.
+func confirmUpdate(selectors selector.Selectors) (bool, error) {
+	return confirm(selectors, "Update the service?", "update the service")
+}
+
+// confirm asks the question before performing an action, unless --yes is
+// given. In non-interactive mode, --yes is required.
+func confirm(selectors selector.Selectors, question string, action string) (bool, error) {
+	if yesFlag {
+		return true, nil
+	}
+	if nonInteractive {
+		return false, fmt.Errorf("confirmation required, use --yes to %s", action)
+	}
+
+	confirmed, err := selectors.Confirm(question)
+	if err != nil {
+		return false, err
+	}
+	if !confirmed {
+		fmt.Fprintln(decorationOutput(), "Cancelled")
+	}
+	return confirmed, nil
+}
+
+// printUpdatePlan describes the changes about to be rolled out to the service.
+func printUpdatePlan(
+	ctx context.Context,
+	w io.Writer,
+	client client.Client,
+	selection UpdateSelection,
+) error {
+	currentTaskDefinition, err := client.DescribeTaskDefinition(ctx, *selection.service.TaskDefinition)
+	if err != nil {
+		return err
+	}
+
+	targetTaskDefinition := currentTaskDefinition
+	if selection.serviceConfig.TaskDefinitionArn != *currentTaskDefinition.TaskDefinitionArn {
+		targetTaskDefinition, err = client.DescribeTaskDefinition(
+			ctx,
+			selection.serviceConfig.TaskDefinitionArn,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	fmt.Fprintf(
+		w,
+		"%s %s -> %s\n",
+		titleStyle.Render("Task definition:"),
+		*currentTaskDefinition.TaskDefinitionArn,
+		*targetTaskDefinition.TaskDefinitionArn,
+	)
+	printDiff(w, taskDefinitionDiff(currentTaskDefinition, targetTaskDefinition))
+
+	desiredCount := selection.serviceConfig.DesiredCount
+	if desiredCount != nil && *desiredCount != selection.service.DesiredCount {
+		fmt.Fprintf(
+			w,
+			"%s %d -> %d\n",
+			titleStyle.Render("Desired count:"),
+			selection.service.DesiredCount,
+			*desiredCount,
+		)
+	}
+
+	return nil
+}
+
 func runUpdate(
 	ctx context.Context,
 	selection UpdateSelection,
 	client client.Client,
 	waitTimeout time.Duration,
 ) error {
-	_, err := client.UpdateService(
+	service, err := client.UpdateService(
 		ctx,
 		&selection.service,
 		selection.serviceConfig,
-		waitTimeout,
 	)
 	if err != nil {
 		return err
 	}
 
+	service, err = watchRollout(ctx, client, service, waitTimeout, decorationOutput())
+	if err != nil {
+		return err
+	}
+
+	if structuredOutput() {
+		return printOutput(serviceOutput(service))
+	}
+
 	return nil
 }
 
@@ -98,4 +208,11 @@ func init() {
 
 	updateCmd.Flags().
 		DurationVarP(&waitTimeoutFlag, "wait-timeout", "w", 5*time.Minute, "The wait time for the service to become available")
+	updateCmd.Flags().
+		StringVar(&taskDefinitionFlag, "task-definition", "", "The task definition ARN or family:revision to deploy")
+	updateCmd.Flags().
+		Int32Var(&desiredCountFlag, "desired-count", 0, "The desired number of tasks")
+	updateCmd.Flags().
+		BoolVarP(&yesFlag, "yes", "y", false, "Update the service without asking for confirmation")
+	updateCmd.Flags().BoolVar(&noColorsFlag, "no-colors", false, "Disable diff coloring")
 }
@@ -0,0 +1,79 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ecs/types"
+	"github.com/sestrella/iecs/client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPrintUpdatePlan(t *testing.T) {
+	mockClient := new(MockClient)
+	current := testTaskDefinition("1", "api:1.0.0")
+	target := testTaskDefinition("2", "api:1.1.0")
+	mockClient.On("DescribeTaskDefinition", mock.Anything, *current.TaskDefinitionArn).
+		Return(current, nil)
+	mockClient.On("DescribeTaskDefinition", mock.Anything, *target.TaskDefinitionArn).
+		Return(target, nil)
+
+	selection := UpdateSelection{
+		service: types.Service{
+			TaskDefinition: current.TaskDefinitionArn,
+			DesiredCount:   2,
+		},
+		serviceConfig: client.ServiceConfig{
+			TaskDefinitionArn: *target.TaskDefinitionArn,
+			DesiredCount:      aws.Int32(3),
+		},
+	}
+
+	var output bytes.Buffer
+	err := printUpdatePlan(context.Background(), &output, mockClient, selection)
+
+	require.NoError(t, err)
+	assert.Equal(
+		t,
+		"Task definition: "+*current.TaskDefinitionArn+" -> "+*target.TaskDefinitionArn+"\n"+
+			"  ~ app.image: api:1.0.0 -> api:1.1.0\n"+
+			"Desired count: 2 -> 3\n",
+		output.String(),
+	)
+	mockClient.AssertExpectations(t)
+}
+
+func TestPrintUpdatePlan_SameTaskDefinition(t *testing.T) {
+	mockClient := new(MockClient)
+	current := testTaskDefinition("1", "api:1.0.0")
+	mockClient.On("DescribeTaskDefinition", mock.Anything, *current.TaskDefinitionArn).
+		Return(current, nil).
+		Once()
+
+	selection := UpdateSelection{
+		service: types.Service{
+			TaskDefinition: current.TaskDefinitionArn,
+			DesiredCount:   2,
+		},
+		serviceConfig: client.ServiceConfig{
+			TaskDefinitionArn: *current.TaskDefinitionArn,
+			DesiredCount:      aws.Int32(2),
+		},
+	}
+
+	var output bytes.Buffer
+	err := printUpdatePlan(context.Background(), &output, mockClient, selection)
+
+	require.NoError(t, err)
+	assert.Equal(
+		t,
+		"Task definition: "+*current.TaskDefinitionArn+" -> "+*current.TaskDefinitionArn+"\n"+
+			"  no changes\n",
+		output.String(),
+	)
+	mockClient.AssertExpectations(t)
+}
@@ -2,7 +2,9 @@ package main
 
 import (
 	_ "embed"
+	"errors"
 	"log"
+	"os"
 
 	"github.com/sestrella/iecs/cmd"
 )
@@ -12,6 +14,10 @@ var version string
 
 func main() {
 	if err := cmd.Execute(version); err != nil {
+		var exitErr *cmd.ExitError
+		if errors.As(err, &exitErr) {
+			os.Exit(exitErr.Code)
+		}
 		log.Fatal(err)
 	}
 }
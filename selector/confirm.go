@@ -0,0 +1,21 @@
+package selector
+
+import (
+	"github.com/charmbracelet/huh"
+)
+
+// Confirm asks the user to confirm an action.
+func (s Selectors) Confirm(title string) (bool, error) {
+	var confirmed bool
+	form := huh.NewForm(
+		huh.NewGroup(
+			huh.NewConfirm().
+				Title(title).
+				Value(&confirmed),
+		),
+	).WithTheme(&s.theme)
+	if err := form.Run(); err != nil {
+		return false, err
+	}
+	return confirmed, nil
+}
@@ -0,0 +1,61 @@
+package selector
+
+import (
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// Filter narrows down the resources offered by a selector. A resource matches
+// when its name, short ID or ARN is equal to the filter value; when no
+// resource matches exactly, the value is used as a regex pattern instead.
+type Filter struct {
+	value string
+	regex *regexp.Regexp
+}
+
+// NewFilter returns a filter for the given value, or nil if the value is empty.
+func NewFilter(value string) (*Filter, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	regex, err := regexp.Compile(value)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Filter{value: value, regex: regex}, nil
+}
+
+// Apply returns the identifiers matching the filter. A nil filter matches
+// everything.
+func (f *Filter) Apply(ids []string) []string {
+	if f == nil {
+		return ids
+	}
+
+	exact := slices.DeleteFunc(slices.Clone(ids), func(id string) bool {
+		return id != f.value && shortName(id) != f.value
+	})
+	if len(exact) > 0 {
+		return exact
+	}
+
+	return slices.DeleteFunc(slices.Clone(ids), func(id string) bool {
+		return !f.regex.MatchString(id)
+	})
+}
+
+// shortName returns the last path segment of an ARN, which corresponds to the
+// cluster name, service name, task ID or task definition "family:revision".
+func shortName(arn string) string {
+	return arn[strings.LastIndex(arn, "/")+1:]
+}
+
+func (f *Filter) String() string {
+	if f == nil {
+		return ""
+	}
+	return f.value
+}
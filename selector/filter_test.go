@@ -0,0 +1,63 @@
+package selector
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var serviceArns = []string{
+	"arn:aws:ecs:us-east-1:123456789012:service/my-cluster/api",
+	"arn:aws:ecs:us-east-1:123456789012:service/my-cluster/api-worker",
+	"arn:aws:ecs:us-east-1:123456789012:service/my-cluster/web",
+}
+
+func TestFilter_Nil(t *testing.T) {
+	filter, err := NewFilter("")
+
+	require.NoError(t, err)
+	assert.Nil(t, filter)
+	assert.Equal(t, serviceArns, filter.Apply(serviceArns))
+}
+
+func TestFilter_ExactName(t *testing.T) {
+	filter, err := NewFilter("api")
+
+	require.NoError(t, err)
+	assert.Equal(t, serviceArns[:1], filter.Apply(serviceArns))
+}
+
+func TestFilter_ExactArn(t *testing.T) {
+	filter, err := NewFilter(serviceArns[1])
+
+	require.NoError(t, err)
+	assert.Equal(t, serviceArns[1:2], filter.Apply(serviceArns))
+}
+
+func TestFilter_Regex(t *testing.T) {
+	filter, err := NewFilter("ap+i-")
+
+	require.NoError(t, err)
+	assert.Equal(t, serviceArns[1:2], filter.Apply(serviceArns))
+}
+
+func TestFilter_InvalidRegex(t *testing.T) {
+	_, err := NewFilter("api(")
+
+	assert.Error(t, err)
+}
+
+func TestSelector_NonInteractiveAmbiguous(t *testing.T) {
+	filter, err := NewFilter("my-cluster/api")
+	require.NoError(t, err)
+
+	_, err = Selector[string]{
+		nonInteractive: true,
+		lister: func() ([]string, error) {
+			return serviceArns, nil
+		},
+	}.Run(filter)
+
+	assert.ErrorContains(t, err, "ambiguous: 2 matches")
+}
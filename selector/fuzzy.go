@@ -0,0 +1,237 @@
+package selector
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"unicode"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/huh"
+)
+
+// fuzzyScore reports whether all the characters of query appear in text in
+// the same order, ignoring case. Matches on consecutive characters and at the
+// start of words score higher, so "api" ranks "api-gateway" above
+// "app-internal".
+func fuzzyScore(query string, text string) (int, bool) {
+	queryRunes := []rune(strings.ToLower(query))
+	if len(queryRunes) == 0 {
+		return 0, true
+	}
+
+	textRunes := []rune(strings.ToLower(text))
+	score := 0
+	matched := 0
+	previous := -2
+	for i, r := range textRunes {
+		if r != queryRunes[matched] {
+			continue
+		}
+
+		score++
+		if previous == i-1 {
+			score += 3
+		}
+		if i == 0 || !unicode.IsLetter(textRunes[i-1]) && !unicode.IsDigit(textRunes[i-1]) {
+			score += 2
+		}
+		previous = i
+
+		matched++
+		if matched == len(queryRunes) {
+			// Shorter texts are closer matches
+			return score*100 - len(textRunes), true
+		}
+	}
+	return 0, false
+}
+
+// fuzzyFilter returns the options whose label matches the query, best
+// matches first.
+func fuzzyFilter[T comparable](query string, options []huh.Option[T]) []huh.Option[T] {
+	if query == "" {
+		return options
+	}
+
+	type scoredOption struct {
+		option huh.Option[T]
+		score  int
+	}
+	var matches []scoredOption
+	for _, option := range options {
+		if score, ok := fuzzyScore(query, option.Key); ok {
+			matches = append(matches, scoredOption{option: option, score: score})
+		}
+	}
+	slices.SortStableFunc(matches, func(a, b scoredOption) int {
+		return b.score - a.score
+	})
+
+	filtered := make([]huh.Option[T], 0, len(matches))
+	for _, match := range matches {
+		filtered = append(filtered, match.option)
+	}
+	return filtered
+}
+
+// noMatches is listed when nothing matches the query, as the fields need at
+// least one option. Its empty value is never picked.
+var noMatches = huh.NewOption("no matches", "")
+
+// updateQuery applies a typed key to the query, reporting whether the key was
+// meant for it. Space is only typed into the query when typeSpace is set.
+func updateQuery(query *string, msg tea.Msg, typeSpace bool) bool {
+	keyMsg, ok := msg.(tea.KeyMsg)
+	if !ok {
+		return false
+	}
+
+	switch {
+	case keyMsg.Type == tea.KeyRunes && !keyMsg.Alt:
+		*query += string(keyMsg.Runes)
+	case keyMsg.Type == tea.KeySpace && typeSpace:
+		*query += " "
+	case keyMsg.Type == tea.KeyBackspace:
+		runes := []rune(*query)
+		*query = string(runes[:max(len(runes)-1, 0)])
+	default:
+		return false
+	}
+	return true
+}
+
+// queryDescription shows the query below the title of a field.
+func queryDescription(query string) string {
+	if query == "" {
+		return "Type to filter"
+	}
+	return "Filter: " + query
+}
+
+// fuzzySelect is a select field whose options are narrowed down by fuzzy
+// matching what is typed, best matches first.
+type fuzzySelect struct {
+	*huh.Select[string]
+	options []huh.Option[string]
+	query   string
+}
+
+func newFuzzySelect(title string, options []huh.Option[string], selected *string) *fuzzySelect {
+	return &fuzzySelect{
+		Select: huh.NewSelect[string]().
+			Title(title).
+			Description(queryDescription("")).
+			Options(options...).
+			Value(selected).
+			// The height includes the lines of the title and the description
+			Height(min(len(options), pickerHeight) + 2).
+			Validate(func(value string) error {
+				if value == "" {
+					return errors.New("no matches")
+				}
+				return nil
+			}),
+		options: options,
+	}
+}
+
+func (f *fuzzySelect) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	if !updateQuery(&f.query, msg, true) {
+		_, cmd := f.Select.Update(msg)
+		return f, cmd
+	}
+
+	options := fuzzyFilter(f.query, f.options)
+	if len(options) == 0 {
+		options = []huh.Option[string]{noMatches}
+	}
+	f.Select.Description(queryDescription(f.query)).Options(options...)
+	// Move the cursor to the best match
+	_, cmd := f.Select.Update(tea.KeyMsg{Type: tea.KeyHome})
+	return f, cmd
+}
+
+// fuzzyMultiSelect is a multi-select field whose options are narrowed down by
+// fuzzy matching what is typed, best matches first. The options filtered out
+// keep their selection.
+type fuzzyMultiSelect struct {
+	*huh.MultiSelect[string]
+	options  []huh.Option[string]
+	selected *[]string
+	// listed holds the values of the options shown, whose selection is
+	// tracked by value
+	listed []string
+	value  []string
+	query  string
+}
+
+func newFuzzyMultiSelect(
+	title string,
+	options []huh.Option[string],
+	selected *[]string,
+	validate func([]string) error,
+) *fuzzyMultiSelect {
+	f := &fuzzyMultiSelect{
+		options:  options,
+		selected: selected,
+		listed:   optionValues(options),
+	}
+	f.MultiSelect = huh.NewMultiSelect[string]().
+		Title(title).
+		Description(queryDescription("")).
+		Options(options...).
+		Filterable(false).
+		// The height includes the lines of the title and the description
+		Height(min(len(options), pickerHeight) + 2).
+		Value(&f.value).
+		Validate(func(value []string) error {
+			return validate(f.merge(value))
+		})
+	return f
+}
+
+func (f *fuzzyMultiSelect) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	if !updateQuery(&f.query, msg, false) {
+		_, cmd := f.MultiSelect.Update(msg)
+		*f.selected = f.merge(f.value)
+		return f, cmd
+	}
+
+	options := slices.Clone(fuzzyFilter(f.query, f.options))
+	for i, option := range options {
+		options[i] = option.Selected(slices.Contains(*f.selected, option.Value))
+	}
+	f.listed = optionValues(options)
+	if len(options) == 0 {
+		options = []huh.Option[string]{noMatches}
+	}
+	f.MultiSelect.Description(queryDescription(f.query)).Options(options...)
+	// Move the cursor to the best match
+	_, cmd := f.MultiSelect.Update(tea.KeyMsg{Type: tea.KeyHome})
+	return f, cmd
+}
+
+// merge returns the selected values, taking the listed ones from value and
+// keeping the ones filtered out.
+func (f *fuzzyMultiSelect) merge(value []string) []string {
+	var merged []string
+	for _, option := range f.options {
+		if slices.Contains(f.listed, option.Value) {
+			if slices.Contains(value, option.Value) {
+				merged = append(merged, option.Value)
+			}
+		} else if slices.Contains(*f.selected, option.Value) {
+			merged = append(merged, option.Value)
+		}
+	}
+	return merged
+}
+
+func optionValues(options []huh.Option[string]) []string {
+	values := make([]string, 0, len(options))
+	for _, option := range options {
+		values = append(values, option.Value)
+	}
+	return values
+}
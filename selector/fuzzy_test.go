@@ -0,0 +1,106 @@
+package selector
+
+import (
+	"errors"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/huh"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFuzzyScore(t *testing.T) {
+	_, ok := fuzzyScore("apiwrk", "api-worker")
+	assert.True(t, ok)
+
+	_, ok = fuzzyScore("API", "api-worker")
+	assert.True(t, ok)
+
+	_, ok = fuzzyScore("wa", "api-worker")
+	assert.False(t, ok)
+
+	_, ok = fuzzyScore("", "api-worker")
+	assert.True(t, ok)
+}
+
+func TestFuzzyFilter(t *testing.T) {
+	options := huh.NewOptions("app-internal", "billing", "api-gateway", "api")
+
+	assert.Equal(t, huh.NewOptions("api", "api-gateway", "app-internal"), fuzzyFilter("api", options))
+	assert.Equal(t, huh.NewOptions("billing"), fuzzyFilter("blg", options))
+	assert.Empty(t, fuzzyFilter("xyz", options))
+	assert.Equal(t, options, fuzzyFilter("", options))
+}
+
+func typeKeys(t *testing.T, field huh.Field, msgs ...tea.KeyMsg) {
+	t.Helper()
+	for _, msg := range msgs {
+		_, _ = field.Update(msg)
+	}
+}
+
+func TestFuzzySelect(t *testing.T) {
+	var selected string
+	field := newFuzzySelect("Select a service", huh.NewOptions("app-internal", "billing", "api-gateway", "api"), &selected)
+	field.WithKeyMap(huh.NewDefaultKeyMap())
+
+	typeKeys(t, field, tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("api")})
+	assert.Equal(t, "api", selected)
+	assert.Contains(t, field.View(), "Filter: api")
+
+	typeKeys(t, field, tea.KeyMsg{Type: tea.KeyDown})
+	assert.Equal(t, "api-gateway", selected)
+
+	typeKeys(t, field, tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("x")})
+	assert.Equal(t, "", selected)
+	typeKeys(t, field, tea.KeyMsg{Type: tea.KeyEnter})
+	assert.EqualError(t, field.Error(), "no matches")
+
+	typeKeys(t, field, tea.KeyMsg{Type: tea.KeyBackspace}, tea.KeyMsg{Type: tea.KeyBackspace})
+	assert.Equal(t, "ap", field.query)
+	assert.Equal(t, "api", selected)
+}
+
+func TestFuzzyMultiSelect(t *testing.T) {
+	var selected []string
+	field := newFuzzyMultiSelect(
+		"Select at least one task",
+		huh.NewOptions("api-1", "api-2", "worker-1"),
+		&selected,
+		func(s []string) error {
+			if len(s) > 0 {
+				return nil
+			}
+			return errors.New("no task selected")
+		},
+	)
+	field.WithKeyMap(huh.NewDefaultKeyMap())
+
+	typeKeys(
+		t,
+		field,
+		tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("wrk")},
+		tea.KeyMsg{Type: tea.KeySpace},
+	)
+	assert.Equal(t, []string{"worker-1"}, selected)
+
+	// Clearing the query lists all the tasks again, keeping the selection
+	typeKeys(
+		t,
+		field,
+		tea.KeyMsg{Type: tea.KeyBackspace},
+		tea.KeyMsg{Type: tea.KeyBackspace},
+		tea.KeyMsg{Type: tea.KeyBackspace},
+		tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("api2")},
+		tea.KeyMsg{Type: tea.KeySpace},
+	)
+	assert.Equal(t, []string{"api-2", "worker-1"}, selected)
+
+	typeKeys(t, field, tea.KeyMsg{Type: tea.KeySpace})
+	assert.Equal(t, []string{"worker-1"}, selected)
+
+	// The tasks filtered out count as selected
+	typeKeys(t, field, tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("zz")}, tea.KeyMsg{Type: tea.KeyEnter})
+	assert.NoError(t, field.Error())
+	assert.Equal(t, []string{"worker-1"}, selected)
+}
@@ -0,0 +1,40 @@
+package selector
+
+// Profile picks one of the given AWS profiles.
+func (s Selectors) Profile(profiles []string) (string, error) {
+	profile, err := stringSelector(s, profiles, "Select an AWS profile", "Profile:").Run(nil)
+	if err != nil {
+		return "", err
+	}
+	return *profile, nil
+}
+
+// Region picks one of the given AWS regions.
+func (s Selectors) Region(regions []string) (string, error) {
+	region, err := stringSelector(s, regions, "Select an AWS region", "Region:").Run(nil)
+	if err != nil {
+		return "", err
+	}
+	return *region, nil
+}
+
+func stringSelector(s Selectors, values []string, prompt string, title string) Selector[string] {
+	return Selector[string]{
+		theme:          s.theme,
+		nonInteractive: s.nonInteractive,
+		output:         s.output,
+		lister: func() ([]string, error) {
+			return values, nil
+		},
+		describer: func(values []string) ([]string, error) {
+			return values, nil
+		},
+		title: prompt,
+		formatter: func(selectedRes *string) Selection {
+			return Selection{
+				title: title,
+				value: *selectedRes,
+			}
+		},
+	}
+}
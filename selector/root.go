@@ -3,12 +3,14 @@ package selector
 import (
 	"context"
 	"fmt"
+	"io"
 	"log"
-	"regexp"
 	"slices"
 	"strconv"
 	"strings"
+	"time"
 
+	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/ecs/types"
 	"github.com/charmbracelet/huh"
 	"github.com/charmbracelet/lipgloss"
@@ -18,98 +20,150 @@ import (
 var titleStyle = lipgloss.NewStyle().Bold(true)
 
 type Selectors struct {
-	client client.Client
-	theme  huh.Theme
+	client         client.Client
+	theme          huh.Theme
+	nonInteractive bool
+	output         io.Writer
 }
 
-func NewSelectors(client client.Client, theme huh.Theme) Selectors {
-	return Selectors{client: client, theme: theme}
+// NewSelectors returns selectors backed by the given client. When
+// nonInteractive is set, selectors fail instead of prompting whenever more
+// than one resource matches. The selected resources are reported to output.
+func NewSelectors(
+	client client.Client,
+	theme huh.Theme,
+	nonInteractive bool,
+	output io.Writer,
+) Selectors {
+	return Selectors{
+		client:         client,
+		theme:          theme,
+		nonInteractive: nonInteractive,
+		output:         output,
+	}
 }
 
 func (s Selectors) Cluster(
 	ctx context.Context,
-	clusterRegex *regexp.Regexp,
+	clusterFilter *Filter,
 ) (*types.Cluster, error) {
 	return Selector[types.Cluster]{
-		theme: s.theme,
+		theme:          s.theme,
+		nonInteractive: s.nonInteractive,
+		output:         s.output,
 		lister: func() ([]string, error) {
 			return s.client.ListClusters(ctx)
 		},
-		describer: func(arn string) ([]types.Cluster, error) {
-			return s.client.DescribeClusters(ctx, []string{arn})
-		},
-		pickers: func(arns []string, selectedArn *string) []huh.Field {
-			return []huh.Field{huh.NewSelect[string]().
-				Title("Select a cluster").
-				Options(
-					huh.NewOptions(arns...)...,
-				).
-				Value(selectedArn).
-				WithHeight(5),
-			}
+		describer: func(arns []string) ([]types.Cluster, error) {
+			return s.client.DescribeClusters(ctx, arns)
 		},
+		title:   "Select a cluster",
+		labeler: clusterOptions,
 		formatter: func(selectedRes *types.Cluster) Selection {
 			return Selection{
 				title: "Cluster:",
 				value: *selectedRes.ClusterArn,
 			}
 		},
-	}.Run(clusterRegex)
+	}.Run(clusterFilter)
+}
+
+// clusterOptions labels the clusters with their status and number of
+// services and tasks, and with their region and account when they span more
+// than one.
+func clusterOptions(clusters []types.Cluster) []huh.Option[string] {
+	locations := map[string]bool{}
+	for _, cluster := range clusters {
+		locations[arnLocation(*cluster.ClusterArn)] = true
+	}
+
+	var rows [][]string
+	for _, cluster := range clusters {
+		row := []string{
+			shortName(*cluster.ClusterArn),
+			stringValue(cluster.Status),
+			fmt.Sprintf("%d services", cluster.ActiveServicesCount),
+			fmt.Sprintf("%d tasks", cluster.RunningTasksCount),
+		}
+		if len(locations) > 1 {
+			row = append(row, arnLocation(*cluster.ClusterArn))
+		}
+		rows = append(rows, row)
+	}
+
+	options := make([]huh.Option[string], 0, len(clusters))
+	for i, label := range columns(rows) {
+		options = append(options, huh.NewOption(label, *clusters[i].ClusterArn))
+	}
+	return options
+}
+
+// arnLocation returns the region and account of an ARN, e.g. "us-east-1,
+// 123456789012".
+func arnLocation(arn string) string {
+	parts := strings.SplitN(arn, ":", 6)
+	if len(parts) < 6 {
+		return ""
+	}
+	return fmt.Sprintf("%s, %s", parts[3], parts[4])
 }
 
 func (s Selectors) Service(
 	ctx context.Context,
 	cluster *types.Cluster,
-	serviceRegex *regexp.Regexp,
+	serviceFilter *Filter,
 ) (*types.Service, error) {
 	return Selector[types.Service]{
-		theme: s.theme,
+		theme:          s.theme,
+		nonInteractive: s.nonInteractive,
+		output:         s.output,
 		lister: func() ([]string, error) {
 			return s.client.ListServices(ctx, *cluster.ClusterArn)
 		},
-		describer: func(arn string) ([]types.Service, error) {
-			return s.client.DescribeServices(
-				ctx,
-				*cluster.ClusterArn,
-				[]string{arn},
-			)
-		},
-		pickers: func(arns []string, selectedArn *string) []huh.Field {
-			return []huh.Field{huh.NewSelect[string]().
-				Title("Select a service").
-				Options(huh.NewOptions(arns...)...).
-				Value(selectedArn).
-				WithHeight(5),
-			}
+		describer: func(arns []string) ([]types.Service, error) {
+			return s.client.DescribeServices(ctx, *cluster.ClusterArn, arns)
 		},
+		title:   "Select a service",
+		labeler: serviceOptions,
 		formatter: func(selectedRes *types.Service) Selection {
 			return Selection{
 				title: "Service:",
 				value: *selectedRes.ServiceArn,
 			}
 		},
-	}.Run(serviceRegex)
+	}.Run(serviceFilter)
 }
 
 func (s Selectors) Task(
 	ctx context.Context,
 	service *types.Service,
+	taskFilter *Filter,
 ) (*types.Task, error) {
 	return Selector[types.Task]{
-		theme: s.theme,
+		theme:          s.theme,
+		nonInteractive: s.nonInteractive,
+		output:         s.output,
 		lister: func() ([]string, error) {
-			return s.client.ListTasks(ctx, *service.ClusterArn, *service.ServiceArn)
+			taskArns, err := s.client.ListTasks(
+				ctx,
+				*service.ClusterArn,
+				*service.ServiceArn,
+				types.DesiredStatusRunning,
+			)
+			if err != nil {
+				return nil, err
+			}
+			if len(taskArns) == 0 {
+				return nil, fmt.Errorf("no tasks found in service %s", *service.ServiceArn)
+			}
+			return taskArns, nil
 		},
-		describer: func(arn string) ([]types.Task, error) {
-			return s.client.DescribeTasks(ctx, *service.ClusterArn, []string{arn})
+		describer: func(arns []string) ([]types.Task, error) {
+			return s.client.DescribeTasks(ctx, *service.ClusterArn, arns)
 		},
-		pickers: func(arns []string, selectedArn *string) []huh.Field {
-			return []huh.Field{huh.NewSelect[string]().
-				Title("Select a task").
-				Options(huh.NewOptions(arns...)...).
-				Value(selectedArn).
-				WithHeight(5),
-			}
+		title: "Select a task",
+		labeler: func(tasks []types.Task) []huh.Option[string] {
+			return taskOptions(tasks, time.Now())
 		},
 		formatter: func(selectedRes *types.Task) Selection {
 			return Selection{
@@ -117,35 +171,43 @@ func (s Selectors) Task(
 				value: *selectedRes.TaskArn,
 			}
 		},
-	}.Run(nil)
+	}.Run(taskFilter)
 }
 
+// Tasks lets the user pick one or more tasks of a service. When includeStopped
+// is set, the tasks recently stopped are offered as well, along with the reason
+// they stopped.
 func (s Selectors) Tasks(
 	ctx context.Context,
 	service *types.Service,
+	taskFilter *Filter,
+	includeStopped bool,
 ) ([]types.Task, error) {
-	taskArns, err := s.client.ListTasks(ctx, *service.ClusterArn, *service.ServiceArn)
+	tasks, err := s.matchingTasks(ctx, service, taskFilter, includeStopped)
 	if err != nil {
 		return nil, err
 	}
 
 	var selectedTaskArns []string
-	if len(taskArns) == 1 {
+	if len(tasks) == 1 {
 		log.Println("Pre-selecting the only task available")
-		selectedTaskArns = append(selectedTaskArns, taskArns[0])
+		selectedTaskArns = append(selectedTaskArns, *tasks[0].TaskArn)
+	} else if s.nonInteractive {
+		return nil, ambiguousError(taskArns(tasks))
 	} else {
 		form := huh.NewForm(
 			huh.NewGroup(
-				huh.NewMultiSelect[string]().
-					Title("Select at least one task").
-					Options(huh.NewOptions(taskArns...)...).
-					Value(&selectedTaskArns).
-					Validate(func(s []string) error {
+				newFuzzyMultiSelect(
+					"Select at least one task",
+					taskOptions(tasks, time.Now()),
+					&selectedTaskArns,
+					func(s []string) error {
 						if len(s) > 0 {
 							return nil
 						}
 						return fmt.Errorf("no task selected")
-					}),
+					},
+				),
 			),
 		).WithTheme(&s.theme)
 		if err = form.Run(); err != nil {
@@ -153,29 +215,235 @@ func (s Selectors) Tasks(
 		}
 	}
 
-	fmt.Printf("%s %s\n", titleStyle.Render("Task(s):"), strings.Join(selectedTaskArns, ","))
-	tasks, err := s.client.DescribeTasks(ctx, *service.ClusterArn, selectedTaskArns)
+	tasks = slices.DeleteFunc(tasks, func(task types.Task) bool {
+		return !slices.Contains(selectedTaskArns, *task.TaskArn)
+	})
+	if len(tasks) == 0 {
+		return nil, fmt.Errorf("no tasks selected")
+	}
+
+	fmt.Fprintf(
+		s.output,
+		"%s %s\n",
+		titleStyle.Render("Task(s):"),
+		strings.Join(selectedTaskArns, ","),
+	)
+	return tasks, nil
+}
+
+// AllTasks returns every running task of the service matching the filter,
+// without prompting.
+func (s Selectors) AllTasks(
+	ctx context.Context,
+	service *types.Service,
+	taskFilter *Filter,
+) ([]types.Task, error) {
+	tasks, err := s.matchingTasks(ctx, service, taskFilter, false)
+	if err != nil {
+		return nil, err
+	}
+
+	fmt.Fprintf(
+		s.output,
+		"%s %s\n",
+		titleStyle.Render("Task(s):"),
+		strings.Join(taskArns(tasks), ","),
+	)
+	return tasks, nil
+}
+
+// matchingTasks describes the tasks of the service matching the filter.
+func (s Selectors) matchingTasks(
+	ctx context.Context,
+	service *types.Service,
+	taskFilter *Filter,
+	includeStopped bool,
+) ([]types.Task, error) {
+	taskArns, err := s.client.ListTasks(
+		ctx,
+		*service.ClusterArn,
+		*service.ServiceArn,
+		types.DesiredStatusRunning,
+	)
 	if err != nil {
 		return nil, err
 	}
 
-	if len(tasks) == 0 {
-		return nil, fmt.Errorf("no tasks selected")
+	if includeStopped {
+		stoppedTaskArns, err := s.client.ListTasks(
+			ctx,
+			*service.ClusterArn,
+			*service.ServiceArn,
+			types.DesiredStatusStopped,
+		)
+		if err != nil {
+			return nil, err
+		}
+		taskArns = append(taskArns, stoppedTaskArns...)
 	}
 
-	return tasks, nil
+	taskArns = taskFilter.Apply(taskArns)
+	if len(taskArns) == 0 {
+		if taskFilter != nil {
+			return nil, fmt.Errorf("no tasks matching %s", taskFilter)
+		}
+		return nil, fmt.Errorf("no tasks found in service %s", *service.ServiceArn)
+	}
+
+	return s.client.DescribeTasks(ctx, *service.ClusterArn, taskArns)
+}
+
+func taskArns(tasks []types.Task) []string {
+	arns := make([]string, 0, len(tasks))
+	for _, task := range tasks {
+		arns = append(arns, *task.TaskArn)
+	}
+	return arns
+}
+
+// serviceOptions labels the services with their status, running and
+// desired task counts and launch type.
+func serviceOptions(services []types.Service) []huh.Option[string] {
+	var rows [][]string
+	for _, service := range services {
+		row := []string{
+			stringValue(service.ServiceName),
+			stringValue(service.Status),
+			fmt.Sprintf("%d/%d running", service.RunningCount, service.DesiredCount),
+			serviceLaunchType(service),
+		}
+		if len(service.Deployments) > 1 {
+			row = append(row, "deploying")
+		}
+		rows = append(rows, row)
+	}
+
+	options := make([]huh.Option[string], 0, len(services))
+	for i, label := range columns(rows) {
+		options = append(options, huh.NewOption(label, *services[i].ServiceArn))
+	}
+	return options
+}
+
+// serviceLaunchType returns the launch type of the service, or its capacity
+// providers when it uses a capacity provider strategy.
+func serviceLaunchType(service types.Service) string {
+	if service.LaunchType != "" {
+		return string(service.LaunchType)
+	}
+	var providers []string
+	for _, strategy := range service.CapacityProviderStrategy {
+		providers = append(providers, stringValue(strategy.CapacityProvider))
+	}
+	return strings.Join(providers, "+")
+}
+
+// taskOptions labels the tasks with their status, health, task definition,
+// launch type and when they started. Stopped tasks are described with when
+// and why they stopped, and the exit codes of their containers.
+func taskOptions(tasks []types.Task, now time.Time) []huh.Option[string] {
+	var rows [][]string
+	for _, task := range tasks {
+		rows = append(rows, taskRow(task, now))
+	}
+
+	options := make([]huh.Option[string], 0, len(tasks))
+	for i, label := range columns(rows) {
+		options = append(options, huh.NewOption(label, *tasks[i].TaskArn))
+	}
+	return options
+}
+
+func taskRow(task types.Task, now time.Time) []string {
+	taskDefinition := ""
+	if task.TaskDefinitionArn != nil {
+		taskDefinition = shortName(*task.TaskDefinitionArn)
+	}
+	row := []string{
+		shortName(*task.TaskArn),
+		stringValue(task.LastStatus),
+		healthStatus(task.HealthStatus),
+		taskDefinition,
+		string(task.LaunchType),
+	}
+
+	if stringValue(task.LastStatus) != "STOPPED" {
+		if task.StartedAt != nil {
+			row = append(row, fmt.Sprintf("started %s ago", now.Sub(*task.StartedAt).Round(time.Second)))
+		}
+		return row
+	}
+
+	if task.StoppedAt != nil {
+		row = append(row, fmt.Sprintf("stopped %s ago", now.Sub(*task.StoppedAt).Round(time.Second)))
+	}
+	if task.StoppedReason != nil {
+		row = append(row, *task.StoppedReason)
+	}
+
+	var exitCodes []string
+	for _, container := range task.Containers {
+		if container.ExitCode != nil {
+			exitCodes = append(exitCodes, fmt.Sprintf("%s=%d", *container.Name, *container.ExitCode))
+		}
+	}
+	if len(exitCodes) > 0 {
+		row = append(row, "exit codes: "+strings.Join(exitCodes, " "))
+	}
+	return row
+}
+
+// containerOptions labels the containers with their status and health.
+func containerOptions(containers []types.Container) []huh.Option[string] {
+	var rows [][]string
+	for _, container := range containers {
+		rows = append(rows, []string{
+			*container.Name,
+			stringValue(container.LastStatus),
+			healthStatus(container.HealthStatus),
+		})
+	}
+
+	options := make([]huh.Option[string], 0, len(containers))
+	for i, label := range columns(rows) {
+		options = append(options, huh.NewOption(label, *containers[i].Name))
+	}
+	return options
+}
+
+// healthStatus returns the health status, or "-" when unknown or without
+// health checks.
+func healthStatus(status types.HealthStatus) string {
+	if status == "" || status == types.HealthStatusUnknown {
+		return "-"
+	}
+	return string(status)
+}
+
+func stringValue(value *string) string {
+	if value == nil {
+		return ""
+	}
+	return *value
 }
 
 func (s Selectors) ServiceConfig(
 	ctx context.Context,
 	service *types.Service,
+	taskDefinitionFilter *Filter,
+	desiredCount *int32,
 ) (*client.ServiceConfig, error) {
 	currentTaskDefinition, err := s.client.DescribeTaskDefinition(ctx, *service.TaskDefinition)
 	if err != nil {
 		return nil, err
 	}
 
-	taskDefinitionArns, err := s.client.ListTaskDefinitions(ctx, *currentTaskDefinition.Family)
+	clusterClient, err := client.ForCluster(s.client, stringValue(service.ClusterArn))
+	if err != nil {
+		return nil, err
+	}
+
+	taskDefinitionArns, err := clusterClient.ListTaskDefinitions(ctx, *currentTaskDefinition.Family)
 	if err != nil {
 		return nil, err
 	}
@@ -184,50 +452,70 @@ func (s Selectors) ServiceConfig(
 	}
 
 	var taskDefinitionArn = currentTaskDefinition.TaskDefinitionArn
+	if taskDefinitionFilter != nil {
+		matches := taskDefinitionFilter.Apply(taskDefinitionArns)
+		if len(matches) == 0 {
+			return nil, fmt.Errorf("no task definitions matching %s", taskDefinitionFilter)
+		}
+		if len(matches) > 1 && s.nonInteractive {
+			return nil, ambiguousError(matches)
+		}
+		taskDefinitionArn = &matches[0]
+	}
+
 	var desiredCountStr = strconv.FormatInt(int64(service.DesiredCount), 10)
-	form := huh.NewForm(
-		huh.NewGroup(
-			huh.NewSelect[string]().
-				Title("Task definition").
-				Options(huh.NewOptions(taskDefinitionArns...)...).
-				Value(taskDefinitionArn).
-				WithHeight(5),
-			huh.NewInput().
-				Title("Desired count").
-				Value(&desiredCountStr).
-				Validate(func(s string) error {
-					val, err := strconv.ParseInt(s, 10, 32)
-					if err != nil {
-						return fmt.Errorf("invalid number")
-					}
-					if val < 0 {
-						return fmt.Errorf("must be greater or equal to 0")
-					}
-					return nil
-				}),
-		),
-	).WithTheme(&s.theme)
-	if err := form.Run(); err != nil {
-		return nil, err
+	if desiredCount != nil {
+		desiredCountStr = strconv.FormatInt(int64(*desiredCount), 10)
 	}
 
-	desiredCount, err := strconv.ParseInt(desiredCountStr, 10, 32)
+	if !s.nonInteractive {
+		form := huh.NewForm(
+			huh.NewGroup(
+				huh.NewSelect[string]().
+					Title("Task definition").
+					Options(huh.NewOptions(taskDefinitionArns...)...).
+					Value(taskDefinitionArn).
+					Height(min(len(taskDefinitionArns), pickerHeight)+2),
+				huh.NewInput().
+					Title("Desired count").
+					Value(&desiredCountStr).
+					Validate(func(s string) error {
+						val, err := strconv.ParseInt(s, 10, 32)
+						if err != nil {
+							return fmt.Errorf("invalid number")
+						}
+						if val < 0 {
+							return fmt.Errorf("must be greater or equal to 0")
+						}
+						return nil
+					}),
+			),
+		).WithTheme(&s.theme)
+		if err := form.Run(); err != nil {
+			return nil, err
+		}
+	}
+
+	selectedDesiredCount, err := strconv.ParseInt(desiredCountStr, 10, 32)
 	if err != nil {
 		return nil, err
 	}
 
 	return &client.ServiceConfig{
 		TaskDefinitionArn: *taskDefinitionArn,
-		DesiredCount:      int32(desiredCount),
+		DesiredCount:      aws.Int32(int32(selectedDesiredCount)),
 	}, nil
 }
 
 func (s Selectors) Container(
 	ctx context.Context,
 	containers []types.Container,
+	containerFilter *Filter,
 ) (*types.Container, error) {
 	return Selector[types.Container]{
-		theme: s.theme,
+		theme:          s.theme,
+		nonInteractive: s.nonInteractive,
+		output:         s.output,
 		lister: func() ([]string, error) {
 			var names []string
 			for _, container := range containers {
@@ -235,31 +523,61 @@ func (s Selectors) Container(
 			}
 			return names, nil
 		},
-		describer: func(name string) ([]types.Container, error) {
-			return slices.DeleteFunc(containers, func(container types.Container) bool {
-				return *container.Name != name
+		describer: func(names []string) ([]types.Container, error) {
+			return slices.DeleteFunc(slices.Clone(containers), func(container types.Container) bool {
+				return !slices.Contains(names, *container.Name)
 			}), nil
 		},
-		pickers: func(names []string, selectedName *string) []huh.Field {
-			return []huh.Field{huh.NewSelect[string]().
-				Title("Select a container").
-				Options(huh.NewOptions(names...)...).
-				Value(selectedName).
-				WithHeight(5),
+		title:   "Select a container",
+		labeler: containerOptions,
+		formatter: func(selectedRes *types.Container) Selection {
+			return Selection{
+				title: "Container:",
+				value: *selectedRes.Name,
 			}
 		},
-		formatter: func(selectedRes *types.Container) Selection {
+	}.Run(containerFilter)
+}
+
+// ContainerDefinition picks a single container of a task definition.
+func (s Selectors) ContainerDefinition(
+	ctx context.Context,
+	containerDefinitions []types.ContainerDefinition,
+	containerFilter *Filter,
+) (*types.ContainerDefinition, error) {
+	return Selector[types.ContainerDefinition]{
+		theme:          s.theme,
+		nonInteractive: s.nonInteractive,
+		output:         s.output,
+		lister: func() ([]string, error) {
+			var names []string
+			for _, containerDefinition := range containerDefinitions {
+				names = append(names, *containerDefinition.Name)
+			}
+			return names, nil
+		},
+		describer: func(names []string) ([]types.ContainerDefinition, error) {
+			return slices.DeleteFunc(
+				slices.Clone(containerDefinitions),
+				func(containerDefinition types.ContainerDefinition) bool {
+					return !slices.Contains(names, *containerDefinition.Name)
+				},
+			), nil
+		},
+		title: "Select a container",
+		formatter: func(selectedRes *types.ContainerDefinition) Selection {
 			return Selection{
 				title: "Container:",
 				value: *selectedRes.Name,
 			}
 		},
-	}.Run(nil)
+	}.Run(containerFilter)
 }
 
 func (s Selectors) ContainerDefinitions(
 	ctx context.Context,
 	taskDefinitionArn string,
+	containerFilter *Filter,
 ) ([]types.ContainerDefinition, error) {
 	taskDefinition, err := s.client.DescribeTaskDefinition(ctx, taskDefinitionArn)
 	if err != nil {
@@ -271,10 +589,17 @@ func (s Selectors) ContainerDefinitions(
 		containerNames = append(containerNames, *containerDefinition.Name)
 	}
 
+	containerNames = containerFilter.Apply(containerNames)
+	if len(containerNames) == 0 {
+		return nil, fmt.Errorf("no containers matching %s", containerFilter)
+	}
+
 	var selectedContainerNames []string
 	if len(containerNames) == 1 {
 		log.Printf("Pre-selecting the only available container")
 		selectedContainerNames = append(selectedContainerNames, containerNames[0])
+	} else if s.nonInteractive {
+		return nil, ambiguousError(containerNames)
 	} else {
 		form := huh.NewForm(
 			huh.NewGroup(
@@ -305,7 +630,8 @@ func (s Selectors) ContainerDefinitions(
 		return nil, fmt.Errorf("no containers selected")
 	}
 
-	fmt.Printf(
+	fmt.Fprintf(
+		s.output,
 		"%s %s\n",
 		titleStyle.Render("Container(s):"),
 		strings.Join(selectedContainerNames, ","),
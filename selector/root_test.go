@@ -0,0 +1,174 @@
+package selector
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ecs/types"
+	"github.com/charmbracelet/huh"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTaskOptions_Running(t *testing.T) {
+	now := time.Now()
+	tasks := []types.Task{
+		{
+			TaskArn:           aws.String("arn:aws:ecs:us-east-1:123456789012:task/my-cluster/1234"),
+			TaskDefinitionArn: aws.String("arn:aws:ecs:us-east-1:123456789012:task-definition/api:3"),
+			LastStatus:        aws.String("RUNNING"),
+			HealthStatus:      types.HealthStatusHealthy,
+			LaunchType:        types.LaunchTypeFargate,
+			StartedAt:         aws.Time(now.Add(-time.Hour)),
+		},
+		{
+			TaskArn:           aws.String("arn:aws:ecs:us-east-1:123456789012:task/my-cluster/56789"),
+			TaskDefinitionArn: aws.String("arn:aws:ecs:us-east-1:123456789012:task-definition/api:4"),
+			LastStatus:        aws.String("PENDING"),
+			HealthStatus:      types.HealthStatusUnknown,
+			LaunchType:        types.LaunchTypeFargate,
+		},
+	}
+
+	assert.Equal(
+		t,
+		[]huh.Option[string]{
+			huh.NewOption("1234   RUNNING  HEALTHY  api:3  FARGATE  started 1h0m0s ago", *tasks[0].TaskArn),
+			huh.NewOption("56789  PENDING  -        api:4  FARGATE", *tasks[1].TaskArn),
+		},
+		taskOptions(tasks, now),
+	)
+}
+
+func TestTaskOptions_Stopped(t *testing.T) {
+	now := time.Now()
+	task := types.Task{
+		TaskArn:       aws.String("arn:aws:ecs:us-east-1:123456789012:task/my-cluster/1234"),
+		LastStatus:    aws.String("STOPPED"),
+		StoppedAt:     aws.Time(now.Add(-5 * time.Minute)),
+		StoppedReason: aws.String("Essential container in task exited"),
+		Containers: []types.Container{
+			{Name: aws.String("app"), ExitCode: aws.Int32(137)},
+			{Name: aws.String("sidecar")},
+		},
+	}
+
+	label := taskOptions([]types.Task{task}, now)[0].Key
+
+	assert.Contains(t, label, "STOPPED")
+	assert.Contains(t, label, "stopped 5m0s ago")
+	assert.Contains(t, label, "Essential container in task exited")
+	assert.Contains(t, label, "exit codes: app=137")
+}
+
+func TestServiceOptions(t *testing.T) {
+	services := []types.Service{
+		{
+			ServiceArn:   aws.String("arn:aws:ecs:us-east-1:123456789012:service/my-cluster/api"),
+			ServiceName:  aws.String("api"),
+			Status:       aws.String("ACTIVE"),
+			DesiredCount: 3,
+			RunningCount: 2,
+			LaunchType:   types.LaunchTypeFargate,
+			Deployments:  []types.Deployment{{}, {}},
+		},
+		{
+			ServiceArn:   aws.String("arn:aws:ecs:us-east-1:123456789012:service/my-cluster/worker"),
+			ServiceName:  aws.String("worker"),
+			Status:       aws.String("ACTIVE"),
+			DesiredCount: 1,
+			RunningCount: 1,
+			CapacityProviderStrategy: []types.CapacityProviderStrategyItem{
+				{CapacityProvider: aws.String("FARGATE")},
+				{CapacityProvider: aws.String("FARGATE_SPOT")},
+			},
+		},
+	}
+
+	assert.Equal(
+		t,
+		[]huh.Option[string]{
+			huh.NewOption("api     ACTIVE  2/3 running  FARGATE               deploying", *services[0].ServiceArn),
+			huh.NewOption("worker  ACTIVE  1/1 running  FARGATE+FARGATE_SPOT", *services[1].ServiceArn),
+		},
+		serviceOptions(services),
+	)
+}
+
+func TestClusterOptions_SingleRegion(t *testing.T) {
+	clusters := []types.Cluster{
+		{
+			ClusterArn:          aws.String("arn:aws:ecs:us-east-1:123456789012:cluster/api"),
+			Status:              aws.String("ACTIVE"),
+			ActiveServicesCount: 2,
+			RunningTasksCount:   5,
+		},
+		{
+			ClusterArn:          aws.String("arn:aws:ecs:us-east-1:123456789012:cluster/web"),
+			Status:              aws.String("ACTIVE"),
+			ActiveServicesCount: 10,
+			RunningTasksCount:   12,
+		},
+	}
+
+	assert.Equal(
+		t,
+		[]huh.Option[string]{
+			huh.NewOption("api  ACTIVE  2 services   5 tasks", *clusters[0].ClusterArn),
+			huh.NewOption("web  ACTIVE  10 services  12 tasks", *clusters[1].ClusterArn),
+		},
+		clusterOptions(clusters),
+	)
+}
+
+func TestClusterOptions_MultipleRegions(t *testing.T) {
+	clusters := []types.Cluster{
+		{ClusterArn: aws.String("arn:aws:ecs:us-east-1:123456789012:cluster/api"), Status: aws.String("ACTIVE")},
+		{ClusterArn: aws.String("arn:aws:ecs:eu-west-1:210987654321:cluster/api"), Status: aws.String("ACTIVE")},
+	}
+
+	assert.Equal(
+		t,
+		[]huh.Option[string]{
+			huh.NewOption("api  ACTIVE  0 services  0 tasks  us-east-1, 123456789012", *clusters[0].ClusterArn),
+			huh.NewOption("api  ACTIVE  0 services  0 tasks  eu-west-1, 210987654321", *clusters[1].ClusterArn),
+		},
+		clusterOptions(clusters),
+	)
+}
+
+func TestContainerOptions(t *testing.T) {
+	containers := []types.Container{
+		{Name: aws.String("app"), LastStatus: aws.String("RUNNING"), HealthStatus: types.HealthStatusHealthy},
+		{Name: aws.String("log-router"), LastStatus: aws.String("RUNNING")},
+	}
+
+	assert.Equal(
+		t,
+		[]huh.Option[string]{
+			huh.NewOption("app         RUNNING  HEALTHY", "app"),
+			huh.NewOption("log-router  RUNNING  -", "log-router"),
+		},
+		containerOptions(containers),
+	)
+}
+
+func TestContainerDefinition(t *testing.T) {
+	containerDefinitions := []types.ContainerDefinition{
+		{Name: aws.String("app")},
+		{Name: aws.String("log-router")},
+	}
+	selectors := Selectors{nonInteractive: true, output: io.Discard}
+
+	filter, err := NewFilter("app")
+	require.NoError(t, err)
+	containerDefinition, err := selectors.ContainerDefinition(context.Background(), containerDefinitions, filter)
+	require.NoError(t, err)
+	assert.Equal(t, "app", *containerDefinition.Name)
+
+	_, err = selectors.ContainerDefinition(context.Background(), containerDefinitions, nil)
+	assert.ErrorContains(t, err, "ambiguous: 2 matches")
+}
@@ -2,17 +2,26 @@ package selector
 
 import (
 	"fmt"
-	"regexp"
+	"io"
 	"slices"
+	"strings"
 
 	"github.com/charmbracelet/huh"
 )
 
+// pickerHeight is the number of options shown at once by the pickers.
+const pickerHeight = 10
+
 type Selector[T any] struct {
-	theme     huh.Theme
-	lister    func() ([]string, error)
-	describer func(arn string) ([]T, error)
-	pickers   func(arns []string, selectedArn *string) []huh.Field
+	theme          huh.Theme
+	nonInteractive bool
+	output         io.Writer
+	title          string
+	lister         func() ([]string, error)
+	describer      func(arns []string) ([]T, error)
+	// labeler describes the listed resources in the picker, returning one
+	// option per resource in the same order. The ARNs are shown when not set.
+	labeler   func(res []T) []huh.Option[string]
 	formatter func(selectedRes *T) Selection
 }
 
@@ -21,43 +30,109 @@ type Selection struct {
 	value string
 }
 
-func (selector Selector[T]) Run(arnRegex *regexp.Regexp) (*T, error) {
+func (selector Selector[T]) Run(filter *Filter) (*T, error) {
 	arns, err := selector.lister()
 	if err != nil {
 		return nil, err
 	}
 
-	if arnRegex != nil {
-		arns = slices.DeleteFunc(arns, func(arn string) bool {
-			return !arnRegex.MatchString(arn)
-		})
-	}
-
+	arns = filter.Apply(arns)
 	if len(arns) == 0 {
 		return nil, fmt.Errorf("no resources available")
 	}
 
-	var selectedArn string
+	var selectedRes *T
 	if len(arns) == 1 {
-		selectedArn = arns[0]
+		res, err := selector.describer(arns)
+		if err != nil {
+			return nil, err
+		}
+		if len(res) == 1 {
+			selectedRes = &res[0]
+		}
+	} else if selector.nonInteractive {
+		return nil, ambiguousError(arns)
+	} else if selector.labeler != nil {
+		// The resources are described once, for both the labels and the result
+		res, err := selector.describer(arns)
+		if err != nil {
+			return nil, err
+		}
+		options := selector.labeler(res)
+		selectedArn, err := selector.pick(options)
+		if err != nil {
+			return nil, err
+		}
+		index := slices.IndexFunc(options, func(option huh.Option[string]) bool {
+			return option.Value == selectedArn
+		})
+		if index != -1 {
+			selectedRes = &res[index]
+		}
 	} else {
-		form := huh.NewForm(huh.NewGroup(selector.pickers(arns, &selectedArn)...)).WithTheme(&selector.theme)
-		if err = form.Run(); err != nil {
+		selectedArn, err := selector.pick(huh.NewOptions(arns...))
+		if err != nil {
+			return nil, err
+		}
+		res, err := selector.describer([]string{selectedArn})
+		if err != nil {
 			return nil, err
 		}
+		if len(res) == 1 {
+			selectedRes = &res[0]
+		}
 	}
 
-	res, err := selector.describer(selectedArn)
-	if err != nil {
-		return nil, err
+	if selectedRes == nil {
+		return nil, nil
+	}
+
+	selection := selector.formatter(selectedRes)
+	fmt.Fprintf(selector.output, "%s %s\n", titleStyle.Render(selection.title), selection.value)
+	return selectedRes, nil
+}
+
+// pick shows the picker and returns the value of the chosen option. Typing
+// narrows down the options by fuzzy matching their labels.
+func (selector Selector[T]) pick(options []huh.Option[string]) (string, error) {
+	var selected string
+	form := huh.NewForm(huh.NewGroup(newFuzzySelect(selector.title, options, &selected))).
+		WithTheme(&selector.theme)
+	if err := form.Run(); err != nil {
+		return "", err
 	}
+	return selected, nil
+}
 
-	if len(res) == 1 {
-		selectedRes := &res[0]
-		selection := selector.formatter(selectedRes)
-		fmt.Printf("%s %s\n", titleStyle.Render(selection.title), selection.value)
-		return selectedRes, nil
+// columns pads the cells of each row so the columns line up.
+func columns(rows [][]string) []string {
+	var widths []int
+	for _, row := range rows {
+		for i, cell := range row {
+			if i == len(widths) {
+				widths = append(widths, 0)
+			}
+			widths[i] = max(widths[i], len([]rune(cell)))
+		}
 	}
 
-	return nil, nil
+	lines := make([]string, 0, len(rows))
+	for _, row := range rows {
+		var line strings.Builder
+		for i, cell := range row {
+			if i > 0 {
+				line.WriteString("  ")
+			}
+			line.WriteString(cell)
+			if i < len(row)-1 {
+				line.WriteString(strings.Repeat(" ", widths[i]-len([]rune(cell))))
+			}
+		}
+		lines = append(lines, line.String())
+	}
+	return lines
+}
+
+func ambiguousError(ids []string) error {
+	return fmt.Errorf("ambiguous: %d matches: %s", len(ids), strings.Join(ids, ", "))
 }
@@ -0,0 +1,50 @@
+package selector
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSelector_DescribesSingleMatch(t *testing.T) {
+	filter, err := NewFilter("my-cluster/web")
+	require.NoError(t, err)
+
+	var described [][]string
+	var output bytes.Buffer
+	selected, err := Selector[string]{
+		nonInteractive: true,
+		output:         &output,
+		lister: func() ([]string, error) {
+			return serviceArns, nil
+		},
+		describer: func(arns []string) ([]string, error) {
+			described = append(described, arns)
+			return arns, nil
+		},
+		formatter: func(selectedRes *string) Selection {
+			return Selection{title: "Service:", value: *selectedRes}
+		},
+	}.Run(filter)
+
+	require.NoError(t, err)
+	assert.Equal(t, serviceArns[2], *selected)
+	assert.Equal(t, [][]string{{serviceArns[2]}}, described)
+	assert.Contains(t, output.String(), serviceArns[2])
+}
+
+func TestColumns(t *testing.T) {
+	assert.Equal(
+		t,
+		[]string{
+			"api     ACTIVE",
+			"worker  DRAINING  deploying",
+		},
+		columns([][]string{
+			{"api", "ACTIVE"},
+			{"worker", "DRAINING", "deploying"},
+		}),
+	)
+}
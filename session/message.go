@@ -0,0 +1,146 @@
+package session
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"time"
+)
+
+// Types of the messages exchanged over the data channel.
+const (
+	inputStreamMessage      = "input_stream_data"
+	outputStreamMessage     = "output_stream_data"
+	acknowledgeMessage      = "acknowledge"
+	channelClosedMessage    = "channel_closed"
+	startPublicationMessage = "start_publication"
+	pausePublicationMessage = "pause_publication"
+)
+
+// payloadType tells how the payload of a stream message is interpreted.
+type payloadType uint32
+
+const (
+	outputPayload               payloadType = 1
+	errorPayload                payloadType = 2
+	sizePayload                 payloadType = 3
+	parameterPayload            payloadType = 4
+	handshakeRequestPayload     payloadType = 5
+	handshakeResponsePayload    payloadType = 6
+	handshakeCompletePayload    payloadType = 7
+	encChallengeRequestPayload  payloadType = 8
+	encChallengeResponsePayload payloadType = 9
+	flagPayload                 payloadType = 10
+	stdErrPayload               payloadType = 11
+	exitCodePayload             payloadType = 12
+)
+
+// Layout of the message header, the payload follows its length.
+const (
+	headerLength         = 116
+	messageTypeOffset    = 4
+	messageTypeLength    = 32
+	schemaVersionOffset  = 36
+	createdDateOffset    = 40
+	sequenceNumberOffset = 48
+	flagsOffset          = 56
+	messageIdOffset      = 64
+	payloadDigestOffset  = 80
+	payloadTypeOffset    = 112
+	payloadLengthOffset  = 116
+	payloadOffset        = 120
+)
+
+// message is a binary message of the data channel, in the format shared by
+// the SSM agent and session-manager-plugin.
+type message struct {
+	messageType    string
+	schemaVersion  uint32
+	createdDate    uint64
+	sequenceNumber int64
+	flags          uint64
+	id             uuid
+	payloadType    payloadType
+	payload        []byte
+}
+
+func newMessage(messageType string, sequenceNumber int64, payloadType payloadType, payload []byte) message {
+	return message{
+		messageType:    messageType,
+		schemaVersion:  1,
+		createdDate:    uint64(time.Now().UnixMilli()),
+		sequenceNumber: sequenceNumber,
+		id:             newUUID(),
+		payloadType:    payloadType,
+		payload:        payload,
+	}
+}
+
+func (m message) marshal() []byte {
+	data := make([]byte, payloadOffset+len(m.payload))
+	binary.BigEndian.PutUint32(data, headerLength)
+	copy(data[messageTypeOffset:], fmt.Sprintf("%-*s", messageTypeLength, m.messageType))
+	binary.BigEndian.PutUint32(data[schemaVersionOffset:], m.schemaVersion)
+	binary.BigEndian.PutUint64(data[createdDateOffset:], m.createdDate)
+	binary.BigEndian.PutUint64(data[sequenceNumberOffset:], uint64(m.sequenceNumber))
+	binary.BigEndian.PutUint64(data[flagsOffset:], m.flags)
+	// The least significant half of the ID goes first
+	copy(data[messageIdOffset:], m.id[8:])
+	copy(data[messageIdOffset+8:], m.id[:8])
+	digest := sha256.Sum256(m.payload)
+	copy(data[payloadDigestOffset:], digest[:])
+	binary.BigEndian.PutUint32(data[payloadTypeOffset:], uint32(m.payloadType))
+	binary.BigEndian.PutUint32(data[payloadLengthOffset:], uint32(len(m.payload)))
+	copy(data[payloadOffset:], m.payload)
+	return data
+}
+
+func unmarshalMessage(data []byte) (message, error) {
+	if len(data) < payloadOffset {
+		return message{}, fmt.Errorf("message too short: %d bytes", len(data))
+	}
+
+	// The header length points at the payload length, which newer agents may
+	// move further to fit more fields
+	length := int(binary.BigEndian.Uint32(data))
+	if length < headerLength || length+4 > len(data) {
+		return message{}, fmt.Errorf("invalid header length: %d", length)
+	}
+	payloadLength := int(binary.BigEndian.Uint32(data[length:]))
+	if length+4+payloadLength > len(data) {
+		return message{}, fmt.Errorf("invalid payload length: %d", payloadLength)
+	}
+
+	var id uuid
+	copy(id[8:], data[messageIdOffset:])
+	copy(id[:8], data[messageIdOffset+8:])
+
+	messageType := data[messageTypeOffset : messageTypeOffset+messageTypeLength]
+	return message{
+		messageType:    string(bytes.TrimRight(messageType, " \x00")),
+		schemaVersion:  binary.BigEndian.Uint32(data[schemaVersionOffset:]),
+		createdDate:    binary.BigEndian.Uint64(data[createdDateOffset:]),
+		sequenceNumber: int64(binary.BigEndian.Uint64(data[sequenceNumberOffset:])),
+		flags:          binary.BigEndian.Uint64(data[flagsOffset:]),
+		id:             id,
+		payloadType:    payloadType(binary.BigEndian.Uint32(data[payloadTypeOffset:])),
+		payload:        data[length+4 : length+4+payloadLength],
+	}, nil
+}
+
+// uuid is a random (version 4) UUID.
+type uuid [16]byte
+
+func newUUID() uuid {
+	var id uuid
+	_, _ = rand.Read(id[:])
+	id[6] = id[6]&0x0f | 0x40
+	id[8] = id[8]&0x3f | 0x80
+	return id
+}
+
+func (id uuid) String() string {
+	return fmt.Sprintf("%x-%x-%x-%x-%x", id[:4], id[4:6], id[6:8], id[8:10], id[10:])
+}
@@ -0,0 +1,412 @@
+// Package session implements the client side of the Session Manager data
+// channel, the websocket protocol spoken by session-manager-plugin, to run
+// the sessions started by ECS ExecuteCommand without the plugin.
+package session
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// EnvVar holds the session, as JSON, for the process running it.
+const EnvVar = "IECS_SESSION"
+
+// clientVersion is the session-manager-plugin version the client behaves
+// like, the agent enables features based on it.
+const clientVersion = "1.2.0.0"
+
+// Statuses of the actions requested by the agent during the handshake.
+const (
+	actionSuccess = 1
+	actionFailed  = 2
+)
+
+// terminateSessionFlag asks the agent to end the session, as
+// session-manager-plugin does when it is interrupted.
+const terminateSessionFlag uint32 = 2
+
+// ErrEncryptionNotSupported is returned when the agent requires the session
+// to be encrypted with KMS, which the client does not implement.
+var ErrEncryptionNotSupported = errors.New("KMS encryption is not supported")
+
+// Session identifies a session started by the ECS ExecuteCommand or SSM
+// StartSession APIs.
+type Session struct {
+	SessionId  string
+	StreamUrl  string
+	TokenValue string
+}
+
+// Size is the size of the local terminal.
+type Size struct {
+	Cols int `json:"cols"`
+	Rows int `json:"rows"`
+}
+
+// Options are the streams a session is attached to.
+type Options struct {
+	Stdin  io.Reader
+	Stdout io.Writer
+	Stderr io.Writer
+	// Resize delivers the size of the terminal, it is read from once the
+	// handshake completes.
+	Resize <-chan Size
+	// TLSConfig overrides the TLS configuration of the connection.
+	TLSConfig *tls.Config
+}
+
+type openDataChannelInput struct {
+	MessageSchemaVersion string
+	RequestId            string
+	TokenValue           string
+	ClientId             string
+	ClientVersion        string
+}
+
+type handshakeRequest struct {
+	AgentVersion           string
+	RequestedClientActions []struct {
+		ActionType       string
+		ActionParameters json.RawMessage
+	}
+}
+
+type processedClientAction struct {
+	ActionType   string
+	ActionStatus int
+	Error        string
+}
+
+type handshakeResponse struct {
+	ClientVersion          string
+	ProcessedClientActions []processedClientAction
+	Errors                 []string
+}
+
+type handshakeComplete struct {
+	CustomerMessage string
+}
+
+type acknowledge struct {
+	AcknowledgedMessageType           string
+	AcknowledgedMessageId             string
+	AcknowledgedMessageSequenceNumber int64
+	IsSequentialMessage               bool
+}
+
+type channelClosed struct {
+	SessionId string
+	Output    string
+}
+
+// Run attaches the streams to the session until the agent closes it, and
+// returns the exit code it reports, 0 when it does not. Once ctx is done, the
+// agent is asked to terminate the session.
+func Run(ctx context.Context, session Session, options Options) (int, error) {
+	parent := ctx
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	conn, err := dial(ctx, session.StreamUrl, options.TLSConfig)
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Close()
+
+	c := &client{
+		ctx:      ctx,
+		conn:     conn,
+		options:  options,
+		received: map[int64]message{},
+		ready:    make(chan struct{}),
+	}
+
+	// Unblock the reads once the context is done
+	go func() {
+		<-ctx.Done()
+		if parent.Err() != nil {
+			c.terminate()
+		}
+		conn.Close()
+	}()
+
+	openDataChannel, err := json.Marshal(openDataChannelInput{
+		MessageSchemaVersion: "1.0",
+		RequestId:            newUUID().String(),
+		TokenValue:           session.TokenValue,
+		ClientId:             newUUID().String(),
+		ClientVersion:        clientVersion,
+	})
+	if err != nil {
+		return 0, err
+	}
+	if err := conn.writeMessage(opText, openDataChannel); err != nil {
+		return 0, err
+	}
+
+	exitCode, err := c.run()
+	if err != nil && ctx.Err() != nil {
+		return exitCode, ctx.Err()
+	}
+	return exitCode, err
+}
+
+type client struct {
+	ctx     context.Context
+	conn    *conn
+	options Options
+	// sendMu guards the sequence number of the messages sent
+	sendMu       sync.Mutex
+	sequence     int64
+	nextReceived int64
+	// received holds the messages that arrived ahead of their turn
+	received  map[int64]message
+	ready     chan struct{}
+	readyOnce sync.Once
+	exitCode  *int
+}
+
+func (c *client) run() (int, error) {
+	go c.forwardInput()
+	go c.forwardResize()
+
+	for {
+		opcode, data, err := c.conn.readMessage()
+		if err != nil {
+			if errors.Is(err, io.EOF) && c.exitCode != nil {
+				return *c.exitCode, nil
+			}
+			return c.code(), fmt.Errorf("data channel closed: %w", err)
+		}
+		if opcode != opBinary {
+			continue
+		}
+
+		msg, err := unmarshalMessage(data)
+		if err != nil {
+			return c.code(), err
+		}
+
+		switch msg.messageType {
+		case outputStreamMessage:
+			if err := c.receive(msg); err != nil {
+				return c.code(), err
+			}
+		case channelClosedMessage:
+			var closed channelClosed
+			if err := json.Unmarshal(msg.payload, &closed); err == nil && closed.Output != "" {
+				fmt.Fprintln(c.options.Stderr, closed.Output)
+			}
+			return c.code(), nil
+		case acknowledgeMessage, startPublicationMessage, pausePublicationMessage:
+			// The connection is reliable, the messages sent are not kept
+			// around to be resent
+		}
+	}
+}
+
+func (c *client) code() int {
+	if c.exitCode == nil {
+		return 0
+	}
+	return *c.exitCode
+}
+
+// receive acknowledges a stream message and handles it in sequence, along
+// with the messages that arrived ahead of it.
+func (c *client) receive(msg message) error {
+	// A connection that broke shows up on the next read, which also returns
+	// the messages left when the agent closes the channel
+	_ = c.acknowledge(msg)
+	if msg.sequenceNumber < c.nextReceived {
+		// Resent by the agent, the acknowledgement got lost
+		return nil
+	}
+	c.received[msg.sequenceNumber] = msg
+
+	for {
+		next, ok := c.received[c.nextReceived]
+		if !ok {
+			return nil
+		}
+		delete(c.received, c.nextReceived)
+		c.nextReceived++
+		if err := c.handle(next); err != nil {
+			return err
+		}
+	}
+}
+
+func (c *client) acknowledge(msg message) error {
+	payload, err := json.Marshal(acknowledge{
+		AcknowledgedMessageType:           msg.messageType,
+		AcknowledgedMessageId:             msg.id.String(),
+		AcknowledgedMessageSequenceNumber: msg.sequenceNumber,
+		IsSequentialMessage:               true,
+	})
+	if err != nil {
+		return err
+	}
+	ack := newMessage(acknowledgeMessage, 0, 0, payload)
+	ack.flags = 3
+	return c.conn.writeMessage(opBinary, ack.marshal())
+}
+
+func (c *client) handle(msg message) error {
+	switch msg.payloadType {
+	case outputPayload:
+		_, err := c.options.Stdout.Write(msg.payload)
+		return err
+	case stdErrPayload:
+		_, err := c.options.Stderr.Write(msg.payload)
+		return err
+	case exitCodePayload:
+		exitCode, err := parseExitCode(msg.payload)
+		if err != nil {
+			return err
+		}
+		c.exitCode = &exitCode
+	case handshakeRequestPayload:
+		return c.handshake(msg.payload)
+	case handshakeCompletePayload:
+		var complete handshakeComplete
+		if err := json.Unmarshal(msg.payload, &complete); err != nil {
+			return err
+		}
+		if complete.CustomerMessage != "" {
+			fmt.Fprintln(c.options.Stderr, complete.CustomerMessage)
+		}
+		c.readyOnce.Do(func() { close(c.ready) })
+	case encChallengeRequestPayload:
+		return ErrEncryptionNotSupported
+	}
+	return nil
+}
+
+// handshake accepts the type of the session and turns down encryption.
+func (c *client) handshake(payload []byte) error {
+	var request handshakeRequest
+	if err := json.Unmarshal(payload, &request); err != nil {
+		return err
+	}
+
+	response := handshakeResponse{ClientVersion: clientVersion, Errors: []string{}}
+	var encrypted bool
+	for _, action := range request.RequestedClientActions {
+		processed := processedClientAction{ActionType: action.ActionType, ActionStatus: actionSuccess}
+		if action.ActionType != "SessionType" {
+			encrypted = encrypted || action.ActionType == "KMSEncryption"
+			processed.ActionStatus = actionFailed
+			processed.Error = fmt.Sprintf("%s is not supported", action.ActionType)
+			response.Errors = append(response.Errors, processed.Error)
+		}
+		response.ProcessedClientActions = append(response.ProcessedClientActions, processed)
+	}
+
+	data, err := json.Marshal(response)
+	if err != nil {
+		return err
+	}
+	if err := c.send(handshakeResponsePayload, data); err != nil {
+		return err
+	}
+	if encrypted {
+		return ErrEncryptionNotSupported
+	}
+	return nil
+}
+
+// terminate asks the agent to end the session, so the remote command does
+// not outlive the client. Before the handshake completes, there is no session
+// to terminate yet.
+func (c *client) terminate() {
+	select {
+	case <-c.ready:
+	default:
+		return
+	}
+	_ = c.conn.netConn.SetWriteDeadline(time.Now().Add(time.Second))
+	_ = c.send(flagPayload, binary.BigEndian.AppendUint32(nil, terminateSessionFlag))
+}
+
+// send writes a stream message with the next sequence number.
+func (c *client) send(payloadType payloadType, payload []byte) error {
+	c.sendMu.Lock()
+	defer c.sendMu.Unlock()
+	msg := newMessage(inputStreamMessage, c.sequence, payloadType, payload)
+	if err := c.conn.writeMessage(opBinary, msg.marshal()); err != nil {
+		return err
+	}
+	c.sequence++
+	return nil
+}
+
+func (c *client) forwardInput() {
+	if c.options.Stdin == nil {
+		return
+	}
+	select {
+	case <-c.ready:
+	case <-c.ctx.Done():
+		return
+	}
+
+	buf := make([]byte, 1024)
+	for {
+		n, err := c.options.Stdin.Read(buf)
+		if n > 0 {
+			if c.send(outputPayload, append([]byte(nil), buf[:n]...)) != nil {
+				return
+			}
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+func (c *client) forwardResize() {
+	if c.options.Resize == nil {
+		return
+	}
+	select {
+	case <-c.ready:
+	case <-c.ctx.Done():
+		return
+	}
+
+	for {
+		select {
+		case <-c.ctx.Done():
+			return
+		case size, ok := <-c.options.Resize:
+			if !ok {
+				return
+			}
+			data, err := json.Marshal(size)
+			if err != nil || c.send(sizePayload, data) != nil {
+				return
+			}
+		}
+	}
+}
+
+// parseExitCode reads an exit code sent as text or as a 32-bit integer.
+func parseExitCode(payload []byte) (int, error) {
+	if exitCode, err := strconv.Atoi(strings.TrimSpace(string(payload))); err == nil {
+		return exitCode, nil
+	}
+	if len(payload) == 4 {
+		return int(int32(binary.BigEndian.Uint32(payload))), nil
+	}
+	return 0, fmt.Errorf("invalid exit code: %q", payload)
+}
@@ -0,0 +1,285 @@
+package session
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeAgent is a stand-in for the data channel endpoint of the SSM agent, it
+// plays the given script once a client connects.
+type fakeAgent struct {
+	t        *testing.T
+	token    string
+	script   func(agent *fakeAgent)
+	reader   *bufio.Reader
+	writer   *bufio.ReadWriter
+	writeMu  sync.Mutex
+	sequence int64
+	acks     []acknowledge
+	done     chan struct{}
+}
+
+func newFakeAgent(t *testing.T, script func(agent *fakeAgent)) (*fakeAgent, Session) {
+	agent := &fakeAgent{t: t, token: "token", script: script, done: make(chan struct{})}
+	server := httptest.NewServer(agent)
+	t.Cleanup(server.Close)
+
+	return agent, Session{
+		SessionId:  "ecs-execute-command-1",
+		StreamUrl:  "ws" + strings.TrimPrefix(server.URL, "http") + "/v1/data-channel/ecs-execute-command-1?role=publish_subscribe",
+		TokenValue: agent.token,
+	}
+}
+
+func (a *fakeAgent) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	defer close(a.done)
+
+	if r.Header.Get("Upgrade") != "websocket" || r.URL.Query().Get("role") != "publish_subscribe" {
+		http.Error(w, "expecting a websocket upgrade", http.StatusBadRequest)
+		return
+	}
+
+	netConn, rw, err := w.(http.Hijacker).Hijack()
+	if err != nil {
+		a.t.Errorf("unable to hijack the connection: %v", err)
+		return
+	}
+	defer netConn.Close()
+
+	_, _ = rw.WriteString("HTTP/1.1 101 Switching Protocols\r\n" +
+		"Upgrade: websocket\r\n" +
+		"Connection: Upgrade\r\n" +
+		"Sec-WebSocket-Accept: " + acceptKey(r.Header.Get("Sec-WebSocket-Key")) + "\r\n\r\n")
+	_ = rw.Flush()
+	a.reader = rw.Reader
+	a.writer = rw
+
+	fin, opcode, payload, err := readFrame(a.reader)
+	if !assert.NoError(a.t, err) || !assert.True(a.t, fin) || !assert.Equal(a.t, opText, opcode) {
+		return
+	}
+	var input openDataChannelInput
+	if assert.NoError(a.t, json.Unmarshal(payload, &input)) {
+		assert.Equal(a.t, a.token, input.TokenValue)
+		assert.Equal(a.t, clientVersion, input.ClientVersion)
+	}
+
+	a.script(a)
+}
+
+func (a *fakeAgent) write(opcode byte, data []byte) {
+	a.writeMu.Lock()
+	defer a.writeMu.Unlock()
+	assert.NoError(a.t, writeFrame(a.writer, opcode, data, false))
+	assert.NoError(a.t, a.writer.Flush())
+}
+
+// sendAt sends a stream message with the given sequence number.
+func (a *fakeAgent) sendAt(sequence int64, payloadType payloadType, payload string) {
+	msg := newMessage(outputStreamMessage, sequence, payloadType, []byte(payload))
+	a.write(opBinary, msg.marshal())
+}
+
+// send sends a stream message with the next sequence number.
+func (a *fakeAgent) send(payloadType payloadType, payload string) {
+	a.sendAt(a.sequence, payloadType, payload)
+	a.sequence++
+}
+
+// receive returns the next stream message sent by the client, keeping the
+// acknowledgements aside.
+func (a *fakeAgent) receive() message {
+	for {
+		fin, opcode, data, err := readFrame(a.reader)
+		require.NoError(a.t, err)
+		require.True(a.t, fin)
+		if opcode != opBinary {
+			continue
+		}
+
+		msg, err := unmarshalMessage(data)
+		require.NoError(a.t, err)
+		if msg.messageType == acknowledgeMessage {
+			var ack acknowledge
+			require.NoError(a.t, json.Unmarshal(msg.payload, &ack))
+			a.acks = append(a.acks, ack)
+			continue
+		}
+		return msg
+	}
+}
+
+func (a *fakeAgent) handshake(actions string) handshakeResponse {
+	a.send(handshakeRequestPayload, `{"AgentVersion":"3.3.0.0","RequestedClientActions":`+actions+`}`)
+
+	msg := a.receive()
+	assert.Equal(a.t, inputStreamMessage, msg.messageType)
+	assert.Equal(a.t, int64(0), msg.sequenceNumber)
+	assert.Equal(a.t, handshakeResponsePayload, msg.payloadType)
+
+	var response handshakeResponse
+	assert.NoError(a.t, json.Unmarshal(msg.payload, &response))
+	return response
+}
+
+func TestRun(t *testing.T) {
+	agent, session := newFakeAgent(t, func(agent *fakeAgent) {
+		response := agent.handshake(`[{"ActionType":"SessionType","ActionParameters":{"SessionType":"InteractiveCommands"}}]`)
+		assert.Equal(t, []processedClientAction{{ActionType: "SessionType", ActionStatus: actionSuccess}}, response.ProcessedClientActions)
+		agent.send(handshakeCompletePayload, `{"HandshakeTimeToComplete":1000000,"CustomerMessage":""}`)
+
+		// The input and the size of the terminal are only sent once the
+		// handshake completes
+		var input []string
+		var sizes []string
+		for len(input) == 0 || len(sizes) == 0 {
+			msg := agent.receive()
+			assert.Equal(t, inputStreamMessage, msg.messageType)
+			switch msg.payloadType {
+			case outputPayload:
+				input = append(input, string(msg.payload))
+			case sizePayload:
+				sizes = append(sizes, string(msg.payload))
+			}
+		}
+		assert.Equal(t, []string{"whoami\n"}, input)
+		assert.Equal(t, []string{`{"cols":80,"rows":24}`}, sizes)
+
+		agent.write(opPing, []byte("ping"))
+		// Out of order, and resent
+		agent.sendAt(3, outputPayload, "world\r\n")
+		agent.sendAt(2, outputPayload, "hello ")
+		agent.sendAt(2, outputPayload, "hello ")
+		agent.sequence = 4
+		agent.send(stdErrPayload, "warning\n")
+		agent.send(exitCodePayload, "3")
+
+		closed := newMessage(channelClosedMessage, 0, 0, []byte(`{"SessionId":"ecs-execute-command-1","Output":""}`))
+		agent.write(opBinary, closed.marshal())
+	})
+
+	resize := make(chan Size, 1)
+	resize <- Size{Cols: 80, Rows: 24}
+	var stdout, stderr bytes.Buffer
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	exitCode, err := Run(ctx, session, Options{
+		Stdin:  strings.NewReader("whoami\n"),
+		Stdout: &stdout,
+		Stderr: &stderr,
+		Resize: resize,
+	})
+	<-agent.done
+
+	require.NoError(t, err)
+	assert.Equal(t, 3, exitCode)
+	assert.Equal(t, "hello world\r\n", stdout.String())
+	assert.Equal(t, "warning\n", stderr.String())
+
+	var sequences []int64
+	for _, ack := range agent.acks {
+		assert.Equal(t, outputStreamMessage, ack.AcknowledgedMessageType)
+		sequences = append(sequences, ack.AcknowledgedMessageSequenceNumber)
+	}
+	// The last messages may not be acknowledged before the channel closes
+	assert.GreaterOrEqual(t, len(sequences), 2)
+	assert.Equal(t, []int64{0, 1}, sequences[:2])
+}
+
+func TestRunEncryption(t *testing.T) {
+	agent, session := newFakeAgent(t, func(agent *fakeAgent) {
+		response := agent.handshake(`[` +
+			`{"ActionType":"SessionType","ActionParameters":{"SessionType":"InteractiveCommands"}},` +
+			`{"ActionType":"KMSEncryption","ActionParameters":{"KMSKeyId":"key"}}` +
+			`]`)
+		assert.Equal(t, []processedClientAction{
+			{ActionType: "SessionType", ActionStatus: actionSuccess},
+			{ActionType: "KMSEncryption", ActionStatus: actionFailed, Error: "KMSEncryption is not supported"},
+		}, response.ProcessedClientActions)
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := Run(ctx, session, Options{Stdout: &bytes.Buffer{}, Stderr: &bytes.Buffer{}})
+	<-agent.done
+
+	assert.ErrorIs(t, err, ErrEncryptionNotSupported)
+}
+
+func TestRunCancel(t *testing.T) {
+	agent, session := newFakeAgent(t, func(agent *fakeAgent) {
+		// Wait for the client to go away
+		_, _, _, _ = readFrame(agent.reader)
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(100*time.Millisecond, cancel)
+
+	_, err := Run(ctx, session, Options{Stdout: &bytes.Buffer{}, Stderr: &bytes.Buffer{}})
+	<-agent.done
+
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestMessage(t *testing.T) {
+	msg := newMessage(inputStreamMessage, 7, sizePayload, []byte(`{"cols":80,"rows":24}`))
+
+	data := msg.marshal()
+	assert.Len(t, data, payloadOffset+len(msg.payload))
+	assert.Equal(t, "input_stream_data               ", string(data[messageTypeOffset:schemaVersionOffset]))
+	assert.Equal(t, msg.id[8:], data[messageIdOffset:messageIdOffset+8])
+
+	parsed, err := unmarshalMessage(data)
+	require.NoError(t, err)
+	assert.Equal(t, msg, parsed)
+
+	_, err = unmarshalMessage(data[:payloadOffset+3])
+	assert.EqualError(t, err, "invalid payload length: 21")
+}
+
+func TestParseExitCode(t *testing.T) {
+	exitCode, err := parseExitCode([]byte("127\n"))
+	require.NoError(t, err)
+	assert.Equal(t, 127, exitCode)
+
+	exitCode, err = parseExitCode([]byte{0, 0, 0, 2})
+	require.NoError(t, err)
+	assert.Equal(t, 2, exitCode)
+
+	_, err = parseExitCode([]byte("unknown"))
+	assert.EqualError(t, err, `invalid exit code: "unknown"`)
+}
+
+func TestRunCancelTerminatesSession(t *testing.T) {
+	var flag message
+	agent, session := newFakeAgent(t, func(agent *fakeAgent) {
+		agent.handshake(`[{"ActionType":"SessionType","ActionParameters":{"SessionType":"InteractiveCommands"}}]`)
+		agent.send(handshakeCompletePayload, `{"HandshakeTimeToComplete":1000000,"CustomerMessage":""}`)
+
+		flag = agent.receive()
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(100*time.Millisecond, cancel)
+
+	_, err := Run(ctx, session, Options{Stdout: &bytes.Buffer{}, Stderr: &bytes.Buffer{}})
+	<-agent.done
+
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, inputStreamMessage, flag.messageType)
+	assert.Equal(t, flagPayload, flag.payloadType)
+	assert.Equal(t, []byte{0, 0, 0, 2}, flag.payload)
+}
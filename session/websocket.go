@@ -0,0 +1,283 @@
+package session
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// websocketGUID is appended to the key of the handshake to compute the
+// accept header (RFC 6455, section 1.3).
+const websocketGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// maxMessageSize bounds the messages read, the data channel sends small
+// chunks of output.
+const maxMessageSize = 16 << 20
+
+// Opcodes of the websocket frames.
+const (
+	opContinuation byte = 0x0
+	opText         byte = 0x1
+	opBinary       byte = 0x2
+	opClose        byte = 0x8
+	opPing         byte = 0x9
+	opPong         byte = 0xa
+)
+
+// conn is a minimal websocket connection, just enough to talk to the data
+// channel of a session.
+type conn struct {
+	netConn net.Conn
+	reader  *bufio.Reader
+	// masked is set on the client side, which must mask the frames it sends
+	masked  bool
+	writeMu sync.Mutex
+}
+
+// dial opens a websocket connection to a ws:// or wss:// URL.
+func dial(ctx context.Context, rawURL string, tlsConfig *tls.Config) (*conn, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+
+	var port string
+	switch u.Scheme {
+	case "ws":
+		port = "80"
+	case "wss":
+		port = "443"
+	default:
+		return nil, fmt.Errorf("unsupported scheme \"%s\" expecting ws or wss", u.Scheme)
+	}
+	if u.Port() != "" {
+		port = u.Port()
+	}
+
+	var dialer net.Dialer
+	netConn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(u.Hostname(), port))
+	if err != nil {
+		return nil, err
+	}
+
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = netConn.SetDeadline(deadline)
+	}
+
+	if u.Scheme == "wss" {
+		config := &tls.Config{}
+		if tlsConfig != nil {
+			config = tlsConfig.Clone()
+		}
+		if config.ServerName == "" {
+			config.ServerName = u.Hostname()
+		}
+		tlsConn := tls.Client(netConn, config)
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			netConn.Close()
+			return nil, err
+		}
+		netConn = tlsConn
+	}
+
+	c := &conn{netConn: netConn, reader: bufio.NewReader(netConn), masked: true}
+	if err := c.handshake(u); err != nil {
+		netConn.Close()
+		return nil, err
+	}
+
+	_ = netConn.SetDeadline(time.Time{})
+	return c, nil
+}
+
+func (c *conn) handshake(u *url.URL) error {
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+	key := base64.StdEncoding.EncodeToString(nonce)
+
+	req := &http.Request{
+		Method: http.MethodGet,
+		URL:    u,
+		Host:   u.Host,
+		Header: http.Header{
+			"Upgrade":               {"websocket"},
+			"Connection":            {"Upgrade"},
+			"Sec-WebSocket-Key":     {key},
+			"Sec-WebSocket-Version": {"13"},
+		},
+	}
+	if err := req.Write(c.netConn); err != nil {
+		return err
+	}
+
+	resp, err := http.ReadResponse(c.reader, req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		return fmt.Errorf("websocket handshake failed: %s", resp.Status)
+	}
+	if resp.Header.Get("Sec-WebSocket-Accept") != acceptKey(key) {
+		return errors.New("websocket handshake failed: invalid accept key")
+	}
+	return nil
+}
+
+func acceptKey(key string) string {
+	sum := sha1.Sum([]byte(key + websocketGUID))
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// readMessage returns the next text or binary message, answering pings
+// along the way. io.EOF is returned once the peer closes the connection.
+func (c *conn) readMessage() (byte, []byte, error) {
+	var opcode byte
+	var data []byte
+	for {
+		fin, frameOpcode, payload, err := readFrame(c.reader)
+		if err != nil {
+			return 0, nil, err
+		}
+
+		switch frameOpcode {
+		case opPing:
+			if err := c.writeMessage(opPong, payload); err != nil {
+				return 0, nil, err
+			}
+			continue
+		case opPong:
+			continue
+		case opClose:
+			_ = c.writeMessage(opClose, payload)
+			return 0, nil, io.EOF
+		case opContinuation:
+			if opcode == 0 {
+				return 0, nil, errors.New("unexpected continuation frame")
+			}
+		default:
+			opcode = frameOpcode
+		}
+
+		data = append(data, payload...)
+		if len(data) > maxMessageSize {
+			return 0, nil, fmt.Errorf("message exceeds %d bytes", maxMessageSize)
+		}
+		if fin {
+			return opcode, data, nil
+		}
+	}
+}
+
+// writeMessage sends a message in a single frame, it is safe to call
+// concurrently.
+func (c *conn) writeMessage(opcode byte, data []byte) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	return writeFrame(c.netConn, opcode, data, c.masked)
+}
+
+// Close sends a close frame before closing the connection.
+func (c *conn) Close() error {
+	_ = c.netConn.SetWriteDeadline(time.Now().Add(time.Second))
+	_ = c.writeMessage(opClose, binary.BigEndian.AppendUint16(nil, 1000))
+	return c.netConn.Close()
+}
+
+func readFrame(r *bufio.Reader) (bool, byte, []byte, error) {
+	header := make([]byte, 2)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return false, 0, nil, err
+	}
+	fin := header[0]&0x80 != 0
+	opcode := header[0] & 0x0f
+	masked := header[1]&0x80 != 0
+
+	length := uint64(header[1] & 0x7f)
+	switch length {
+	case 126:
+		extended := make([]byte, 2)
+		if _, err := io.ReadFull(r, extended); err != nil {
+			return false, 0, nil, err
+		}
+		length = uint64(binary.BigEndian.Uint16(extended))
+	case 127:
+		extended := make([]byte, 8)
+		if _, err := io.ReadFull(r, extended); err != nil {
+			return false, 0, nil, err
+		}
+		length = binary.BigEndian.Uint64(extended)
+	}
+	if length > maxMessageSize {
+		return false, 0, nil, fmt.Errorf("frame exceeds %d bytes", maxMessageSize)
+	}
+
+	var mask []byte
+	if masked {
+		mask = make([]byte, 4)
+		if _, err := io.ReadFull(r, mask); err != nil {
+			return false, 0, nil, err
+		}
+	}
+
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return false, 0, nil, err
+	}
+	if masked {
+		for i := range payload {
+			payload[i] ^= mask[i%4]
+		}
+	}
+	return fin, opcode, payload, nil
+}
+
+func writeFrame(w io.Writer, opcode byte, payload []byte, masked bool) error {
+	frame := []byte{0x80 | opcode}
+
+	var maskBit byte
+	if masked {
+		maskBit = 0x80
+	}
+	switch {
+	case len(payload) < 126:
+		frame = append(frame, maskBit|byte(len(payload)))
+	case len(payload) <= 0xffff:
+		frame = append(frame, maskBit|126)
+		frame = binary.BigEndian.AppendUint16(frame, uint16(len(payload)))
+	default:
+		frame = append(frame, maskBit|127)
+		frame = binary.BigEndian.AppendUint64(frame, uint64(len(payload)))
+	}
+
+	if !masked {
+		_, err := w.Write(append(frame, payload...))
+		return err
+	}
+
+	mask := make([]byte, 4)
+	if _, err := rand.Read(mask); err != nil {
+		return err
+	}
+	frame = append(frame, mask...)
+	for i, b := range payload {
+		frame = append(frame, b^mask[i%4])
+	}
+	_, err := w.Write(frame)
+	return err
+}
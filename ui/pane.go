@@ -0,0 +1,112 @@
+package ui
+
+import (
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+var (
+	paneStyle = lipgloss.NewStyle().
+			Border(lipgloss.RoundedBorder()).
+			BorderForeground(lipgloss.Color("240")).
+			Padding(0, 1)
+	focusedPaneStyle = paneStyle.BorderForeground(lipgloss.Color("212"))
+	paneTitleStyle   = lipgloss.NewStyle().Bold(true)
+	cursorStyle      = lipgloss.NewStyle().Foreground(lipgloss.Color("212")).Bold(true)
+	dimStyle         = lipgloss.NewStyle().Foreground(lipgloss.Color("240"))
+)
+
+// pane is a scrollable list of items with a cursor.
+type pane struct {
+	title  string
+	ids    []string
+	labels []string
+	cursor int
+}
+
+// setItems replaces the items of the pane, keeping the cursor on the same
+// item when it is still listed.
+func (p *pane) setItems(ids []string, labels []string) {
+	selectedId := p.selected()
+	p.ids = ids
+	p.labels = labels
+	p.cursor = 0
+	for i, id := range ids {
+		if id == selectedId {
+			p.cursor = i
+			break
+		}
+	}
+}
+
+func (p *pane) clear() {
+	p.ids = nil
+	p.labels = nil
+	p.cursor = 0
+}
+
+// selected returns the ID of the item under the cursor.
+func (p pane) selected() string {
+	if p.cursor < len(p.ids) {
+		return p.ids[p.cursor]
+	}
+	return ""
+}
+
+func (p *pane) move(delta int) bool {
+	cursor := min(max(p.cursor+delta, 0), max(len(p.ids)-1, 0))
+	if cursor == p.cursor {
+		return false
+	}
+	p.cursor = cursor
+	return true
+}
+
+func (p pane) view(width int, height int, focused bool) string {
+	style := paneStyle
+	if focused {
+		style = focusedPaneStyle
+	}
+	// Borders and padding take 4 columns and the borders 2 lines
+	innerWidth := max(width-4, 1)
+	rows := max(height-3, 1)
+
+	lines := []string{paneTitleStyle.Render(truncate(p.title, innerWidth))}
+	if len(p.labels) == 0 {
+		lines = append(lines, dimStyle.Render("(none)"))
+	}
+
+	// Keep the cursor visible by scrolling the items
+	offset := 0
+	if p.cursor >= rows {
+		offset = p.cursor - rows + 1
+	}
+	for i := offset; i < len(p.labels) && i < offset+rows; i++ {
+		label := truncate(p.labels[i], innerWidth-2)
+		if i == p.cursor {
+			lines = append(lines, cursorStyle.Render("> "+label))
+		} else {
+			lines = append(lines, "  "+label)
+		}
+	}
+
+	return style.
+		Width(width - 2).
+		Height(height - 2).
+		Render(strings.Join(lines, "\n"))
+}
+
+func truncate(value string, width int) string {
+	if width <= 0 {
+		return ""
+	}
+	runes := []rune(value)
+	if len(runes) <= width {
+		return value
+	}
+	if width == 1 {
+		return "…"
+	}
+	return string(runes[:width-1]) + "…"
+}
@@ -0,0 +1,452 @@
+package ui
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/ecs/types"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/sestrella/iecs/client"
+)
+
+// Action is an operation triggered from the dashboard.
+type Action string
+
+const (
+	ActionExec   Action = "exec"
+	ActionLogs   Action = "logs"
+	ActionEvents Action = "events"
+	ActionUpdate Action = "update"
+)
+
+// Selection holds the resources an action applies to. Task and Container are
+// only set when the corresponding pane, or a pane to its right, is focused.
+type Selection struct {
+	Cluster   *types.Cluster
+	Service   *types.Service
+	Task      *types.Task
+	Container *types.Container
+}
+
+// Runner runs an action while the dashboard is suspended, giving it full
+// control of the terminal.
+type Runner func(action Action, selection Selection) error
+
+const (
+	clustersPane = iota
+	servicesPane
+	tasksPane
+	containersPane
+)
+
+var (
+	headerStyle = lipgloss.NewStyle().Bold(true)
+	errorStyle  = lipgloss.NewStyle().Foreground(lipgloss.Color("9"))
+)
+
+const helpText = "←/→ pane • ↑/↓ move • e exec • l logs • v events • u update • r refresh • q quit"
+
+type clustersMsg struct {
+	clusters []types.Cluster
+	err      error
+}
+
+type servicesMsg struct {
+	clusterArn string
+	services   []types.Service
+	err        error
+}
+
+type tasksMsg struct {
+	serviceArn string
+	tasks      []types.Task
+	err        error
+}
+
+type refreshMsg struct{}
+
+type actionMsg struct {
+	action Action
+	err    error
+}
+
+// Model is the dashboard listing clusters, services, tasks and containers in
+// navigable panes.
+type Model struct {
+	ctx             context.Context
+	client          client.Client
+	runner          Runner
+	refreshInterval time.Duration
+
+	panes    [4]pane
+	focus    int
+	clusters []types.Cluster
+	services []types.Service
+	tasks    []types.Task
+
+	width       int
+	height      int
+	refreshedAt time.Time
+	status      string
+	err         error
+}
+
+// New returns the dashboard model. Data is refreshed every refreshInterval.
+func New(ctx context.Context, client client.Client, runner Runner, refreshInterval time.Duration) Model {
+	return Model{
+		ctx:             ctx,
+		client:          client,
+		runner:          runner,
+		refreshInterval: refreshInterval,
+		panes: [4]pane{
+			{title: "Clusters"},
+			{title: "Services"},
+			{title: "Tasks"},
+			{title: "Containers"},
+		},
+	}
+}
+
+// Run shows the dashboard until the user quits.
+func Run(ctx context.Context, client client.Client, runner Runner, refreshInterval time.Duration) error {
+	_, err := tea.NewProgram(
+		New(ctx, client, runner, refreshInterval),
+		tea.WithAltScreen(),
+		tea.WithContext(ctx),
+	).Run()
+	return err
+}
+
+func (m Model) Init() tea.Cmd {
+	return tea.Batch(m.loadClusters(), m.scheduleRefresh())
+}
+
+func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.width = msg.Width
+		m.height = msg.Height
+		return m, nil
+
+	case tea.KeyMsg:
+		return m.handleKey(msg)
+
+	case refreshMsg:
+		return m, tea.Batch(m.loadClusters(), m.scheduleRefresh())
+
+	case clustersMsg:
+		m.err = msg.err
+		if msg.err != nil {
+			return m, nil
+		}
+		m.clusters = msg.clusters
+		m.refreshedAt = time.Now()
+		var ids, labels []string
+		for _, cluster := range msg.clusters {
+			ids = append(ids, *cluster.ClusterArn)
+			labels = append(labels, clusterLabel(cluster))
+		}
+		m.panes[clustersPane].setItems(ids, labels)
+		return m, m.loadServices()
+
+	case servicesMsg:
+		if msg.clusterArn != m.panes[clustersPane].selected() {
+			return m, nil
+		}
+		m.err = msg.err
+		m.services = msg.services
+		var ids, labels []string
+		for _, service := range msg.services {
+			ids = append(ids, *service.ServiceArn)
+			labels = append(labels, serviceLabel(service))
+		}
+		m.panes[servicesPane].setItems(ids, labels)
+		return m, m.loadTasks()
+
+	case tasksMsg:
+		if msg.serviceArn != m.panes[servicesPane].selected() {
+			return m, nil
+		}
+		m.err = msg.err
+		m.tasks = msg.tasks
+		var ids, labels []string
+		for _, task := range msg.tasks {
+			ids = append(ids, *task.TaskArn)
+			labels = append(labels, taskLabel(task))
+		}
+		m.panes[tasksPane].setItems(ids, labels)
+		m.updateContainers()
+		return m, nil
+
+	case actionMsg:
+		m.err = msg.err
+		m.status = ""
+		if msg.err == nil {
+			m.status = fmt.Sprintf("%s finished", msg.action)
+		}
+		return m, m.loadClusters()
+	}
+
+	return m, nil
+}
+
+func (m Model) handleKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "q", "ctrl+c":
+		return m, tea.Quit
+	case "left", "h", "shift+tab":
+		m.focus = max(m.focus-1, clustersPane)
+	case "right", "tab", "enter":
+		m.focus = min(m.focus+1, containersPane)
+	case "up", "k":
+		return m.moveCursor(-1)
+	case "down", "j":
+		return m.moveCursor(1)
+	case "r":
+		m.status = ""
+		return m, m.loadClusters()
+	case "e":
+		return m.runAction(ActionExec)
+	case "l":
+		return m.runAction(ActionLogs)
+	case "v":
+		return m.runAction(ActionEvents)
+	case "u":
+		return m.runAction(ActionUpdate)
+	}
+	return m, nil
+}
+
+func (m Model) moveCursor(delta int) (tea.Model, tea.Cmd) {
+	if !m.panes[m.focus].move(delta) {
+		return m, nil
+	}
+
+	switch m.focus {
+	case clustersPane:
+		m.services = nil
+		m.tasks = nil
+		m.panes[servicesPane].clear()
+		m.panes[tasksPane].clear()
+		m.panes[containersPane].clear()
+		return m, m.loadServices()
+	case servicesPane:
+		m.tasks = nil
+		m.panes[tasksPane].clear()
+		m.panes[containersPane].clear()
+		return m, m.loadTasks()
+	case tasksPane:
+		m.updateContainers()
+	}
+	return m, nil
+}
+
+func (m *Model) updateContainers() {
+	var ids, labels []string
+	if task := m.selectedTask(); task != nil {
+		for _, container := range task.Containers {
+			ids = append(ids, *container.Name)
+			labels = append(labels, containerLabel(container))
+		}
+	}
+	m.panes[containersPane].setItems(ids, labels)
+}
+
+// selection returns the resources under the cursor of the focused pane and
+// the panes to its left.
+func (m Model) selection() Selection {
+	var selection Selection
+	if index := m.panes[clustersPane].cursor; index < len(m.clusters) {
+		selection.Cluster = &m.clusters[index]
+	}
+	if index := m.panes[servicesPane].cursor; index < len(m.services) {
+		selection.Service = &m.services[index]
+	}
+	if m.focus >= tasksPane {
+		selection.Task = m.selectedTask()
+	}
+	if m.focus >= containersPane && selection.Task != nil {
+		if index := m.panes[containersPane].cursor; index < len(selection.Task.Containers) {
+			selection.Container = &selection.Task.Containers[index]
+		}
+	}
+	return selection
+}
+
+func (m Model) selectedTask() *types.Task {
+	if index := m.panes[tasksPane].cursor; index < len(m.tasks) {
+		return &m.tasks[index]
+	}
+	return nil
+}
+
+// runAction suspends the dashboard and hands the terminal over to the runner.
+func (m Model) runAction(action Action) (tea.Model, tea.Cmd) {
+	selection := m.selection()
+	if selection.Service == nil {
+		m.status = fmt.Sprintf("select a service to run %s", action)
+		return m, nil
+	}
+
+	if action == ActionExec {
+		task := m.selectedTask()
+		if task == nil {
+			m.status = "select a task to exec into"
+			return m, nil
+		}
+		selection.Task = task
+		if selection.Container == nil && len(task.Containers) == 1 {
+			selection.Container = &task.Containers[0]
+		}
+		if selection.Container == nil {
+			m.status = "select a container to exec into"
+			return m, nil
+		}
+	}
+
+	return m, tea.Exec(
+		&runnerCommand{run: func() error {
+			return m.runner(action, selection)
+		}},
+		func(err error) tea.Msg {
+			return actionMsg{action: action, err: err}
+		},
+	)
+}
+
+func (m Model) View() string {
+	if m.width == 0 {
+		return "Loading..."
+	}
+
+	var breadcrumbs []string
+	for i := clustersPane; i <= containersPane; i++ {
+		if id := m.panes[i].selected(); id != "" {
+			breadcrumbs = append(breadcrumbs, id[strings.LastIndex(id, "/")+1:])
+		}
+	}
+	header := headerStyle.Render("iecs") + " " + strings.Join(breadcrumbs, " › ")
+	if !m.refreshedAt.IsZero() {
+		header += dimStyle.Render(fmt.Sprintf("  (refreshed at %s)", m.refreshedAt.Format(time.TimeOnly)))
+	}
+
+	footer := dimStyle.Render(helpText)
+	if m.err != nil {
+		footer = errorStyle.Render(m.err.Error())
+	} else if m.status != "" {
+		footer = m.status
+	}
+
+	paneWidth := m.width / len(m.panes)
+	paneHeight := max(m.height-2, 3)
+	var panes []string
+	for i, pane := range m.panes {
+		width := paneWidth
+		if i == len(m.panes)-1 {
+			width = m.width - paneWidth*(len(m.panes)-1)
+		}
+		panes = append(panes, pane.view(width, paneHeight, i == m.focus))
+	}
+
+	return lipgloss.JoinVertical(
+		lipgloss.Left,
+		truncate(header, m.width),
+		lipgloss.JoinHorizontal(lipgloss.Top, panes...),
+		truncate(footer, m.width),
+	)
+}
+
+func (m Model) scheduleRefresh() tea.Cmd {
+	return tea.Tick(m.refreshInterval, func(time.Time) tea.Msg {
+		return refreshMsg{}
+	})
+}
+
+func (m Model) loadClusters() tea.Cmd {
+	return func() tea.Msg {
+		clusterArns, err := m.client.ListClusters(m.ctx)
+		if err != nil {
+			return clustersMsg{err: err}
+		}
+		clusters, err := m.client.DescribeClusters(m.ctx, clusterArns)
+		return clustersMsg{clusters: clusters, err: err}
+	}
+}
+
+func (m Model) loadServices() tea.Cmd {
+	clusterArn := m.panes[clustersPane].selected()
+	if clusterArn == "" {
+		return nil
+	}
+	return func() tea.Msg {
+		serviceArns, err := m.client.ListServices(m.ctx, clusterArn)
+		if err != nil {
+			return servicesMsg{clusterArn: clusterArn, err: err}
+		}
+		services, err := m.client.DescribeServices(m.ctx, clusterArn, serviceArns)
+		return servicesMsg{clusterArn: clusterArn, services: services, err: err}
+	}
+}
+
+func (m Model) loadTasks() tea.Cmd {
+	clusterArn := m.panes[clustersPane].selected()
+	serviceArn := m.panes[servicesPane].selected()
+	if serviceArn == "" {
+		return nil
+	}
+	return func() tea.Msg {
+		taskArns, err := m.client.ListTasks(m.ctx, clusterArn, serviceArn, types.DesiredStatusRunning)
+		if err != nil || len(taskArns) == 0 {
+			return tasksMsg{serviceArn: serviceArn, err: err}
+		}
+		tasks, err := m.client.DescribeTasks(m.ctx, clusterArn, taskArns)
+		return tasksMsg{serviceArn: serviceArn, tasks: tasks, err: err}
+	}
+}
+
+func clusterLabel(cluster types.Cluster) string {
+	return fmt.Sprintf("%s (%d services)", *cluster.ClusterName, cluster.ActiveServicesCount)
+}
+
+func serviceLabel(service types.Service) string {
+	return fmt.Sprintf("%s %d/%d", *service.ServiceName, service.RunningCount, service.DesiredCount)
+}
+
+func taskLabel(task types.Task) string {
+	id := (*task.TaskArn)[strings.LastIndex(*task.TaskArn, "/")+1:]
+	label := fmt.Sprintf("%s %s", id, stringValue(task.LastStatus))
+	if task.HealthStatus != "" && task.HealthStatus != types.HealthStatusUnknown {
+		label += " " + string(task.HealthStatus)
+	}
+	return label
+}
+
+func containerLabel(container types.Container) string {
+	label := fmt.Sprintf("%s %s", *container.Name, stringValue(container.LastStatus))
+	if container.HealthStatus != "" && container.HealthStatus != types.HealthStatusUnknown {
+		label += " " + string(container.HealthStatus)
+	}
+	return label
+}
+
+func stringValue(value *string) string {
+	if value == nil {
+		return ""
+	}
+	return *value
+}
+
+// runnerCommand adapts an action to tea.ExecCommand. The runner writes to the
+// terminal directly, so the streams handed over by bubbletea are not used.
+type runnerCommand struct {
+	run func() error
+}
+
+func (c *runnerCommand) Run() error          { return c.run() }
+func (c *runnerCommand) SetStdin(io.Reader)  {}
+func (c *runnerCommand) SetStdout(io.Writer) {}
+func (c *runnerCommand) SetStderr(io.Writer) {}
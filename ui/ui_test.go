@@ -0,0 +1,174 @@
+package ui
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ecs/types"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/sestrella/iecs/client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	clusterArn = "arn:aws:ecs:us-east-1:123456789012:cluster/default"
+	serviceArn = "arn:aws:ecs:us-east-1:123456789012:service/default/api"
+	taskArn    = "arn:aws:ecs:us-east-1:123456789012:task/default/0123456789abcdef"
+)
+
+// fakeClient serves a single cluster, service and task. Methods not used by
+// the dashboard are left to the embedded nil interface.
+type fakeClient struct {
+	client.Client
+}
+
+func (c fakeClient) ListClusters(ctx context.Context) ([]string, error) {
+	return []string{clusterArn}, nil
+}
+
+func (c fakeClient) DescribeClusters(ctx context.Context, clusterArns []string) ([]types.Cluster, error) {
+	return []types.Cluster{{
+		ClusterArn:          aws.String(clusterArn),
+		ClusterName:         aws.String("default"),
+		ActiveServicesCount: 1,
+	}}, nil
+}
+
+func (c fakeClient) ListServices(ctx context.Context, clusterArn string) ([]string, error) {
+	return []string{serviceArn}, nil
+}
+
+func (c fakeClient) DescribeServices(ctx context.Context, clusterArn string, serviceArns []string) ([]types.Service, error) {
+	return []types.Service{{
+		ClusterArn:   aws.String(clusterArn),
+		ServiceArn:   aws.String(serviceArn),
+		ServiceName:  aws.String("api"),
+		DesiredCount: 2,
+		RunningCount: 1,
+	}}, nil
+}
+
+func (c fakeClient) ListTasks(
+	ctx context.Context,
+	clusterArn string,
+	serviceArn string,
+	desiredStatus types.DesiredStatus,
+) ([]string, error) {
+	return []string{taskArn}, nil
+}
+
+func (c fakeClient) DescribeTasks(ctx context.Context, clusterArn string, taskArns []string) ([]types.Task, error) {
+	return []types.Task{{
+		TaskArn:      aws.String(taskArn),
+		LastStatus:   aws.String("RUNNING"),
+		HealthStatus: types.HealthStatusHealthy,
+		Containers: []types.Container{
+			{Name: aws.String("app"), LastStatus: aws.String("RUNNING")},
+			{Name: aws.String("sidecar"), LastStatus: aws.String("RUNNING")},
+		},
+	}}, nil
+}
+
+// load feeds the model the messages of a full refresh.
+func load(t *testing.T, m Model) Model {
+	t.Helper()
+
+	cmd := m.loadClusters()
+	for cmd != nil {
+		model, next := m.Update(cmd())
+		m = model.(Model)
+		cmd = next
+	}
+	return m
+}
+
+func update(m Model, msg tea.Msg) Model {
+	model, _ := m.Update(msg)
+	return model.(Model)
+}
+
+func TestLoad(t *testing.T) {
+	m := load(t, New(context.TODO(), fakeClient{}, nil, time.Minute))
+
+	assert.Equal(t, clusterArn, m.panes[clustersPane].selected())
+	assert.Equal(t, serviceArn, m.panes[servicesPane].selected())
+	assert.Equal(t, taskArn, m.panes[tasksPane].selected())
+	assert.Equal(t, []string{"app", "sidecar"}, m.panes[containersPane].ids)
+	assert.Equal(t, []string{"api 1/2"}, m.panes[servicesPane].labels)
+	assert.Equal(t, []string{"0123456789abcdef RUNNING HEALTHY"}, m.panes[tasksPane].labels)
+}
+
+func TestUpdateStaleResponse(t *testing.T) {
+	m := load(t, New(context.TODO(), fakeClient{}, nil, time.Minute))
+
+	m = update(m, servicesMsg{clusterArn: "other", services: []types.Service{}})
+
+	assert.Equal(t, []string{serviceArn}, m.panes[servicesPane].ids)
+}
+
+func TestSelection(t *testing.T) {
+	m := load(t, New(context.TODO(), fakeClient{}, nil, time.Minute))
+
+	selection := m.selection()
+	assert.Equal(t, clusterArn, *selection.Cluster.ClusterArn)
+	assert.Equal(t, serviceArn, *selection.Service.ServiceArn)
+	assert.Nil(t, selection.Task)
+	assert.Nil(t, selection.Container)
+
+	m = update(m, tea.KeyMsg{Type: tea.KeyTab})
+	m = update(m, tea.KeyMsg{Type: tea.KeyTab})
+	m = update(m, tea.KeyMsg{Type: tea.KeyTab})
+	m = update(m, tea.KeyMsg{Type: tea.KeyDown})
+
+	selection = m.selection()
+	assert.Equal(t, taskArn, *selection.Task.TaskArn)
+	assert.Equal(t, "sidecar", *selection.Container.Name)
+}
+
+func TestRunActionRequiresContainer(t *testing.T) {
+	m := load(t, New(context.TODO(), fakeClient{}, nil, time.Minute))
+
+	model, cmd := m.runAction(ActionExec)
+
+	assert.Nil(t, cmd)
+	assert.Equal(t, "select a container to exec into", model.(Model).status)
+}
+
+func TestRunActionRequiresService(t *testing.T) {
+	m := New(context.TODO(), fakeClient{}, nil, time.Minute)
+
+	model, cmd := m.runAction(ActionLogs)
+
+	assert.Nil(t, cmd)
+	assert.Equal(t, "select a service to run logs", model.(Model).status)
+}
+
+func TestRunAction(t *testing.T) {
+	m := load(t, New(context.TODO(), fakeClient{}, nil, time.Minute))
+
+	model, cmd := m.runAction(ActionLogs)
+
+	require.NotNil(t, cmd)
+	assert.Empty(t, model.(Model).status)
+}
+
+func TestView(t *testing.T) {
+	m := load(t, New(context.TODO(), fakeClient{}, nil, time.Minute))
+	m = update(m, tea.WindowSizeMsg{Width: 120, Height: 20})
+
+	view := m.View()
+
+	assert.Contains(t, view, "default › api › 0123456789abcdef › app")
+	assert.Contains(t, view, "Clusters")
+	assert.Contains(t, view, "> api 1/2")
+	assert.Contains(t, view, "q quit")
+}
+
+func TestTruncate(t *testing.T) {
+	assert.Equal(t, "api", truncate("api", 3))
+	assert.Equal(t, "ap…", truncate("apis", 3))
+	assert.Equal(t, "", truncate("api", 0))
+}